@@ -0,0 +1,51 @@
+package merkle
+
+import (
+	"fmt"
+	"runtime"
+	"sync"
+	"sync/atomic"
+)
+
+// ProofItem is a single leaf together with its proof and the root it is proven against.
+type ProofItem struct {
+	Root  []byte
+	Index uint64
+	Leaf  []byte
+	Proof [][]byte
+}
+
+// VerifyBatchParallel validates the proofs of all items using the given number of workers. If workers is not positive
+// runtime.GOMAXPROCS(0) workers are used. Every worker uses its own ReusableValidator, so the per-item overhead is the
+// same as for ReusableValidator.Validate.
+//
+// The returned slice contains the result for every item in the order of the input. If the validation of an item fails
+// with an error its result is false and the error of the item with the lowest index is returned alongside the results.
+func VerifyBatchParallel(items []ProofItem, workers int, opts ...ValidatorOpt) ([]bool, error) {
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+	workers = min(workers, len(items))
+
+	results := make([]bool, len(items))
+	errs := make([]error, len(items))
+	var next atomic.Int64
+	var wg sync.WaitGroup
+	for range workers {
+		wg.Go(func() {
+			v := NewReusableValidator(nil, opts...)
+			for i := next.Add(1) - 1; i < int64(len(items)); i = next.Add(1) - 1 {
+				item := &items[i]
+				results[i], errs[i] = v.validate(item.Root, item.Index, item.Leaf, item.Proof)
+			}
+		})
+	}
+	wg.Wait()
+
+	for i, err := range errs {
+		if err != nil {
+			return results, fmt.Errorf("item %d: %w", i, validationError(err))
+		}
+	}
+	return results, nil
+}
@@ -0,0 +1,98 @@
+package merkle_test
+
+import (
+	"errors"
+	"fmt"
+	"runtime"
+	"testing"
+
+	"github.com/fasmat/merkle"
+)
+
+// proofItems returns items for every leaf of a tree with the given number of leaves.
+func proofItems(tb testing.TB, leaves uint64) []merkle.ProofItem {
+	tb.Helper()
+
+	items := make([]merkle.ProofItem, 0, leaves)
+	for i := range leaves {
+		tree := merkle.TreeBuilder().
+			WithLeafToProve(i).
+			Build()
+		for j := range leaves {
+			tree.Add(leaf(j))
+		}
+		root, proof := tree.RootAndProof()
+		items = append(items, merkle.ProofItem{Root: root, Index: i, Leaf: leaf(i), Proof: proof})
+	}
+	return items
+}
+
+func TestVerifyBatchParallel(t *testing.T) {
+	t.Parallel()
+
+	items := append(proofItems(t, 10), proofItems(t, 16)...)
+	items[3].Leaf = leaf(4)
+
+	for _, workers := range []int{0, 1, 3, 100} {
+		t.Run(fmt.Sprintf("%d workers", workers), func(t *testing.T) {
+			t.Parallel()
+
+			results, err := merkle.VerifyBatchParallel(items, workers)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(results) != len(items) {
+				t.Fatalf("Expected %d results, got %d", len(items), len(results))
+			}
+			for i, valid := range results {
+				if valid != (i != 3) {
+					t.Errorf("item %d: expected valid to be %t, got %t", i, i != 3, valid)
+				}
+			}
+		})
+	}
+}
+
+func TestVerifyBatchParallelError(t *testing.T) {
+	t.Parallel()
+
+	items := proofItems(t, 8)
+	items[5].Proof = items[5].Proof[:1]
+	items[6].Proof = items[6].Proof[:1]
+
+	results, err := merkle.VerifyBatchParallel(items, 4)
+	if !errors.Is(err, merkle.ErrShortProof) {
+		t.Fatalf("Expected error %v, got %v", merkle.ErrShortProof, err)
+	}
+	if err.Error() != "item 5: "+merkle.ErrShortProof.Error() {
+		t.Errorf("Expected error of item 5, got %v", err)
+	}
+	for i, valid := range results {
+		if valid != (i != 5 && i != 6) {
+			t.Errorf("item %d: expected valid to be %t, got %t", i, i != 5 && i != 6, valid)
+		}
+	}
+}
+
+func TestVerifyBatchParallelEmpty(t *testing.T) {
+	t.Parallel()
+
+	results, err := merkle.VerifyBatchParallel(nil, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 0 {
+		t.Errorf("Expected no results, got %v", results)
+	}
+}
+
+func BenchmarkVerifyBatchParallel(b *testing.B) {
+	items := proofItems(b, 1<<10)
+	for _, workers := range []int{1, 2, 4, runtime.GOMAXPROCS(0)} {
+		b.Run(fmt.Sprintf("workers=%d", workers), func(b *testing.B) {
+			for b.Loop() {
+				merkle.VerifyBatchParallel(items, workers) //nolint:errcheck
+			}
+		})
+	}
+}
@@ -0,0 +1,45 @@
+package merkle
+
+import (
+	"fmt"
+	"slices"
+	"sync"
+
+	"golang.org/x/crypto/blake2b"
+)
+
+// Blake2bHasher returns a Hasher that computes the parent node by hashing the concatenation of the two children with
+// BLAKE2b-256 from golang.org/x/crypto/blake2b keyed with the given key. Using distinct keys for logically distinct
+// trees cryptographically isolates them from each other without having to add a prefix to every node. Like Sha256 it
+// uses a sync.Pool to reuse hash.Hash instances. The salt and personalization parameters of BLAKE2b are not supported,
+// since golang.org/x/crypto/blake2b does not expose them, distinct keys provide the same isolation.
+//
+// The key can be up to 64 bytes, an empty key results in the unkeyed BLAKE2b-256 (see Blake2b256). Blake2bHasher
+// panics if the key is too long. To validate proofs of a tree built with this hasher the validator has to use a
+// hasher with the same key.
+func Blake2bHasher(key []byte) Hasher {
+	// validate the key once, instead of when the first instance is created
+	if _, err := blake2b.New256(key); err != nil {
+		panic(fmt.Sprintf("merkle: invalid BLAKE2b key of %d bytes, must be at most %d", len(key), blake2b.Size))
+	}
+	key = slices.Clone(key)
+	return &cryptoHasher{
+		size: blake2b.Size256,
+		pool: &sync.Pool{
+			New: func() any {
+				h, _ := blake2b.New256(key) // the key was validated above
+				return h
+			},
+		},
+	}
+}
+
+// Blake2b256 returns a Hasher that computes the parent node by hashing the concatenation of the two children with
+// BLAKE2b-256 from golang.org/x/crypto/blake2b without a key. Like Sha256 it uses a sync.Pool to reuse hash.Hash
+// instances, so it can be shared by multiple trees that are built concurrently.
+//
+// BLAKE2b is often faster than SHA-256 on platforms without SHA-256 instructions, compare BenchmarkTreeAddBlake2b256
+// with BenchmarkTreeAdd on the target platform.
+func Blake2b256() Hasher {
+	return Blake2bHasher(nil)
+}
@@ -0,0 +1,174 @@
+package merkle_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"testing"
+
+	"github.com/fasmat/merkle"
+)
+
+func TestBlake2bHasher(t *testing.T) {
+	t.Parallel()
+
+	lChild := make([]byte, 32)
+	rChild := make([]byte, 32)
+	for i := range 32 {
+		lChild[i] = byte(i)
+		rChild[i] = byte(i + 32)
+	}
+
+	// expected values computed with Python's hashlib.blake2b(lChild + rChild, digest_size=32, key=key)
+	tt := []struct {
+		name     string
+		key      []byte
+		expected string
+	}{
+		{
+			name:     "no key",
+			expected: "10d8e6d534b00939843fe9dcc4dae48cdf008f6b8b2b82b156f5404d874887f5",
+		},
+		{
+			name:     "key",
+			key:      []byte("key"),
+			expected: "b821dad361cc735108978be1179daab2e218253f9ac91f1f68aba5eeb50056d5",
+		},
+		{
+			name:     "other key",
+			key:      []byte("tree-b"),
+			expected: "6f3dfce1556b01f0bf8652bbe51fe7b710797c33a4d9957cdb34185ca1f7fb99",
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			hasher := merkle.Blake2bHasher(tc.key)
+			node := hex.EncodeToString(hasher.Hash(nil, lChild, rChild))
+			if node != tc.expected {
+				t.Errorf("Expected hash to be %s, got %s", tc.expected, node)
+			}
+		})
+	}
+}
+
+func TestBlake2bHasherMultipleBlocks(t *testing.T) {
+	t.Parallel()
+
+	hasher := merkle.Blake2bHasher([]byte("0123456789abcdef"))
+	node := hex.EncodeToString(hasher.Hash(nil, make([]byte, 256), make([]byte, 44)))
+	expected := "307092cf50ba31f218d4cd8f9de6c9e58fc30f451c649f5146ac5307c83b3d81"
+	if node != expected {
+		t.Errorf("Expected hash to be %s, got %s", expected, node)
+	}
+}
+
+func TestBlake2bHasherIsolation(t *testing.T) {
+	t.Parallel()
+
+	build := func(hasher merkle.Hasher) ([]byte, [][]byte, map[uint64][]byte) {
+		tree := merkle.TreeBuilder().
+			WithHasher(hasher).
+			WithLeafToProve(4).
+			Build()
+		leaves := make(map[uint64][]byte)
+		for i := range 8 {
+			b := make([]byte, tree.NodeSize())
+			binary.LittleEndian.PutUint64(b, uint64(i))
+			tree.Add(b)
+			if i == 4 {
+				leaves[uint64(i)] = b
+			}
+		}
+		root, proof := tree.RootAndProof()
+		return root, proof, leaves
+	}
+
+	rootA, proofA, leaves := build(merkle.Blake2bHasher([]byte("tree-a")))
+	rootB, _, _ := build(merkle.Blake2bHasher([]byte("tree-b")))
+	if bytes.Equal(rootA, rootB) {
+		t.Error("Expected roots with different keys to differ")
+	}
+
+	valid, err := merkle.ValidateProof(rootA, leaves, proofA, merkle.WithHasher(merkle.Blake2bHasher([]byte("tree-a"))))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !valid {
+		t.Error("proof is not valid")
+	}
+
+	valid, err = merkle.ValidateProof(rootA, leaves, proofA, merkle.WithHasher(merkle.Blake2bHasher([]byte("tree-b"))))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if valid {
+		t.Error("Expected proof to be invalid with a different key")
+	}
+}
+
+func TestBlake2b256(t *testing.T) {
+	t.Parallel()
+
+	lChild := make([]byte, 32)
+	rChild := make([]byte, 32)
+	for i := range 32 {
+		lChild[i] = byte(i)
+		rChild[i] = byte(i + 32)
+	}
+
+	// the buffer may point to the same memory as the left child
+	hasher := merkle.Blake2b256()
+	node := hex.EncodeToString(hasher.Hash(lChild[:0], lChild, rChild))
+	expected := "10d8e6d534b00939843fe9dcc4dae48cdf008f6b8b2b82b156f5404d874887f5"
+	if node != expected {
+		t.Errorf("Expected hash to be %s, got %s", expected, node)
+	}
+
+	tree := merkle.TreeBuilder().
+		WithHasher(merkle.Blake2b256()).
+		WithLeafToProve(4).
+		Build()
+	leaves := make(map[uint64][]byte)
+	for i := range 10 {
+		b := make([]byte, tree.NodeSize())
+		binary.LittleEndian.PutUint64(b, uint64(i))
+		tree.Add(b)
+		if i == 4 {
+			leaves[uint64(i)] = b
+		}
+	}
+	root, proof := tree.RootAndProof()
+
+	valid, err := merkle.ValidateProof(root, leaves, proof, merkle.WithHasher(merkle.Blake2b256()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !valid {
+		t.Error("proof is not valid")
+	}
+}
+
+func TestBlake2bHasherInvalidKey(t *testing.T) {
+	t.Parallel()
+
+	defer func() {
+		if recover() == nil {
+			t.Error("Expected Blake2bHasher to panic for a key longer than 64 bytes")
+		}
+	}()
+	merkle.Blake2bHasher(make([]byte, 65))
+}
+
+func BenchmarkTreeAddBlake2b256(b *testing.B) {
+	tree := merkle.TreeBuilder().
+		WithHasher(merkle.Blake2b256()).
+		Build()
+	buf := make([]byte, tree.NodeSize())
+	for i := 0; b.Loop(); i++ {
+		binary.LittleEndian.PutUint64(buf, uint64(i))
+		tree.Add(buf)
+	}
+}
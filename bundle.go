@@ -0,0 +1,105 @@
+package merkle
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+)
+
+// ProofBundle contains the proven leaves of a tree together with their proof. This is everything needed to
+// reconstruct the root of the tree.
+type ProofBundle struct {
+	Leaves map[uint64][]byte
+	Proof  [][]byte
+}
+
+// SameRoot reconstructs the roots of both bundles and returns true if they are equal, i.e. if both bundles were
+// generated from the same tree. This does not require a trusted root, but it only shows that the bundles are
+// consistent with each other, not that they belong to a specific tree. With WithMismatchError differing roots are
+// reported as a *ValidationError wrapping ErrRootMismatch.
+func SameRoot(bundleA, bundleB ProofBundle, opts ...ValidatorOpt) (bool, error) {
+	rootA, err := ComputeRoot(bundleA.Leaves, bundleA.Proof, opts...)
+	if err != nil {
+		return false, err
+	}
+	rootB, err := ComputeRoot(bundleB.Leaves, bundleB.Proof, opts...)
+	if err != nil {
+		return false, err
+	}
+	return checkMatch(bytes.Equal(rootA, rootB), parseValidatorOpts(opts))
+}
+
+// ErrUnsupportedAlgorithm is returned by VerifyJSON when the bundle specifies an unknown hash algorithm.
+var ErrUnsupportedAlgorithm = errors.New("unsupported algorithm")
+
+// bundleAlgorithms maps the algorithms accepted by VerifyJSON to the constructors of their hashers.
+var bundleAlgorithms = map[string]func() Hasher{
+	"sha256":      Sha256,
+	"sha512":      Sha512,
+	"sha3-256":    Sha3_256,
+	"keccak256":   Keccak256,
+	"blake2b-256": Blake2b256,
+}
+
+// jsonProofBundle is the JSON representation of a proof bundle as accepted by VerifyJSON.
+type jsonProofBundle struct {
+	Root      string            `json:"root"`
+	Leaves    map[uint64]string `json:"leaves"`
+	Proof     []string          `json:"proof"`
+	Algorithm string            `json:"algorithm,omitempty"`
+}
+
+// VerifyJSON decodes a JSON encoded proof bundle and validates it. All byte values are hex encoded, the leaves are
+// given as an object with the leaf indices as keys:
+//
+//	{
+//		"root": "89a0f157...",
+//		"leaves": {"4": "04000000..."},
+//		"proof": ["05000000...", "fa670379...", "ba94ffe7..."],
+//		"algorithm": "sha256"
+//	}
+//
+// The algorithm is optional. If it is set it has to be one of "sha256" (Sha256), "sha512" (Sha512), "sha3-256"
+// (Sha3_256), "keccak256" (Keccak256) or "blake2b-256" (Blake2b256) and overrides a hasher passed with WithHasher,
+// otherwise an error wrapping ErrUnsupportedAlgorithm is returned. Trees built with a different hasher can be verified
+// by omitting the algorithm and passing the hasher with WithHasher. The given options are not modified.
+//
+// Like for ValidateProof every error is a *ValidationError: a bundle that cannot be decoded is reported with
+// ReasonOther and an unknown algorithm with ReasonUnsupportedAlgorithm.
+func VerifyJSON(data []byte, opts ...ValidatorOpt) (bool, error) {
+	var bundle jsonProofBundle
+	if err := json.Unmarshal(data, &bundle); err != nil {
+		return false, validationError(fmt.Errorf("decode bundle: %w", err))
+	}
+
+	if bundle.Algorithm != "" {
+		hasher, ok := bundleAlgorithms[bundle.Algorithm]
+		if !ok {
+			return false, validationError(fmt.Errorf("%w: %s", ErrUnsupportedAlgorithm, bundle.Algorithm))
+		}
+		opts = append(slices.Clone(opts), WithHasher(hasher()))
+	}
+
+	root, err := hex.DecodeString(bundle.Root)
+	if err != nil {
+		return false, validationError(fmt.Errorf("decode root: %w", err))
+	}
+	leaves := make(map[uint64][]byte, len(bundle.Leaves))
+	for idx, leaf := range bundle.Leaves {
+		leaves[idx], err = hex.DecodeString(leaf)
+		if err != nil {
+			return false, validationError(fmt.Errorf("decode leaf %d: %w", idx, err))
+		}
+	}
+	proof := make([][]byte, len(bundle.Proof))
+	for i, node := range bundle.Proof {
+		proof[i], err = hex.DecodeString(node)
+		if err != nil {
+			return false, validationError(fmt.Errorf("decode proof node %d: %w", i, err))
+		}
+	}
+	return ValidateProof(root, leaves, proof, opts...)
+}
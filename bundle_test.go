@@ -0,0 +1,216 @@
+package merkle_test
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/fasmat/merkle"
+)
+
+func proofBundle(tb testing.TB, numLeaves int, leavesToProve ...uint64) merkle.ProofBundle {
+	tb.Helper()
+
+	builder := merkle.TreeBuilder()
+	for _, leaf := range leavesToProve {
+		builder.WithLeafToProve(leaf)
+	}
+	tree := builder.Build()
+
+	bundle := merkle.ProofBundle{
+		Leaves: make(map[uint64][]byte, len(leavesToProve)),
+	}
+	for i := range numLeaves {
+		b := make([]byte, tree.NodeSize())
+		binary.LittleEndian.PutUint64(b, uint64(i))
+		tree.Add(b)
+		if slices.Contains(leavesToProve, uint64(i)) {
+			bundle.Leaves[uint64(i)] = b
+		}
+	}
+
+	_, bundle.Proof = tree.RootAndProof()
+	return bundle
+}
+
+func TestSameRoot(t *testing.T) {
+	t.Parallel()
+
+	same, err := merkle.SameRoot(proofBundle(t, 10, 0, 4), proofBundle(t, 10, 8))
+	if err != nil {
+		t.Error(err)
+	}
+	if !same {
+		t.Error("expected bundles of the same tree to have the same root")
+	}
+
+	same, err = merkle.SameRoot(proofBundle(t, 10, 4), proofBundle(t, 9, 4))
+	if err != nil {
+		t.Error(err)
+	}
+	if same {
+		t.Error("expected bundles of different trees to have different roots")
+	}
+
+	same, err = merkle.SameRoot(proofBundle(t, 10, 4), merkle.ProofBundle{})
+	if !errors.Is(err, merkle.ErrNoLeaves) {
+		t.Errorf("expected error: %v, got: %v", merkle.ErrNoLeaves, err)
+	}
+	if same {
+		t.Error("expected bundles to not have the same root")
+	}
+}
+
+func TestVerifyJSON(t *testing.T) {
+	t.Parallel()
+
+	tt := []struct {
+		name  string
+		data  string
+		valid bool
+		err   error
+	}{
+		{
+			name: "valid",
+			data: `{
+				"root": "89a0f1577268cc19b0a39c7a69f804fd140640c699585eb635ebb03c06154cce",
+				"leaves": {"4": "0400000000000000000000000000000000000000000000000000000000000000"},
+				"proof": [
+					"0500000000000000000000000000000000000000000000000000000000000000",
+					"fa670379e5c2212ed93ff09769622f81f98a91e1ec8fb114d607dd25220b9088",
+					"ba94ffe7edabf26ef12736f8eb5ce74d15bedb6af61444ae2906e926b1a95084"
+				],
+				"algorithm": "sha256"
+			}`,
+			valid: true,
+		},
+		{
+			name: "tampered leaf",
+			data: `{
+				"root": "89a0f1577268cc19b0a39c7a69f804fd140640c699585eb635ebb03c06154cce",
+				"leaves": {"4": "0500000000000000000000000000000000000000000000000000000000000000"},
+				"proof": [
+					"0500000000000000000000000000000000000000000000000000000000000000",
+					"fa670379e5c2212ed93ff09769622f81f98a91e1ec8fb114d607dd25220b9088",
+					"ba94ffe7edabf26ef12736f8eb5ce74d15bedb6af61444ae2906e926b1a95084"
+				]
+			}`,
+		},
+		{
+			name: "tampered index",
+			data: `{
+				"root": "89a0f1577268cc19b0a39c7a69f804fd140640c699585eb635ebb03c06154cce",
+				"leaves": {"5": "0400000000000000000000000000000000000000000000000000000000000000"},
+				"proof": [
+					"0500000000000000000000000000000000000000000000000000000000000000",
+					"fa670379e5c2212ed93ff09769622f81f98a91e1ec8fb114d607dd25220b9088",
+					"ba94ffe7edabf26ef12736f8eb5ce74d15bedb6af61444ae2906e926b1a95084"
+				]
+			}`,
+		},
+		{
+			name: "unsupported algorithm",
+			data: `{
+				"root": "89a0f1577268cc19b0a39c7a69f804fd140640c699585eb635ebb03c06154cce",
+				"leaves": {"4": "0400000000000000000000000000000000000000000000000000000000000000"},
+				"proof": [],
+				"algorithm": "md5"
+			}`,
+			err: merkle.ErrUnsupportedAlgorithm,
+		},
+		{
+			name: "invalid hex",
+			data: `{"root": "zz", "leaves": {"4": "04"}, "proof": []}`,
+			err:  hex.InvalidByteError('z'),
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			valid, err := merkle.VerifyJSON([]byte(tc.data))
+			if !errors.Is(err, tc.err) {
+				t.Errorf("expected error: %v, got: %v", tc.err, err)
+			}
+			if valid != tc.valid {
+				t.Errorf("expected valid: %t, got: %t", tc.valid, valid)
+			}
+		})
+	}
+
+	valid, err := merkle.VerifyJSON([]byte(`{`))
+	if err == nil {
+		t.Error("expected error for malformed JSON")
+	}
+	if valid {
+		t.Error("expected malformed JSON to be invalid")
+	}
+}
+
+func TestVerifyJSONAlgorithms(t *testing.T) {
+	t.Parallel()
+
+	tt := []struct {
+		algorithm string
+		hasher    merkle.Hasher
+	}{
+		{algorithm: "sha256", hasher: merkle.Sha256()},
+		{algorithm: "sha512", hasher: merkle.Sha512()},
+		{algorithm: "sha3-256", hasher: merkle.Sha3_256()},
+		{algorithm: "keccak256", hasher: merkle.Keccak256()},
+		{algorithm: "blake2b-256", hasher: merkle.Blake2b256()},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.algorithm, func(t *testing.T) {
+			t.Parallel()
+
+			tree := merkle.TreeBuilder().
+				WithHasher(tc.hasher).
+				WithLeafToProve(4).
+				Build()
+			leaves := make(map[uint64]string)
+			for i := range 10 {
+				b := make([]byte, tree.NodeSize())
+				binary.LittleEndian.PutUint64(b, uint64(i))
+				tree.Add(b)
+				if i == 4 {
+					leaves[uint64(i)] = hex.EncodeToString(b)
+				}
+			}
+			root, proof := tree.RootAndProof()
+			encodedProof := make([]string, len(proof))
+			for i, node := range proof {
+				encodedProof[i] = hex.EncodeToString(node)
+			}
+			data, err := json.Marshal(map[string]any{
+				"root":      hex.EncodeToString(root),
+				"leaves":    leaves,
+				"proof":     encodedProof,
+				"algorithm": tc.algorithm,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			// the algorithm of the bundle overrides the hasher of the options, which must not be modified
+			opts := make([]merkle.ValidatorOpt, 1, 2)
+			opts[0] = merkle.WithHasher(merkle.Sha256())
+
+			valid, err := merkle.VerifyJSON(data, opts...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !valid {
+				t.Error("proof is not valid")
+			}
+			if opts[:2][1] != nil {
+				t.Error("Expected the backing array of the options not to be modified")
+			}
+		})
+	}
+}
@@ -0,0 +1,49 @@
+package merkle
+
+import (
+	"fmt"
+	"hash"
+)
+
+// ChunkedLeafBuilder hashes a single leaf whose data is passed in multiple chunks and adds the hash to a tree. This
+// allows adding very large leaves without buffering their data, e.g. while reading them from the network.
+//
+// Use Tree.ChunkedLeaf to create a ChunkedLeafBuilder.
+type ChunkedLeafBuilder struct {
+	tree *Tree
+	h    hash.Hash
+	buf  []byte
+}
+
+// ChunkedLeaf returns a ChunkedLeafBuilder that hashes the data of a leaf with the given hash function and adds the
+// digest to the tree. The size of the digest has to match the size of the leaves of the tree, ChunkedLeaf panics
+// otherwise.
+//
+// Hashing a leaf in chunks with sha256.New results in the same tree as adding the data as a whole to a tree with
+// the default hasher that was built with Builder.WithLeafContentHashing. The digest is added with Tree.AddLeafHash,
+// so the same restrictions apply: the leaf hasher of the tree is not applied and trees with a sequential leaf hasher
+// are not supported.
+func (t *Tree) ChunkedLeaf(h hash.Hash) *ChunkedLeafBuilder {
+	if h.Size() != t.leafHasher.Size() {
+		panic(fmt.Sprintf("merkle: chunked leaf hash has size %d, expected %d", h.Size(), t.leafHasher.Size()))
+	}
+	h.Reset()
+	return &ChunkedLeafBuilder{
+		tree: t,
+		h:    h,
+		buf:  make([]byte, 0, h.Size()),
+	}
+}
+
+// WriteChunk adds the next chunk of data to the current leaf. The chunk is not retained and can be reused by the
+// caller after the call returns.
+func (c *ChunkedLeafBuilder) WriteChunk(chunk []byte) {
+	c.h.Write(chunk)
+}
+
+// FinalizeLeaf hashes the chunks written since the last call and adds the digest as next leaf to the tree. Afterwards
+// the builder can be used to add the next leaf.
+func (c *ChunkedLeafBuilder) FinalizeLeaf() error {
+	defer c.h.Reset()
+	return c.tree.AddLeafHash(c.h.Sum(c.buf[:0]))
+}
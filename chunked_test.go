@@ -0,0 +1,50 @@
+package merkle_test
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"crypto/sha512"
+	"testing"
+
+	"github.com/fasmat/merkle"
+)
+
+func TestChunkedLeaf(t *testing.T) {
+	t.Parallel()
+
+	data := bytes.Repeat([]byte("0123456789"), 1000)
+
+	whole := merkle.TreeBuilder().WithLeafContentHashing().Build()
+	chunked := merkle.NewTree()
+	c := chunked.ChunkedLeaf(sha256.New())
+	for i := range uint64(5) {
+		whole.Add(leaf(i))
+		c.WriteChunk(leaf(i))
+		if err := c.FinalizeLeaf(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	whole.Add(data)
+	c.WriteChunk(data[:10])
+	c.WriteChunk(data[10:5000])
+	c.WriteChunk(data[5000:])
+	if err := c.FinalizeLeaf(); err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.Equal(whole.Root(), chunked.Root()) {
+		t.Errorf("Expected root to be %x, got %x", whole.Root(), chunked.Root())
+	}
+}
+
+func TestChunkedLeafInvalidSize(t *testing.T) {
+	t.Parallel()
+
+	defer func() {
+		if recover() == nil {
+			t.Error("Expected ChunkedLeaf to panic for a hash of a different size")
+		}
+	}()
+	merkle.NewTree().ChunkedLeaf(sha512.New())
+}
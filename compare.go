@@ -0,0 +1,51 @@
+package merkle
+
+import (
+	"slices"
+)
+
+// BuilderOpt configures a Builder, e.g. func(b *Builder) { b.WithHasher(h) }.
+type BuilderOpt func(*Builder)
+
+// CompareConfigs builds trees from the given leaves with the two given configurations and reports whether their roots
+// differ and the indices of the leaves whose proofs differ between the configurations. This helps to assess the
+// impact of a configuration change, e.g. when migrating a tree to a different hasher.
+//
+// The proof of every leaf is built separately, so the leaves are added once per leaf and configuration. It is meant as
+// diagnostic tool for small trees. Leaves to prove set by the configurations are overridden.
+func CompareConfigs(leaves [][]byte, cfgA, cfgB []BuilderOpt) (bool, []uint64) {
+	rootA, _ := buildWithConfig(leaves, cfgA)
+	rootB, _ := buildWithConfig(leaves, cfgB)
+	rootsDiffer := !slices.Equal(rootA, rootB)
+
+	var changed []uint64
+	for i := range uint64(len(leaves)) {
+		_, proofA := buildWithConfig(leaves, cfgA, i)
+		_, proofB := buildWithConfig(leaves, cfgB, i)
+		if !slices.EqualFunc(proofA, proofB, slices.Equal) {
+			changed = append(changed, i)
+		}
+	}
+	return rootsDiffer, changed
+}
+
+// buildWithConfig builds a tree with the given configuration from the leaves and returns its root and the proof for
+// the leaves with the given indices, relative to the first leaf. Leaves to prove set by the configuration are
+// overridden, without indices no proof is built.
+func buildWithConfig(leaves [][]byte, cfg []BuilderOpt, indices ...uint64) ([]byte, [][]byte) {
+	builder := TreeBuilder()
+	for _, opt := range cfg {
+		opt(builder)
+	}
+	builder.leavesToProve = make(map[uint64]struct{})
+	builder.leafHashToProve = nil
+	for _, idx := range indices {
+		builder.WithLeafToProve(builder.indexOffset + idx)
+	}
+
+	tree := builder.Build()
+	for _, leaf := range leaves {
+		tree.Add(leaf)
+	}
+	return tree.RootAndProof()
+}
@@ -0,0 +1,60 @@
+package merkle_test
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/fasmat/merkle"
+)
+
+func TestCompareConfigs(t *testing.T) {
+	t.Parallel()
+
+	leaves := make([][]byte, 8)
+	for i := range leaves {
+		leaves[i] = leaf(uint64(i))
+	}
+	sha256Cfg := []merkle.BuilderOpt{func(b *merkle.Builder) { b.WithHasher(merkle.Sha256()) }}
+
+	tt := []struct {
+		name        string
+		cfg         []merkle.BuilderOpt
+		rootsDiffer bool
+		changed     []uint64
+	}{
+		{
+			name:        "sha512/256",
+			cfg:         []merkle.BuilderOpt{func(b *merkle.Builder) { b.WithHasher(sha512_256Hasher{}) }},
+			rootsDiffer: true,
+			changed:     []uint64{0, 1, 2, 3, 4, 5, 6, 7},
+		},
+		{
+			name: "same config",
+			cfg:  sha256Cfg,
+		},
+		{
+			name: "min height of balanced tree",
+			cfg:  []merkle.BuilderOpt{func(b *merkle.Builder) { b.WithMinHeight(3) }},
+		},
+		{
+			name:        "min height above tree",
+			cfg:         []merkle.BuilderOpt{func(b *merkle.Builder) { b.WithMinHeight(5) }},
+			rootsDiffer: true,
+			changed:     []uint64{0, 1, 2, 3, 4, 5, 6, 7},
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			rootsDiffer, changed := merkle.CompareConfigs(leaves, sha256Cfg, tc.cfg)
+			if rootsDiffer != tc.rootsDiffer {
+				t.Errorf("Expected roots differ to be %t, got %t", tc.rootsDiffer, rootsDiffer)
+			}
+			if !slices.Equal(changed, tc.changed) {
+				t.Errorf("Expected changed proofs %v, got %v", tc.changed, changed)
+			}
+		})
+	}
+}
@@ -0,0 +1,132 @@
+package merkle
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"hash"
+	"sync"
+)
+
+const (
+	ctVersionV1         = 0 // Version v1 of RFC 6962
+	ctSignatureTypeHead = 1 // SignatureType tree_hash of RFC 6962
+
+	rfc6962LeafPrefix = 0x00 // Prefix of the leaf hash input of RFC 6962
+	rfc6962NodePrefix = 0x01 // Prefix of the interior node hash input of RFC 6962
+)
+
+// rfc6962Prefixes holds the prefixes, so writing them to the hash doesn't allocate.
+var rfc6962Prefixes = []byte{rfc6962LeafPrefix, rfc6962NodePrefix}
+
+// leafHasherProvider is implemented by hashers that require a specific leaf hasher. The tree and the validator use it
+// as leaf hasher instead of ValueLeafs if no leaf hasher is set.
+type leafHasherProvider interface {
+	leafHasher() LeafHasher
+}
+
+// rfc6962Hashes is the pool of SHA-256 instances shared by the node and leaf hasher of RFC 6962.
+type rfc6962Hashes struct {
+	pool *sync.Pool
+}
+
+func newRFC6962Hashes() rfc6962Hashes {
+	return rfc6962Hashes{
+		pool: &sync.Pool{
+			New: func() any {
+				return sha256.New()
+			},
+		},
+	}
+}
+
+// hash computes SHA-256(prefix || a || b) in buf.
+func (r rfc6962Hashes) hash(buf []byte, prefix byte, a, b []byte) []byte {
+	// Use the sync.Pool to get a hash.Hash instance. The cast is safe, since we control the pool
+	h := r.pool.Get().(hash.Hash)
+	defer r.pool.Put(h)
+	defer h.Reset()
+
+	h.Write(rfc6962Prefixes[prefix : prefix+1])
+	h.Write(a)
+	h.Write(b)
+	return h.Sum(buf[:0])
+}
+
+type rfc6962Hasher struct {
+	rfc6962Hashes
+}
+
+func (rfc6962Hasher) Size() int {
+	return sha256.Size
+}
+
+func (r *rfc6962Hasher) Hash(buf, lChild, rChild []byte) []byte {
+	return r.hash(buf, rfc6962NodePrefix, lChild, rChild)
+}
+
+func (r *rfc6962Hasher) leafHasher() LeafHasher {
+	return &rfc6962LeafHasher{r.rfc6962Hashes}
+}
+
+// RFC6962Hasher returns a Hasher that computes interior nodes like Certificate Transparency (RFC 6962, section 2.1):
+// SHA-256(0x01 || lChild || rChild). Trees and validators using it hash their leaves with RFC6962LeafHasher, i.e.
+// SHA-256(0x00 || data), unless a different leaf hasher is set. The distinct prefixes separate the domains of leaves
+// and interior nodes, so an interior node cannot be passed off as a leaf (second-preimage attack).
+//
+// Setting a different leaf hasher, e.g. ValueLeafs, breaks the domain separation: the leaves are then no longer
+// distinguishable from interior nodes.
+//
+// The roots match those of Certificate Transparency logs for trees with a power of two number of leaves. For other
+// sizes they differ, since this package pads missing siblings while RFC 6962 promotes nodes without a sibling to the
+// next layer.
+func RFC6962Hasher() Hasher {
+	return &rfc6962Hasher{newRFC6962Hashes()}
+}
+
+type rfc6962LeafHasher struct {
+	rfc6962Hashes
+}
+
+func (rfc6962LeafHasher) Size() int {
+	return sha256.Size
+}
+
+func (rfc6962LeafHasher) Sequential() bool {
+	return false
+}
+
+func (r *rfc6962LeafHasher) Hash(buf, data []byte, _ [][]byte) []byte {
+	return r.hash(buf, rfc6962LeafPrefix, data, nil)
+}
+
+// RFC6962LeafHasher returns a LeafHasher that computes the leaf hash like Certificate Transparency (RFC 6962, section
+// 2.1): SHA-256(0x00 || data). The data can be of arbitrary length. It is the default leaf hasher of trees and
+// validators using RFC6962Hasher.
+func RFC6962LeafHasher() LeafHasher {
+	return &rfc6962LeafHasher{newRFC6962Hashes()}
+}
+
+// CTTreeHeadSignatureInput returns the data that is signed for a Certificate Transparency Signed Tree Head (STH) as
+// specified in RFC 6962, section 3.5: the TreeHeadSignature structure with version v1, signature type tree_hash, the
+// timestamp in milliseconds since the Unix epoch, the tree size and the SHA-256 root hash, all integers big-endian.
+//
+// The root has to be a 32 byte SHA-256 root hash, CTTreeHeadSignatureInput panics if it has a different size.
+func CTTreeHeadSignatureInput(timestamp, treeSize uint64, root []byte) []byte {
+	if len(root) != sha256.Size {
+		panic(fmt.Sprintf("merkle: invalid CT root hash size %d, expected %d", len(root), sha256.Size))
+	}
+
+	input := make([]byte, 0, 2+8+8+sha256.Size)
+	input = append(input, ctVersionV1, ctSignatureTypeHead)
+	input = binary.BigEndian.AppendUint64(input, timestamp)
+	input = binary.BigEndian.AppendUint64(input, treeSize)
+	return append(input, root...)
+}
+
+// CTTreeHeadHash returns the SHA-256 hash of the Signed Tree Head input returned by CTTreeHeadSignatureInput. This is
+// the digest that is signed by signers that expect a pre-hashed message, e.g. ECDSA with P-256 as used by CT logs.
+func CTTreeHeadHash(timestamp, treeSize uint64, root []byte) []byte {
+	hash := sha256.Sum256(CTTreeHeadSignatureInput(timestamp, treeSize, root))
+	return hash[:]
+}
@@ -0,0 +1,137 @@
+package merkle_test
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"testing"
+
+	"github.com/fasmat/merkle"
+)
+
+func TestCTTreeHeadHash(t *testing.T) {
+	t.Parallel()
+
+	// STH of the empty tree: the root is the SHA-256 hash of the empty string (RFC 6962, section 2.1)
+	root, _ := hex.DecodeString("e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855")
+	timestamp := uint64(1396877652123)
+
+	expectedInput := "00" + // version v1
+		"01" + // signature type tree_hash
+		"000001453c65709b" + // timestamp
+		"0000000000000000" + // tree size
+		"e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+	input := merkle.CTTreeHeadSignatureInput(timestamp, 0, root)
+	if hex.EncodeToString(input) != expectedInput {
+		t.Fatalf("Expected input to be %s, got %x", expectedInput, input)
+	}
+
+	expectedHash := sha256.Sum256(input)
+	if hash := merkle.CTTreeHeadHash(timestamp, 0, root); !bytes.Equal(hash, expectedHash[:]) {
+		t.Errorf("Expected hash to be %x, got %x", expectedHash, hash)
+	}
+	if hash := merkle.CTTreeHeadHash(timestamp, 1, root); bytes.Equal(hash, expectedHash[:]) {
+		t.Error("Expected hash to depend on the tree size")
+	}
+}
+
+func TestCTTreeHeadHashInvalidRoot(t *testing.T) {
+	t.Parallel()
+
+	defer func() {
+		if recover() == nil {
+			t.Error("Expected CTTreeHeadHash to panic")
+		}
+	}()
+	merkle.CTTreeHeadHash(0, 0, make([]byte, 16))
+}
+
+// ctLeaves are the leaves of the Merkle tree test vectors of Certificate Transparency.
+var ctLeaves = []string{
+	"",
+	"00",
+	"10",
+	"2021",
+	"3031",
+	"40414243",
+	"5051525354555657",
+	"606162636465666768696a6b6c6d6e6f",
+}
+
+func TestRFC6962Hasher(t *testing.T) {
+	t.Parallel()
+
+	tt := []struct {
+		leaves   int
+		expected string
+	}{
+		{leaves: 1, expected: "6e340b9cffb37a989ca544e6bb780a2c78901d3fb33738768511a30617afa01d"},
+		{leaves: 2, expected: "fac54203e7cc696cf0dfcb42c92a1d9dbaf70ad9e621f4bd8d98662f00e3c125"},
+		{leaves: 4, expected: "d37ee418976dd95753c1c73862b9398fa2a2cf9b4ff0fdfe8b30cd95209614b7"},
+		{leaves: 8, expected: "5dc9da79a70659a9ad559cb701ded9a2ab9d823aad2f4960cfe370eff4604328"},
+	}
+
+	for _, tc := range tt {
+		t.Run(fmt.Sprintf("%d leaves", tc.leaves), func(t *testing.T) {
+			t.Parallel()
+
+			tree := merkle.TreeBuilder().
+				WithHasher(merkle.RFC6962Hasher()).
+				WithLeafToProve(0).
+				Build()
+			leaves := make(map[uint64][]byte)
+			for i, l := range ctLeaves[:tc.leaves] {
+				data, _ := hex.DecodeString(l)
+				tree.Add(data)
+				if i == 0 {
+					leaves[uint64(i)] = data
+				}
+			}
+			root, proof := tree.RootAndProof()
+			if hex.EncodeToString(root) != tc.expected {
+				t.Fatalf("Expected root to be %s, got %x", tc.expected, root)
+			}
+
+			valid, err := merkle.ValidateProof(root, leaves, proof, merkle.WithHasher(merkle.RFC6962Hasher()))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !valid {
+				t.Error("proof is not valid")
+			}
+		})
+	}
+}
+
+func TestRFC6962HasherLeafHasher(t *testing.T) {
+	t.Parallel()
+
+	build := func(tb *merkle.Builder) []byte {
+		tree := tb.Build()
+		for _, l := range ctLeaves {
+			data, _ := hex.DecodeString(l)
+			tree.Add(data)
+		}
+		return tree.Root()
+	}
+
+	root := build(merkle.TreeBuilder().WithHasher(merkle.RFC6962Hasher()))
+	contentRoot := build(merkle.TreeBuilder().WithHasher(merkle.RFC6962Hasher()).WithLeafContentHashing())
+	if !bytes.Equal(root, contentRoot) {
+		t.Errorf("Expected root with content hashing to be %x, got %x", root, contentRoot)
+	}
+	explicitRoot := build(merkle.TreeBuilder().
+		WithHasher(merkle.RFC6962Hasher()).
+		WithLeafHasher(merkle.RFC6962LeafHasher()))
+	if !bytes.Equal(root, explicitRoot) {
+		t.Errorf("Expected root with explicit leaf hasher to be %x, got %x", root, explicitRoot)
+	}
+
+	// the leaf hash is SHA-256(0x00 || data)
+	data := []byte("leaf")
+	expected := sha256.Sum256(append([]byte{0x00}, data...))
+	if hash := merkle.RFC6962LeafHasher().Hash(nil, data, nil); !bytes.Equal(hash, expected[:]) {
+		t.Errorf("Expected leaf hash to be %x, got %x", expected, hash)
+	}
+}
@@ -0,0 +1,29 @@
+package merkle
+
+import "fmt"
+
+// VerifyDirectedProof validates a single leaf proof in a format that encodes the side of every sibling instead of the
+// index of the leaf, as returned by Tree.RootAndDirectedProof or used by other Merkle tree libraries that encode
+// direction bits separately. The steps are ordered from the leaf to the root.
+//
+// The index of the leaf is implied by the sides of the steps, so the proof is validated like ValidateProof with that
+// index and the hashes of the steps as proof. This means all options of ValidateProof are supported, including leaf
+// hashers that depend on the index of the leaf. Proofs with more than 64 steps are rejected with an error wrapping
+// ErrInvalidLeafIndex, since the implied index does not fit into an uint64.
+func VerifyDirectedProof(root, leaf []byte, steps []ProofStep, opts ...ValidatorOpt) (bool, error) {
+	if len(steps) > 64 {
+		return false, validationError(fmt.Errorf("%w: proof has %d steps, at most 64 are supported",
+			ErrInvalidLeafIndex, len(steps)))
+	}
+
+	var index uint64
+	proof := make([][]byte, len(steps))
+	for i, step := range steps {
+		if step.IsLeft {
+			// the node on the path is the right child
+			index |= 1 << i
+		}
+		proof[i] = step.Hash
+	}
+	return ValidateProof(root, map[uint64][]byte{index: leaf}, proof, opts...)
+}
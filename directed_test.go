@@ -0,0 +1,102 @@
+package merkle_test
+
+import (
+	"encoding/hex"
+	"errors"
+	"testing"
+
+	"github.com/fasmat/merkle"
+)
+
+func TestVerifyDirectedProof(t *testing.T) {
+	t.Parallel()
+
+	root, _ := hex.DecodeString("89a0f1577268cc19b0a39c7a69f804fd140640c699585eb635ebb03c06154cce")
+	node1, _ := hex.DecodeString("fa670379e5c2212ed93ff09769622f81f98a91e1ec8fb114d607dd25220b9088")
+	node2, _ := hex.DecodeString("ba94ffe7edabf26ef12736f8eb5ce74d15bedb6af61444ae2906e926b1a95084")
+
+	tt := []struct {
+		name  string
+		leaf  []byte
+		steps []merkle.ProofStep
+		valid bool
+	}{
+		{
+			name: "valid",
+			leaf: leaf(4),
+			steps: []merkle.ProofStep{
+				{Hash: leaf(5), IsLeft: false},
+				{Hash: node1, IsLeft: false},
+				{Hash: node2, IsLeft: true},
+			},
+			valid: true,
+		},
+		{
+			name:  "from tree",
+			leaf:  leaf(4),
+			steps: directedProof(t, 4),
+			valid: true,
+		},
+		{
+			name: "wrong side",
+			leaf: leaf(4),
+			steps: []merkle.ProofStep{
+				{Hash: leaf(5), IsLeft: true},
+				{Hash: node1, IsLeft: false},
+				{Hash: node2, IsLeft: true},
+			},
+			valid: false,
+		},
+		{
+			name: "wrong leaf",
+			leaf: leaf(3),
+			steps: []merkle.ProofStep{
+				{Hash: leaf(5), IsLeft: false},
+				{Hash: node1, IsLeft: false},
+				{Hash: node2, IsLeft: true},
+			},
+			valid: false,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			valid, err := merkle.VerifyDirectedProof(root, tc.leaf, tc.steps)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if valid != tc.valid {
+				t.Errorf("Expected valid to be %v, got %v", tc.valid, valid)
+			}
+		})
+	}
+}
+
+func TestVerifyDirectedProofTooManySteps(t *testing.T) {
+	t.Parallel()
+
+	steps := make([]merkle.ProofStep, 65)
+	for i := range steps {
+		steps[i] = merkle.ProofStep{Hash: leaf(uint64(i)), IsLeft: false}
+	}
+	_, err := merkle.VerifyDirectedProof(make([]byte, 32), leaf(0), steps)
+	if !errors.Is(err, merkle.ErrInvalidLeafIndex) {
+		t.Errorf("Expected error %v, got %v", merkle.ErrInvalidLeafIndex, err)
+	}
+}
+
+func directedProof(t *testing.T, index uint64) []merkle.ProofStep {
+	t.Helper()
+
+	tree := merkle.TreeBuilder().WithLeafToProve(index).Build()
+	for i := range uint64(8) {
+		tree.Add(leaf(i))
+	}
+	_, steps := tree.RootAndDirectedProof(index)
+	if steps == nil {
+		t.Fatal("Expected directed proof")
+	}
+	return steps
+}
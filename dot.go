@@ -0,0 +1,129 @@
+package merkle
+
+import (
+	"encoding/hex"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// dotWriter collects the nodes and edges of a DOT graph.
+type dotWriter struct {
+	b      strings.Builder
+	nextID int
+}
+
+// newDOTWriter returns a dotWriter with the header of the graph written.
+func newDOTWriter() *dotWriter {
+	d := &dotWriter{}
+	d.b.WriteString("digraph merkle {\n\tnode [shape=box];\n")
+	return d
+}
+
+// writeTo closes the graph and writes it to w.
+func (d *dotWriter) writeTo(w io.Writer) error {
+	d.b.WriteString("}\n")
+	_, err := io.WriteString(w, d.b.String())
+	return err
+}
+
+// node adds a node with the given label and style to the graph and returns its id.
+func (d *dotWriter) node(label, style string) int {
+	id := d.nextID
+	d.nextID++
+	fmt.Fprintf(&d.b, "\tn%d [label=%q, style=%s];\n", id, label, style)
+	return id
+}
+
+// hashNode adds a node for the hash at the given height to the graph and returns its id.
+func (d *dotWriter) hashNode(height int, hash []byte) int {
+	label := fmt.Sprintf("h%d %s", height, hex.EncodeToString(hash))
+	if len(hash) > 4 {
+		label = fmt.Sprintf("h%d %s...", height, hex.EncodeToString(hash[:4]))
+	}
+	return d.node(label, "solid")
+}
+
+// parent adds a node for the hash at the given height with the given children to the graph and returns its id.
+func (d *dotWriter) parent(height int, hash []byte, lChild, rChild int) int {
+	id := d.hashNode(height, hash)
+	fmt.Fprintf(&d.b, "\tn%d -> n%d;\n\tn%d -> n%d;\n", id, lChild, id, rChild)
+	return id
+}
+
+// WriteDOT writes the structure of the tree as Graphviz DOT graph to w. Nodes are labeled with their height and the
+// first bytes of their hash in hex, padding nodes are drawn dashed.
+//
+// The tree does not retain its layers, so the graph only contains what is available: the roots of the balanced
+// subtrees added so far (the parked nodes), the padding nodes and the nodes that combine them into the root of the
+// tree. Neither the leaves nor the nodes below the parked nodes are included, for a balanced tree the graph consists of
+// the root only. To render every node of a tree collect its layers with Builder.WithFullNodeSink while the tree is
+// built and pass them to WriteLayersDOT.
+func (t *Tree) WriteDOT(w io.Writer) error {
+	d := newDOTWriter()
+
+	// Walk up the layers like rootAndProof, without emitting nodes or modifying the parked nodes
+	var root []byte
+	rootID := -1
+	for height, parkedNode := range t.parkedNodes {
+		if parkedNode != nil && root == nil && height == len(t.parkedNodes)-1 {
+			root = parkedNode
+			rootID = d.hashNode(height, parkedNode)
+			break
+		}
+
+		switch {
+		case parkedNode != nil && root != nil:
+			lChild := d.hashNode(height, parkedNode)
+			root = t.hasherAt(height+1).Hash(nil, parkedNode, root)
+			rootID = d.parent(height+1, root, lChild, rootID)
+		case parkedNode != nil:
+			lChild := d.hashNode(height, parkedNode)
+			root = t.hasherAt(height+1).Hash(nil, parkedNode, t.paddingAt(height))
+			rootID = d.parent(height+1, root, lChild, d.node("padding", "dashed"))
+		case root != nil:
+			rChild := d.node("padding", "dashed")
+			root = t.hasherAt(height+1).Hash(nil, root, t.paddingAt(height))
+			rootID = d.parent(height+1, root, rootID, rChild)
+		}
+	}
+	for i := len(t.parkedNodes); i < int(t.minHeight); i++ {
+		if rootID < 0 {
+			rootID = d.node("empty", "dashed")
+		}
+		rChild := d.node("padding", "dashed")
+		root = t.hasherAt(i+1).Hash(nil, root, t.paddingAt(i))
+		rootID = d.parent(i+1, root, rootID, rChild)
+	}
+
+	return d.writeTo(w)
+}
+
+// WriteLayersDOT writes the tree with the given layers as Graphviz DOT graph to w, including its leaves and all
+// interior nodes. The layers have the format of TreeFromLayers: layers[0] contains the leaves, every following layer
+// the parents of the previous one and the last layer the root. Nodes are labeled like by Tree.WriteDOT, a node without
+// right sibling is drawn with a dashed padding node as right child.
+//
+// The hashes of the layers are not checked, use TreeFromLayers for that. An error wrapping ErrInvalidLayers is returned
+// if a layer has more nodes than its children.
+func WriteLayersDOT(w io.Writer, layers [][][]byte) error {
+	d := newDOTWriter()
+	var children []int
+	for height, layer := range layers {
+		ids := make([]int, len(layer))
+		for i, hash := range layer {
+			switch {
+			case height == 0:
+				ids[i] = d.hashNode(height, hash)
+			case 2*i >= len(children):
+				return fmt.Errorf("%w: layer %d has more than %d nodes", ErrInvalidLayers, height, (len(children)+1)/2)
+			case 2*i+1 < len(children):
+				ids[i] = d.parent(height, hash, children[2*i], children[2*i+1])
+			default:
+				ids[i] = d.parent(height, hash, children[2*i], d.node("padding", "dashed"))
+			}
+		}
+		children = ids
+	}
+	return d.writeTo(w)
+}
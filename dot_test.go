@@ -0,0 +1,166 @@
+package merkle_test
+
+import (
+	"bytes"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/fasmat/merkle"
+)
+
+func TestTreeWriteDOT(t *testing.T) {
+	t.Parallel()
+
+	tt := []struct {
+		name    string
+		leaves  uint64
+		nodes   int
+		edges   int
+		padding int
+		root    string
+	}{
+		{
+			// the tree does not retain its layers, see TestWriteLayersDOT for the full graph
+			name:   "balanced",
+			leaves: 8,
+			nodes:  1,
+			root:   "h3 89a0f157...",
+		},
+		{
+			name:    "unbalanced",
+			leaves:  10,
+			nodes:   7,
+			edges:   6,
+			padding: 2,
+			root:    "h4 59f32a43...",
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			tree := merkle.NewTree()
+			for i := range tc.leaves {
+				tree.Add(leaf(i))
+			}
+			root := tree.Root()
+
+			var buf bytes.Buffer
+			if err := tree.WriteDOT(&buf); err != nil {
+				t.Fatal(err)
+			}
+			dot := buf.String()
+
+			if !strings.HasPrefix(dot, "digraph merkle {") || !strings.HasSuffix(dot, "}\n") {
+				t.Fatalf("Expected a DOT graph, got %q", dot)
+			}
+			if n := strings.Count(dot, "[label="); n != tc.nodes {
+				t.Errorf("Expected %d nodes, got %d", tc.nodes, n)
+			}
+			if n := strings.Count(dot, "->"); n != tc.edges {
+				t.Errorf("Expected %d edges, got %d", tc.edges, n)
+			}
+			if n := strings.Count(dot, "style=dashed"); n != tc.padding {
+				t.Errorf("Expected %d padding nodes, got %d", tc.padding, n)
+			}
+			if !strings.Contains(dot, tc.root) {
+				t.Errorf("Expected graph to contain root %q, got %q", tc.root, dot)
+			}
+			if !bytes.Equal(tree.Root(), root) {
+				t.Error("WriteDOT modified the tree")
+			}
+		})
+	}
+}
+
+func TestWriteLayersDOT(t *testing.T) {
+	t.Parallel()
+
+	var layers [][][]byte
+	tree := merkle.TreeBuilder().WithFullNodeSink(func(height int, index uint64, hash []byte) {
+		for len(layers) <= height {
+			layers = append(layers, nil)
+		}
+		if uint64(len(layers[height])) == index {
+			layers[height] = append(layers[height], slices.Clone(hash))
+		}
+	}).Build()
+	leaves := make([][]byte, 10)
+	for i := range leaves {
+		leaves[i] = leaf(uint64(i))
+		tree.Add(leaves[i])
+	}
+	tree.Root() // report the nodes that depend on padding to the sink
+	layers[0] = leaves
+
+	tt := []struct {
+		name     string
+		layers   [][][]byte
+		leaves   int
+		interior int
+		padding  int
+		root     string
+	}{
+		{
+			name:     "balanced",
+			layers:   knownLayers(),
+			leaves:   8,
+			interior: 7,
+			root:     "h3 89a0f157...",
+		},
+		{
+			name:     "unbalanced",
+			layers:   layers,
+			leaves:   10,
+			interior: 11,
+			padding:  2,
+			root:     "h4 59f32a43...",
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			var buf bytes.Buffer
+			if err := merkle.WriteLayersDOT(&buf, tc.layers); err != nil {
+				t.Fatal(err)
+			}
+			dot := buf.String()
+
+			if !strings.HasPrefix(dot, "digraph merkle {") || !strings.HasSuffix(dot, "}\n") {
+				t.Fatalf("Expected a DOT graph, got %q", dot)
+			}
+			if n := strings.Count(dot, "label=\"h0 "); n != tc.leaves {
+				t.Errorf("Expected %d leaves, got %d", tc.leaves, n)
+			}
+			if n := strings.Count(dot, "[label=") - tc.leaves - tc.padding; n != tc.interior {
+				t.Errorf("Expected %d interior nodes, got %d", tc.interior, n)
+			}
+			if n := strings.Count(dot, "->"); n != 2*tc.interior {
+				t.Errorf("Expected %d edges, got %d", 2*tc.interior, n)
+			}
+			if n := strings.Count(dot, "style=dashed"); n != tc.padding {
+				t.Errorf("Expected %d padding nodes, got %d", tc.padding, n)
+			}
+			if !strings.Contains(dot, tc.root) {
+				t.Errorf("Expected graph to contain root %q, got %q", tc.root, dot)
+			}
+		})
+	}
+}
+
+func TestWriteLayersDOTInvalid(t *testing.T) {
+	t.Parallel()
+
+	layers := knownLayers()
+	layers[2] = append(layers[2], layers[2][0], layers[2][1])
+
+	var buf bytes.Buffer
+	if err := merkle.WriteLayersDOT(&buf, layers); !errors.Is(err, merkle.ErrInvalidLayers) {
+		t.Errorf("Expected error %v, got %v", merkle.ErrInvalidLayers, err)
+	}
+}
@@ -0,0 +1,11 @@
+package merkle
+
+// CorruptProof flips the bits of the first byte of the first proof node collected by the tree.
+func CorruptProof(t *Tree) {
+	t.proof[0][0] ^= 0xff
+}
+
+// SetLeafCount sets the number of leaves added to the tree without adding them.
+func SetLeafCount(t *Tree, count uint64) {
+	t.currentLeaf = count
+}
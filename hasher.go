@@ -1,11 +1,26 @@
 package merkle
 
 import (
+	"crypto"
+	"crypto/hmac"
 	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/binary"
+	"errors"
+	"fmt"
 	"hash"
+	"slices"
 	"sync"
 )
 
+var (
+	// ErrLeafTooLarge is returned when a leaf exceeds the maximum size allowed by its leaf hasher.
+	ErrLeafTooLarge = errors.New("leaf too large")
+
+	// ErrMissingNonce is returned by the validator when no nonce is known for a leaf hashed with NoncedLeafHasher.
+	ErrMissingNonce = errors.New("missing leaf nonce")
+)
+
 // Hasher is an interface for calculating the parent node from two child nodes.
 type Hasher interface {
 	// Hash computes the hash of the given child hashes.
@@ -18,6 +33,28 @@ type Hasher interface {
 	Size() int
 }
 
+// hasherAt returns the hasher for the nodes at the given height. If no layer hasher is set the default hasher is used.
+// It panics if the layer hasher returns a hasher with a different size than the default hasher.
+func hasherAt(layerHasher func(height uint64) Hasher, height uint64, defaultHasher Hasher) Hasher {
+	if layerHasher == nil {
+		return defaultHasher
+	}
+	h := layerHasher(height)
+	if h.Size() != defaultHasher.Size() {
+		panic(fmt.Sprintf("merkle: hasher for height %d has size %d, expected %d",
+			height, h.Size(), defaultHasher.Size()))
+	}
+	return h
+}
+
+// bindSize binds the given root to the number of leaves of its tree by hashing the big-endian encoded count with the
+// root: H(count || root). The result is calculated in buf.
+func bindSize(hasher Hasher, buf []byte, count uint64, root []byte) []byte {
+	var encoded [8]byte
+	binary.BigEndian.PutUint64(encoded[:], count)
+	return hasher.Hash(buf, encoded[:], root)
+}
+
 type sha256Hasher struct {
 	pool *sync.Pool
 }
@@ -50,6 +87,229 @@ func Sha256() Hasher {
 	}
 }
 
+type sha512Hasher struct {
+	pool *sync.Pool
+}
+
+func (sha512Hasher) Size() int {
+	return sha512.Size
+}
+
+func (s *sha512Hasher) Hash(buf, lChild, rChild []byte) []byte {
+	// Use the sync.Pool to get a hash.Hash instance. The cast is safe, since we control the pool
+	h := s.pool.Get().(hash.Hash)
+	defer s.pool.Put(h)
+	defer h.Reset()
+
+	h.Write(lChild)
+	h.Write(rChild)
+	return h.Sum(buf[:0])
+}
+
+// Sha512 returns a Hasher that computes the root by concatenating the two children and hashing them with SHA512. The
+// nodes of a tree built with it are 64 bytes long. Like Sha256 it uses a sync.Pool to reuse hash.Hash instances, so
+// it can be shared by multiple trees that are built concurrently.
+func Sha512() Hasher {
+	return &sha512Hasher{
+		pool: &sync.Pool{
+			New: func() any {
+				return sha512.New()
+			},
+		},
+	}
+}
+
+type cryptoHasher struct {
+	size int
+	pool *sync.Pool
+}
+
+func (c *cryptoHasher) Size() int {
+	return c.size
+}
+
+func (c *cryptoHasher) Hash(buf, lChild, rChild []byte) []byte {
+	// Use the sync.Pool to get a hash.Hash instance. The cast is safe, since we control the pool
+	h := c.pool.Get().(hash.Hash)
+	defer c.pool.Put(h)
+	defer h.Reset()
+
+	h.Write(lChild)
+	h.Write(rChild)
+	return h.Sum(buf[:0])
+}
+
+// FromCryptoHash returns a Hasher that computes the root by concatenating the two children and hashing them with the
+// given hash function, e.g. crypto.SHA3_256 or crypto.SHA384. Like Sha256 it uses a sync.Pool to reuse hash.Hash
+// instances. The size of the nodes is the size of the hash function.
+//
+// The hash function has to be linked into the binary (see crypto.RegisterHash), e.g. by importing crypto/sha3.
+// Mirroring crypto.Hash.New, FromCryptoHash panics if it is not available.
+func FromCryptoHash(h crypto.Hash) Hasher {
+	if !h.Available() {
+		panic(fmt.Sprintf("merkle: requested hash function %v is unavailable", h))
+	}
+	return &cryptoHasher{
+		size: h.Size(),
+		pool: &sync.Pool{
+			New: func() any {
+				return h.New()
+			},
+		},
+	}
+}
+
+// HMACHasher returns a Hasher that computes the parent node as HMAC of the concatenation of the two children with the
+// given key and hash function, e.g. sha256.New: HMAC(key, lChild || rChild). This ties the structure of the tree to
+// the secret key, so roots cannot be computed (or precomputed) without it. The size of the nodes is the size of the
+// hash function.
+//
+// HMAC instances are reused with a sync.Pool, so the hasher can be shared by multiple trees that are built
+// concurrently. To validate proofs of a tree built with this hasher the validator has to use a hasher with the same
+// key and hash function.
+func HMACHasher(key []byte, h func() hash.Hash) Hasher {
+	key = slices.Clone(key)
+	return &cryptoHasher{
+		size: h().Size(),
+		pool: &sync.Pool{
+			New: func() any {
+				return hmac.New(h, key)
+			},
+		},
+	}
+}
+
+type truncatedSha256Hasher struct {
+	size int
+	pool *sync.Pool
+}
+
+// truncatedSha256State is a hash.Hash instance with a buffer for the full digest.
+type truncatedSha256State struct {
+	h      hash.Hash
+	digest [sha256.Size]byte
+}
+
+func (t *truncatedSha256Hasher) Size() int {
+	return t.size
+}
+
+func (t *truncatedSha256Hasher) Hash(buf, lChild, rChild []byte) []byte {
+	// Use the sync.Pool to get a hash.Hash instance. The cast is safe, since we control the pool
+	s := t.pool.Get().(*truncatedSha256State)
+	defer t.pool.Put(s)
+	defer s.h.Reset()
+
+	s.h.Write(lChild)
+	s.h.Write(rChild)
+	digest := s.h.Sum(s.digest[:0])
+	return append(buf[:0], digest[:t.size]...)
+}
+
+// TruncatedSha256 returns a Hasher that computes the parent node like Sha256, but only keeps the first n bytes of the
+// digest. The nodes of a tree built with it are n bytes long, which reduces the size of proofs at the cost of
+// collision resistance. Unless a leaf hasher is set the leaves have to be n bytes long as well.
+//
+// TruncatedSha256 panics if n is not between 1 and 32.
+func TruncatedSha256(n int) Hasher {
+	if n < 1 || n > sha256.Size {
+		panic(fmt.Sprintf("merkle: invalid truncated SHA256 size %d, must be between 1 and %d", n, sha256.Size))
+	}
+	return &truncatedSha256Hasher{
+		size: n,
+		pool: &sync.Pool{
+			New: func() any {
+				return &truncatedSha256State{h: sha256.New()}
+			},
+		},
+	}
+}
+
+type boundedSha256Hasher struct {
+	pool chan hash.Hash
+}
+
+func (boundedSha256Hasher) Size() int {
+	return sha256.Size
+}
+
+func (s *boundedSha256Hasher) Hash(buf, lChild, rChild []byte) []byte {
+	var h hash.Hash
+	select {
+	case h = <-s.pool:
+	default:
+		h = sha256.New()
+	}
+	defer func() {
+		h.Reset()
+		select {
+		case s.pool <- h:
+		default:
+			// pool is full, drop the instance
+		}
+	}()
+
+	h.Write(lChild)
+	h.Write(rChild)
+	return h.Sum(buf[:0])
+}
+
+// Sha256WithPoolSize returns a Hasher that computes the same hashes as Sha256 but uses a bounded pool of at most size
+// hash.Hash instances instead of a sync.Pool. Instances are created on demand when the pool is empty and discarded
+// when it is full.
+//
+// Unlike a sync.Pool the bounded pool is not cleared by the garbage collector, which avoids re-allocating instances
+// under sustained high concurrency. It is recommended to set size to the expected number of goroutines hashing
+// concurrently (e.g. runtime.GOMAXPROCS(0)).
+//
+// A size of 0 disables pooling: every call to Hash creates a new instance. Sha256WithPoolSize panics if size is
+// negative.
+func Sha256WithPoolSize(size int) Hasher {
+	if size < 0 {
+		panic(fmt.Sprintf("merkle: invalid pool size %d, must not be negative", size))
+	}
+	return &boundedSha256Hasher{
+		pool: make(chan hash.Hash, size),
+	}
+}
+
+type suffixedHasher struct {
+	hasher Hasher
+	suffix []byte
+	pool   *sync.Pool
+}
+
+func (s *suffixedHasher) Size() int {
+	return s.hasher.Size()
+}
+
+func (s *suffixedHasher) Hash(buf, lChild, rChild []byte) []byte {
+	// Use the sync.Pool to get a scratch buffer. The cast is safe, since we control the pool
+	scratch := s.pool.Get().(*[]byte)
+	defer s.pool.Put(scratch)
+
+	*scratch = append(append((*scratch)[:0], rChild...), s.suffix...)
+	return s.hasher.Hash(buf, lChild, *scratch)
+}
+
+// SuffixedHasher returns a Hasher that appends the given suffix to the two children before hashing them with the given
+// Hasher, i.e. the parent is computed as H(lChild || rChild || suffix). This can be used to bind the nodes of the tree
+// to a version or separator. The given Hasher has to hash the concatenation of its children, which is the case for
+// all hashers provided by this package.
+//
+// To validate proofs of a tree built with this hasher pass the same hasher to the validator with WithHasher.
+func SuffixedHasher(h Hasher, suffix []byte) Hasher {
+	return &suffixedHasher{
+		hasher: h,
+		suffix: slices.Clone(suffix),
+		pool: &sync.Pool{
+			New: func() any {
+				return new([]byte)
+			},
+		},
+	}
+}
+
 // LeafHasher is an interface for calculating the hash of the leaf from its data and (optionally) from its left siblings
 // on the path to the root.
 // Hashing the left siblings ensures that the merkle tree is built sequentially and parallelization of hashing is not
@@ -69,6 +329,26 @@ type LeafHasher interface {
 	Size() int
 }
 
+// IndexedLeafHasher is a LeafHasher whose leaf hash also depends on the index of the leaf. If the leaf hasher of a
+// tree or validator implements this interface HashIndexed is used instead of Hash.
+//
+// The index is the position of the leaf in the tree relative to its first leaf (see Builder.WithLeafIndexOffset),
+// which is the same index the validator uses for the leaves of a proof of the tree.
+type IndexedLeafHasher interface {
+	LeafHasher
+
+	// HashIndexed computes the hash of the leaf with the given index like Hash.
+	HashIndexed(buf []byte, index uint64, data []byte, leftSiblings [][]byte) []byte
+}
+
+// hashLeaf computes the hash of the leaf with the given index using HashIndexed if the leaf hasher supports it.
+func hashLeaf(leafHasher LeafHasher, buf []byte, index uint64, data []byte, leftSiblings [][]byte) []byte {
+	if h, ok := leafHasher.(IndexedLeafHasher); ok {
+		return h.HashIndexed(buf, index, data, leftSiblings)
+	}
+	return leafHasher.Hash(buf, data, leftSiblings)
+}
+
 type valueLeafs struct {
 	size int
 }
@@ -92,14 +372,183 @@ func (valueLeafs) Hash(buf, data []byte, _ [][]byte) []byte {
 // The LeafHasher will copy the data passed to Add(). For this uses a buffer of the given size. You can specify the
 // size of the buffer that is used. To avoid unnecessary re-allocations it should be large enough to hold any leaf you
 // want to add.
+//
+// An empty value results in an empty leaf, which is hashed like any other node, i.e. its parent is the hash of only
+// its sibling. It is not treated as padding.
 func ValueLeafs(size int) LeafHasher {
 	return &valueLeafs{
 		size: size,
 	}
 }
 
+// LeafChecker can optionally be implemented by a LeafHasher to reject leaves it cannot hash. It is used by
+// Tree.AddChecked and by the validator before any leaf is hashed.
+type LeafChecker interface {
+	// Check returns an error if the given data cannot be used as leaf.
+	Check(data []byte) error
+}
+
+type boundedValueLeafs struct {
+	valueLeafs
+	maxSize int
+}
+
+func (b *boundedValueLeafs) Check(data []byte) error {
+	if len(data) > b.maxSize {
+		return fmt.Errorf("%w: leaf has %d bytes, maximum is %d bytes", ErrLeafTooLarge, len(data), b.maxSize)
+	}
+	return nil
+}
+
+func (b *boundedValueLeafs) Hash(buf, data []byte, leftSiblings [][]byte) []byte {
+	if err := b.Check(data); err != nil {
+		panic(fmt.Sprintf("merkle: %v", err))
+	}
+	return b.valueLeafs.Hash(buf, data, leftSiblings)
+}
+
+// BoundedValueLeafs returns a LeafHasher like ValueLeafs that rejects leaves larger than maxSize bytes. This prevents
+// a single oversized leaf from allocating an arbitrarily large buffer.
+//
+// Use Tree.AddChecked to add leaves to a tree using this leaf hasher, it returns an error wrapping ErrLeafTooLarge
+// for oversized leaves. Tree.Add panics instead. The validator returns the same error if a proven leaf is too large.
+func BoundedValueLeafs(size, maxSize int) LeafHasher {
+	return &boundedValueLeafs{
+		valueLeafs: valueLeafs{
+			size: size,
+		},
+		maxSize: maxSize,
+	}
+}
+
+type contentLeafs struct {
+	hasher Hasher
+}
+
+func (c *contentLeafs) Size() int {
+	return c.hasher.Size()
+}
+
+func (contentLeafs) Sequential() bool {
+	return false
+}
+
+func (c *contentLeafs) Hash(buf, data []byte, _ [][]byte) []byte {
+	return c.hasher.Hash(buf, data, nil)
+}
+
+// ContentHasher returns a LeafHasher that hashes the data of a leaf with the given Hasher to obtain the leaf node.
+// In contrast to ValueLeafs the data can be of arbitrary length. It is the leaf hasher used by
+// Builder.WithLeafContentHashing and can be passed to the validator with WithLeafHasher to validate proofs where the
+// proven leaves are the raw data instead of their hashes.
+func ContentHasher(h Hasher) LeafHasher {
+	return &contentLeafs{
+		hasher: h,
+	}
+}
+
+type counterLeafs struct {
+	hasher Hasher
+	encode func(index uint64) []byte
+}
+
+func (c *counterLeafs) Size() int {
+	return c.hasher.Size()
+}
+
+func (c *counterLeafs) Sequential() bool {
+	return false
+}
+
+func (c *counterLeafs) Hash(_, _ []byte, _ [][]byte) []byte {
+	panic("merkle: the leaf hasher returned by CounterLeafHasher requires the index of the leaf, use HashIndexed")
+}
+
+func (c *counterLeafs) HashIndexed(buf []byte, index uint64, data []byte, _ [][]byte) []byte {
+	if c.encode != nil {
+		return c.hasher.Hash(buf, c.encode(index), data)
+	}
+	var counter [8]byte
+	binary.BigEndian.PutUint64(counter[:], index)
+	return c.hasher.Hash(buf, counter[:], data)
+}
+
+// CounterLeafHasher returns an IndexedLeafHasher that computes the leaf hash by hashing the index of the leaf (encoded
+// as 64-bit big-endian integer) followed by the data with the given hasher: H(index || data). This binds every leaf to
+// its position and thereby enforces the order in which the leaves are added, without the dependence on the left
+// siblings of SequentialWorkHasher.
+//
+// Since the leaves depend on their position, subtrees built with this hasher cannot be combined with Combine.
+func CounterLeafHasher(h Hasher) LeafHasher {
+	return CounterLeafHasherWithEncoder(h, nil)
+}
+
+// CounterLeafHasherWithEncoder returns a leaf hasher like CounterLeafHasher that encodes the index of the leaf with the
+// given function, e.g. to match an external specification that uses little-endian or varint encoded indices. If
+// encode is nil the index is encoded as 64-bit big-endian integer. To validate proofs of a tree built with this hasher
+// the validator has to use a hasher with the same encoding.
+func CounterLeafHasherWithEncoder(h Hasher, encode func(index uint64) []byte) LeafHasher {
+	return &counterLeafs{
+		hasher: h,
+		encode: encode,
+	}
+}
+
+// indexChecker can be implemented by an IndexedLeafHasher to reject leaf indices it cannot hash. It is used by the
+// validator before any leaf is hashed.
+type indexChecker interface {
+	checkIndex(index uint64) error
+}
+
+type noncedLeafs struct {
+	hasher Hasher
+	nonces map[uint64][]byte
+}
+
+func (n *noncedLeafs) Size() int {
+	return n.hasher.Size()
+}
+
+func (n *noncedLeafs) Sequential() bool {
+	return false
+}
+
+func (n *noncedLeafs) Hash(_, _ []byte, _ [][]byte) []byte {
+	panic("merkle: the leaf hasher returned by NoncedLeafHasher requires the index of the leaf, use HashIndexed")
+}
+
+func (n *noncedLeafs) checkIndex(index uint64) error {
+	if _, ok := n.nonces[index]; !ok {
+		return fmt.Errorf("%w: leaf %d", ErrMissingNonce, index)
+	}
+	return nil
+}
+
+func (n *noncedLeafs) HashIndexed(buf []byte, index uint64, data []byte, _ [][]byte) []byte {
+	nonce, ok := n.nonces[index]
+	if !ok {
+		panic(fmt.Sprintf("merkle: no nonce for leaf %d", index))
+	}
+	return n.hasher.Hash(buf, nonce, data)
+}
+
+// NoncedLeafHasher returns an IndexedLeafHasher that mixes a per-leaf nonce into the leaf hash: H(nonce || data) where
+// nonce is the value of the given map for the index of the leaf. Committing to salted leaves hides their data, e.g.
+// from the recipients of proofs for other leaves, as long as the nonces are kept secret.
+//
+// The map is not copied and must not be modified while it is in use. Adding a leaf without nonce to a tree panics.
+// To validate proofs of a tree built with this hasher use WithLeafNonces with the nonces of the proven leaves.
+func NoncedLeafHasher(h Hasher, nonces map[uint64][]byte) LeafHasher {
+	return &noncedLeafs{
+		hasher: h,
+		nonces: nonces,
+	}
+}
+
 type sequentialWorkHasher struct {
-	pool *sync.Pool
+	pool           *sync.Pool
+	order          Order
+	lengthPrefixed bool
 }
 
 func (sequentialWorkHasher) Size() int {
@@ -116,22 +565,68 @@ func (s *sequentialWorkHasher) Hash(buf, data []byte, parkingNodes [][]byte) []b
 	defer s.pool.Put(h)
 	defer h.Reset()
 
-	h.Write(data)
+	if s.order == OrderDataFirst {
+		s.writeData(h, data)
+	}
 	for i := range parkingNodes {
 		h.Write(parkingNodes[i])
 	}
+	if s.order == OrderParkingNodesFirst {
+		s.writeData(h, data)
+	}
 	return h.Sum(buf[:0])
 }
 
+// writeData writes the data to h, prefixed with its length if configured.
+func (s *sequentialWorkHasher) writeData(h hash.Hash, data []byte) {
+	if s.lengthPrefixed {
+		var length [8]byte
+		binary.BigEndian.PutUint64(length[:], uint64(len(data)))
+		h.Write(length[:])
+	}
+	h.Write(data)
+}
+
 // SequentialWorkHasher returns a LeafHasher that computes the leaf hash by concatenating the data and the parking nodes
 // and hashing them with SHA256. It uses a sync.Pool to reuse hash.Hash instances for efficiency while still allowing
 // multiple trees to be built concurrently using the same underlying hasher.
+//
+// An empty value is allowed, in this case the leaf hash is the hash of only the parking nodes.
+//
+// SequentialWorkHasher is equivalent to SequentialWorkHasherOrdered(OrderDataFirst, false).
 func SequentialWorkHasher() LeafHasher {
+	return SequentialWorkHasherOrdered(OrderDataFirst, false)
+}
+
+// Order is the order in which the data of a leaf and the parking nodes are hashed by SequentialWorkHasherOrdered.
+type Order int
+
+const (
+	// OrderDataFirst hashes the data of the leaf followed by the parking nodes.
+	OrderDataFirst Order = iota
+
+	// OrderParkingNodesFirst hashes the parking nodes followed by the data of the leaf.
+	OrderParkingNodesFirst
+)
+
+// SequentialWorkHasherOrdered returns a LeafHasher like SequentialWorkHasher that hashes the data and the parking
+// nodes in the given order. If lengthPrefixed is true the data is prefixed with its length encoded as 64-bit
+// big-endian integer, like the index encoded by CounterLeafHasher. Since the parking nodes all have the size of a node
+// this makes the encoding of the hashed values unambiguous for data of variable length.
+//
+// To validate proofs of a tree built with this hasher the validator has to use a hasher with the same order and
+// length prefixing. SequentialWorkHasherOrdered panics if order is not one of the defined orders.
+func SequentialWorkHasherOrdered(order Order, lengthPrefixed bool) LeafHasher {
+	if order != OrderDataFirst && order != OrderParkingNodesFirst {
+		panic(fmt.Sprintf("merkle: invalid order %d", order))
+	}
 	return &sequentialWorkHasher{
 		pool: &sync.Pool{
 			New: func() any {
 				return sha256.New()
 			},
 		},
+		order:          order,
+		lengthPrefixed: lengthPrefixed,
 	}
 }
@@ -1,9 +1,17 @@
 package merkle_test
 
 import (
+	"bytes"
+	"crypto"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/sha512"
 	"encoding/binary"
 	"encoding/hex"
+	"errors"
 	"fmt"
+	"runtime"
+	"slices"
 	"testing"
 
 	"github.com/fasmat/merkle"
@@ -179,3 +187,715 @@ func TestExampleBuilder_WithLeafHasher_Detailed(t *testing.T) {
 		)
 	}
 }
+
+func TestSha256WithPoolSize(t *testing.T) {
+	t.Parallel()
+
+	lChild := make([]byte, 32)
+	binary.LittleEndian.PutUint64(lChild, 0)
+	rChild := make([]byte, 32)
+	binary.LittleEndian.PutUint64(rChild, 1)
+
+	hasher := merkle.Sha256WithPoolSize(1)
+	for range 3 {
+		rootString := hex.EncodeToString(hasher.Hash(nil, lChild, rChild))
+		if rootString != "cb592844121d926f1ca3ad4e1d6fb9d8e260ed6e3216361f7732e975a0e8bbf6" {
+			t.Errorf(
+				"Expected hash to be cb592844121d926f1ca3ad4e1d6fb9d8e260ed6e3216361f7732e975a0e8bbf6, got %s",
+				rootString,
+			)
+		}
+	}
+
+	tree := merkle.TreeBuilder().
+		WithHasher(merkle.Sha256WithPoolSize(runtime.GOMAXPROCS(0))).
+		Build()
+	buf := make([]byte, tree.NodeSize())
+	for i := range 8 {
+		binary.LittleEndian.PutUint64(buf, uint64(i))
+		tree.Add(buf)
+	}
+
+	rootString := hex.EncodeToString(tree.Root())
+	if rootString != "89a0f1577268cc19b0a39c7a69f804fd140640c699585eb635ebb03c06154cce" {
+		t.Errorf(
+			"Expected hash to be 89a0f1577268cc19b0a39c7a69f804fd140640c699585eb635ebb03c06154cce, got %s",
+			rootString,
+		)
+	}
+}
+
+func TestSha256WithPoolSizeZero(t *testing.T) {
+	t.Parallel()
+
+	hasher := merkle.Sha256WithPoolSize(0)
+	for range 3 {
+		node := hex.EncodeToString(hasher.Hash(nil, make([]byte, 32), make([]byte, 32)))
+		expected := "f5a5fd42d16a20302798ef6ed309979b43003d2320d9f0e8ea9831a92759fb4b"
+		if node != expected {
+			t.Errorf("Expected hash to be %s, got %s", expected, node)
+		}
+	}
+}
+
+func TestSha256WithPoolSizeNegative(t *testing.T) {
+	t.Parallel()
+
+	defer func() {
+		if recover() == nil {
+			t.Error("Expected Sha256WithPoolSize to panic for a negative size")
+		}
+	}()
+	merkle.Sha256WithPoolSize(-1)
+}
+
+func TestSha512(t *testing.T) {
+	t.Parallel()
+
+	hasher := merkle.Sha512()
+	if hasher.Size() != sha512.Size {
+		t.Fatalf("Expected size %d, got %d", sha512.Size, hasher.Size())
+	}
+
+	lChild := bytes.Repeat([]byte{1}, sha512.Size)
+	rChild := bytes.Repeat([]byte{2}, sha512.Size)
+	expected := sha512.Sum512(append(slices.Clone(lChild), rChild...))
+	if node := hasher.Hash(nil, lChild, rChild); !bytes.Equal(node, expected[:]) {
+		t.Errorf("Expected hash to be %x, got %x", expected, node)
+	}
+
+	// the buffer may point to the same memory as the left child
+	buf := slices.Clone(lChild)
+	if node := hasher.Hash(buf, buf, rChild); !bytes.Equal(node, expected[:]) {
+		t.Errorf("Expected hash to be %x, got %x", expected, node)
+	}
+}
+
+func TestSha512RoundTrip(t *testing.T) {
+	t.Parallel()
+
+	tree := merkle.TreeBuilder().
+		WithHasher(merkle.Sha512()).
+		WithLeafToProve(4).
+		Build()
+	if tree.NodeSize() != sha512.Size {
+		t.Fatalf("Expected node size %d, got %d", sha512.Size, tree.NodeSize())
+	}
+
+	leaves := make(map[uint64][]byte)
+	for i := range uint64(10) {
+		buf := make([]byte, tree.NodeSize())
+		binary.LittleEndian.PutUint64(buf, i)
+		tree.Add(buf)
+		if i == 4 {
+			leaves[i] = buf
+		}
+	}
+	root, proof := tree.RootAndProof()
+	if len(root) != sha512.Size {
+		t.Errorf("Expected root of %d bytes, got %d", sha512.Size, len(root))
+	}
+
+	valid, err := merkle.ValidateProof(root, leaves, proof,
+		merkle.WithHasher(merkle.Sha512()),
+		merkle.WithNodeSizeCheck(),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !valid {
+		t.Error("proof is not valid")
+	}
+
+	valid, err = merkle.ValidateProof(root, leaves, proof, merkle.WithHasher(merkle.Sha256()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if valid {
+		t.Error("Expected proof to be invalid with SHA256")
+	}
+}
+
+func TestFromCryptoHash(t *testing.T) {
+	t.Parallel()
+
+	tree := merkle.TreeBuilder().
+		WithHasher(merkle.FromCryptoHash(crypto.SHA256)).
+		WithLeafToProve(4).
+		Build()
+	leaves := make(map[uint64][]byte)
+	for i := range uint64(8) {
+		tree.Add(leaf(i))
+		if i == 4 {
+			leaves[i] = leaf(i)
+		}
+	}
+	root, proof := tree.RootAndProof()
+
+	rootString := hex.EncodeToString(root)
+	if rootString != "89a0f1577268cc19b0a39c7a69f804fd140640c699585eb635ebb03c06154cce" {
+		t.Errorf(
+			"Expected hash to be 89a0f1577268cc19b0a39c7a69f804fd140640c699585eb635ebb03c06154cce, got %s",
+			rootString,
+		)
+	}
+
+	valid, err := merkle.ValidateProof(root, leaves, proof, merkle.WithHasher(merkle.FromCryptoHash(crypto.SHA256)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !valid {
+		t.Error("proof is not valid")
+	}
+
+	sha512Hasher := merkle.FromCryptoHash(crypto.SHA512)
+	if sha512Hasher.Size() != sha512.Size {
+		t.Errorf("Expected size %d, got %d", sha512.Size, sha512Hasher.Size())
+	}
+	lChild := bytes.Repeat([]byte{1}, sha512.Size)
+	rChild := bytes.Repeat([]byte{2}, sha512.Size)
+	expected := merkle.Sha512().Hash(nil, lChild, rChild)
+	if node := sha512Hasher.Hash(nil, lChild, rChild); !bytes.Equal(node, expected) {
+		t.Errorf("Expected hash to be %x, got %x", expected, node)
+	}
+}
+
+func TestFromCryptoHashUnavailable(t *testing.T) {
+	t.Parallel()
+
+	defer func() {
+		if recover() == nil {
+			t.Error("Expected FromCryptoHash to panic for an unavailable hash function")
+		}
+	}()
+	merkle.FromCryptoHash(crypto.MD4)
+}
+
+func TestHMACHasher(t *testing.T) {
+	t.Parallel()
+
+	build := func(key []byte) ([]byte, [][]byte) {
+		tree := merkle.TreeBuilder().
+			WithHasher(merkle.HMACHasher(key, sha256.New)).
+			WithLeafToProve(4).
+			Build()
+		for i := range uint64(10) {
+			tree.Add(leaf(i))
+		}
+		return tree.RootAndProof()
+	}
+
+	rootA, proofA := build([]byte("key-a"))
+	rootB, _ := build([]byte("key-b"))
+	if bytes.Equal(rootA, rootB) {
+		t.Error("Expected roots with different keys to differ")
+	}
+
+	lChild := leaf(0)
+	rChild := leaf(1)
+	mac := hmac.New(sha256.New, []byte("key-a"))
+	mac.Write(lChild)
+	mac.Write(rChild)
+	node := merkle.HMACHasher([]byte("key-a"), sha256.New).Hash(nil, lChild, rChild)
+	if !bytes.Equal(node, mac.Sum(nil)) {
+		t.Errorf("Expected hash to be %x, got %x", mac.Sum(nil), node)
+	}
+
+	leaves := map[uint64][]byte{4: leaf(4)}
+	valid, err := merkle.ValidateProof(rootA, leaves, proofA,
+		merkle.WithHasher(merkle.HMACHasher([]byte("key-a"), sha256.New)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !valid {
+		t.Error("proof is not valid")
+	}
+
+	valid, err = merkle.ValidateProof(rootA, leaves, proofA,
+		merkle.WithHasher(merkle.HMACHasher([]byte("key-b"), sha256.New)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if valid {
+		t.Error("Expected proof to be invalid with a different key")
+	}
+}
+
+func TestTruncatedSha256(t *testing.T) {
+	t.Parallel()
+
+	hasher := merkle.TruncatedSha256(20)
+	if hasher.Size() != 20 {
+		t.Fatalf("Expected size 20, got %d", hasher.Size())
+	}
+	lChild := bytes.Repeat([]byte{1}, 20)
+	rChild := bytes.Repeat([]byte{2}, 20)
+	expected := sha256.Sum256(append(slices.Clone(lChild), rChild...))
+	if node := hasher.Hash(lChild[:0], lChild, rChild); !bytes.Equal(node, expected[:20]) {
+		t.Errorf("Expected hash to be %x, got %x", expected[:20], node)
+	}
+
+	tree := merkle.TreeBuilder().
+		WithHasher(merkle.TruncatedSha256(20)).
+		WithLeafToProve(4).
+		Build()
+	if tree.NodeSize() != 20 {
+		t.Fatalf("Expected node size 20, got %d", tree.NodeSize())
+	}
+	leaves := make(map[uint64][]byte)
+	for i := range uint64(10) {
+		buf := make([]byte, 20)
+		binary.LittleEndian.PutUint64(buf, i)
+		tree.Add(buf)
+		if i == 4 {
+			leaves[i] = buf
+		}
+	}
+	root, proof := tree.RootAndProof()
+	if len(root) != 20 {
+		t.Errorf("Expected root of 20 bytes, got %d", len(root))
+	}
+	for i, node := range proof {
+		if len(node) != 20 {
+			t.Errorf("Expected proof node %d to have 20 bytes, got %d", i, len(node))
+		}
+	}
+
+	valid, err := merkle.ValidateProof(root, leaves, proof,
+		merkle.WithHasher(merkle.TruncatedSha256(20)),
+		merkle.WithNodeSizeCheck(),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !valid {
+		t.Error("proof is not valid")
+	}
+}
+
+func TestTruncatedSha256InvalidSize(t *testing.T) {
+	t.Parallel()
+
+	for _, n := range []int{0, -1, 33} {
+		t.Run(fmt.Sprintf("size %d", n), func(t *testing.T) {
+			t.Parallel()
+
+			defer func() {
+				if recover() == nil {
+					t.Errorf("Expected TruncatedSha256 to panic for size %d", n)
+				}
+			}()
+			merkle.TruncatedSha256(n)
+		})
+	}
+}
+
+func BenchmarkSha256Parallel(b *testing.B) {
+	hasher := merkle.Sha256()
+	b.SetParallelism(16)
+	b.RunParallel(func(pb *testing.PB) {
+		lChild := make([]byte, hasher.Size())
+		rChild := make([]byte, hasher.Size())
+		buf := make([]byte, hasher.Size())
+		for pb.Next() {
+			buf = hasher.Hash(buf, lChild, rChild)
+		}
+	})
+}
+
+func BenchmarkSha256WithPoolSizeParallel(b *testing.B) {
+	hasher := merkle.Sha256WithPoolSize(16 * runtime.GOMAXPROCS(0))
+	b.SetParallelism(16)
+	b.RunParallel(func(pb *testing.PB) {
+		lChild := make([]byte, hasher.Size())
+		rChild := make([]byte, hasher.Size())
+		buf := make([]byte, hasher.Size())
+		for pb.Next() {
+			buf = hasher.Hash(buf, lChild, rChild)
+		}
+	})
+}
+
+func TestSuffixedHasher(t *testing.T) {
+	t.Parallel()
+
+	hasher := merkle.SuffixedHasher(merkle.Sha256(), []byte{0x01})
+
+	lChild := make([]byte, 32)
+	binary.LittleEndian.PutUint64(lChild, 0)
+	rChild := make([]byte, 32)
+	binary.LittleEndian.PutUint64(rChild, 1)
+	expected := sha256.Sum256(slices.Concat(lChild, rChild, []byte{0x01}))
+	node := hasher.Hash(nil, lChild, rChild)
+	if !bytes.Equal(node, expected[:]) {
+		t.Errorf("Expected hash to be %x, got %x", expected, node)
+	}
+
+	tree := merkle.TreeBuilder().
+		WithHasher(hasher).
+		WithLeafToProve(4).
+		Build()
+	leaves := make(map[uint64][]byte)
+	for i := range 8 {
+		b := make([]byte, tree.NodeSize())
+		binary.LittleEndian.PutUint64(b, uint64(i))
+		tree.Add(b)
+		if i == 4 {
+			leaves[uint64(i)] = b
+		}
+	}
+
+	root, proof := tree.RootAndProof()
+	rootString := hex.EncodeToString(root)
+	if rootString == "89a0f1577268cc19b0a39c7a69f804fd140640c699585eb635ebb03c06154cce" {
+		t.Error("Expected root with suffix to differ from root without suffix")
+	}
+
+	valid, err := merkle.ValidateProof(root, leaves, proof, merkle.WithHasher(hasher))
+	if err != nil {
+		t.Error(err)
+	}
+	if !valid {
+		t.Error("proof is not valid")
+	}
+
+	valid, err = merkle.ValidateProof(root, leaves, proof)
+	if err != nil {
+		t.Error(err)
+	}
+	if valid {
+		t.Error("expected proof to be invalid without suffix")
+	}
+}
+
+func TestBoundedValueLeafs(t *testing.T) {
+	t.Parallel()
+
+	leafHasher := merkle.BoundedValueLeafs(32, 32)
+
+	t.Run("within bound", func(t *testing.T) {
+		t.Parallel()
+
+		tree := merkle.TreeBuilder().
+			WithLeafHasher(leafHasher).
+			WithLeafToProve(4).
+			Build()
+		leaves := make(map[uint64][]byte)
+		for i := range 8 {
+			b := make([]byte, tree.NodeSize())
+			binary.LittleEndian.PutUint64(b, uint64(i))
+			if err := tree.AddChecked(b); err != nil {
+				t.Fatal(err)
+			}
+			if i == 4 {
+				leaves[uint64(i)] = b
+			}
+		}
+
+		root, proof := tree.RootAndProof()
+		rootString := hex.EncodeToString(root)
+		expectedRoot := "89a0f1577268cc19b0a39c7a69f804fd140640c699585eb635ebb03c06154cce"
+		if rootString != expectedRoot {
+			t.Errorf("Expected root to be %s, got %s", expectedRoot, rootString)
+		}
+
+		valid, err := merkle.ValidateProof(root, leaves, proof, merkle.WithLeafHasher(leafHasher))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !valid {
+			t.Error("proof is not valid")
+		}
+	})
+
+	t.Run("over bound", func(t *testing.T) {
+		t.Parallel()
+
+		tree := merkle.TreeBuilder().
+			WithLeafHasher(leafHasher).
+			Build()
+		err := tree.AddChecked(make([]byte, 33))
+		if !errors.Is(err, merkle.ErrLeafTooLarge) {
+			t.Errorf("Expected error to be %v, got %v", merkle.ErrLeafTooLarge, err)
+		}
+		if stats := tree.Stats(); stats.LeafCount != 0 {
+			t.Errorf("Expected rejected leaf not to be added, got %d leaves", stats.LeafCount)
+		}
+
+		leaves := map[uint64][]byte{0: make([]byte, 33)}
+		_, err = merkle.ValidateProof(make([]byte, 32), leaves, nil, merkle.WithLeafHasher(leafHasher))
+		if !errors.Is(err, merkle.ErrLeafTooLarge) {
+			t.Errorf("Expected error to be %v, got %v", merkle.ErrLeafTooLarge, err)
+		}
+
+		defer func() {
+			if recover() == nil {
+				t.Error("Expected Add to panic for an oversized leaf")
+			}
+		}()
+		tree.Add(make([]byte, 33))
+	})
+}
+
+func TestSequentialWorkHasherOrdered(t *testing.T) {
+	t.Parallel()
+
+	build := func(leafHasher merkle.LeafHasher) ([]byte, [][]byte) {
+		tree := merkle.TreeBuilder().
+			WithLeafHasher(leafHasher).
+			WithLeavesToProve(map[uint64]struct{}{0: {}, 1: {}, 4: {}}).
+			Build()
+		for i := range uint64(8) {
+			tree.Add(leaf(i))
+		}
+		return tree.RootAndProof()
+	}
+	leaves := map[uint64][]byte{0: leaf(0), 1: leaf(1), 4: leaf(4)}
+
+	root, _ := build(merkle.SequentialWorkHasherOrdered(merkle.OrderDataFirst, false))
+	if hex.EncodeToString(root) != "02ce397ec513f034dd6ec5dce3cdb8bfcf10f400a9979cb03abf52d3b5f6c88b" {
+		t.Errorf("Expected default order to match SequentialWorkHasher, got root %x", root)
+	}
+
+	roots := map[string]struct{}{hex.EncodeToString(root): {}}
+	for _, order := range []merkle.Order{merkle.OrderDataFirst, merkle.OrderParkingNodesFirst} {
+		for _, lengthPrefixed := range []bool{false, true} {
+			if order == merkle.OrderDataFirst && !lengthPrefixed {
+				continue
+			}
+
+			root, proof := build(merkle.SequentialWorkHasherOrdered(order, lengthPrefixed))
+			if _, ok := roots[hex.EncodeToString(root)]; ok {
+				t.Errorf("order %d, length prefixed %t: expected a distinct root, got %x", order, lengthPrefixed, root)
+			}
+			roots[hex.EncodeToString(root)] = struct{}{}
+
+			leafHasher := merkle.WithLeafHasher(merkle.SequentialWorkHasherOrdered(order, lengthPrefixed))
+			valid, err := merkle.ValidateProof(root, leaves, proof, leafHasher)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !valid {
+				t.Errorf("order %d, length prefixed %t: proof is not valid", order, lengthPrefixed)
+			}
+
+			valid, err = merkle.ValidateProof(root, leaves, proof, merkle.WithLeafHasher(merkle.SequentialWorkHasher()))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if valid {
+				t.Errorf("order %d, length prefixed %t: proof is valid with the default hasher", order, lengthPrefixed)
+			}
+		}
+	}
+}
+
+func TestSequentialWorkHasherOrderedLengthPrefix(t *testing.T) {
+	t.Parallel()
+
+	data := []byte("data")
+	parkingNode := bytes.Repeat([]byte{0xab}, 32)
+
+	// the length is encoded as 64-bit big-endian integer
+	var encoded []byte
+	encoded = binary.BigEndian.AppendUint64(encoded, uint64(len(data)))
+	encoded = append(encoded, data...)
+	encoded = append(encoded, parkingNode...)
+	expected := sha256.Sum256(encoded)
+
+	leafHasher := merkle.SequentialWorkHasherOrdered(merkle.OrderDataFirst, true)
+	if node := leafHasher.Hash(nil, data, [][]byte{parkingNode}); !bytes.Equal(node, expected[:]) {
+		t.Errorf("Expected leaf hash to be %x, got %x", expected, node)
+	}
+}
+
+func TestSequentialWorkHasherOrderedInvalidOrder(t *testing.T) {
+	t.Parallel()
+
+	defer func() {
+		if recover() == nil {
+			t.Error("Expected SequentialWorkHasherOrdered to panic for an invalid order")
+		}
+	}()
+	merkle.SequentialWorkHasherOrdered(merkle.Order(2), false)
+}
+
+func TestCounterLeafHasher(t *testing.T) {
+	t.Parallel()
+
+	leafHasher := merkle.CounterLeafHasher(merkle.Sha256())
+	tree := merkle.TreeBuilder().
+		WithLeafHasher(leafHasher).
+		WithLeavesToProve(map[uint64]struct{}{0: {}, 3: {}, 9: {}}).
+		Build()
+	for i := range uint64(10) {
+		tree.Add(leaf(i))
+	}
+	root, proof := tree.RootAndProof()
+
+	if bytes.Equal(root, merkle.NewTree().Root()) {
+		t.Fatal("Expected root to differ from the root without counter")
+	}
+
+	leaves := map[uint64][]byte{0: leaf(0), 3: leaf(3), 9: leaf(9)}
+	valid, err := merkle.ValidateProof(root, leaves, proof, merkle.WithLeafHasher(leafHasher))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !valid {
+		t.Error("proof is not valid")
+	}
+
+	// the same proof without the counter or with the leaves at different positions is not valid
+	valid, err = merkle.ValidateProof(root, leaves, proof)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if valid {
+		t.Error("proof is valid without counter")
+	}
+	swapped := map[uint64][]byte{0: leaf(3), 3: leaf(0), 9: leaf(9)}
+	valid, err = merkle.ValidateProof(root, swapped, proof, merkle.WithLeafHasher(leafHasher))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if valid {
+		t.Error("proof is valid with swapped leaves")
+	}
+}
+
+func TestCounterLeafHasherLeaf(t *testing.T) {
+	t.Parallel()
+
+	tree := merkle.TreeBuilder().
+		WithLeafHasher(merkle.CounterLeafHasher(merkle.Sha256())).
+		Build()
+	tree.Add(leaf(7))
+
+	counter := make([]byte, 8)
+	expected := sha256.Sum256(append(counter, leaf(7)...))
+	if !bytes.Equal(tree.Root(), expected[:]) {
+		t.Errorf("Expected root to be %x, got %x", expected, tree.Root())
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("Expected Hash to panic")
+		}
+	}()
+	merkle.CounterLeafHasher(merkle.Sha256()).Hash(nil, leaf(7), nil)
+}
+
+func TestCounterLeafHasherWithEncoder(t *testing.T) {
+	t.Parallel()
+
+	encoders := map[string]func(uint64) []byte{
+		"default": nil,
+		"big endian": func(index uint64) []byte {
+			return binary.BigEndian.AppendUint64(nil, index)
+		},
+		"little endian": func(index uint64) []byte {
+			return binary.LittleEndian.AppendUint64(nil, index)
+		},
+		"varint": func(index uint64) []byte {
+			return binary.AppendUvarint(nil, index)
+		},
+	}
+
+	roots := make(map[string][]byte)
+	leaves := map[uint64][]byte{2: leaf(2), 5: leaf(5)}
+	for name, encode := range encoders {
+		leafHasher := merkle.CounterLeafHasherWithEncoder(merkle.Sha256(), encode)
+		tree := merkle.TreeBuilder().
+			WithLeafHasher(leafHasher).
+			WithLeavesToProve(map[uint64]struct{}{2: {}, 5: {}}).
+			Build()
+		for i := range uint64(6) {
+			tree.Add(leaf(i))
+		}
+		root, proof := tree.RootAndProof()
+		roots[name] = root
+
+		valid, err := merkle.ValidateProof(root, leaves, proof, merkle.WithLeafHasher(leafHasher))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !valid {
+			t.Errorf("%s: proof is not valid", name)
+		}
+	}
+
+	if !bytes.Equal(roots["default"], roots["big endian"]) {
+		t.Errorf("Expected default encoding to be big endian, got roots %x and %x",
+			roots["default"], roots["big endian"])
+	}
+	if bytes.Equal(roots["big endian"], roots["little endian"]) || bytes.Equal(roots["big endian"], roots["varint"]) ||
+		bytes.Equal(roots["little endian"], roots["varint"]) {
+		t.Error("Expected different encodings to result in different roots")
+	}
+}
+
+func TestNoncedLeafHasher(t *testing.T) {
+	t.Parallel()
+
+	nonces := make(map[uint64][]byte)
+	for i := range uint64(10) {
+		nonces[i] = []byte(fmt.Sprintf("nonce %d", i))
+	}
+
+	tree := merkle.TreeBuilder().
+		WithLeafHasher(merkle.NoncedLeafHasher(merkle.Sha256(), nonces)).
+		WithLeafToProve(4).
+		Build()
+	for i := range uint64(10) {
+		tree.Add(leaf(i))
+	}
+	root, proof := tree.RootAndProof()
+	leaves := map[uint64][]byte{4: leaf(4)}
+
+	expected := sha256.Sum256(append(slices.Clone(nonces[4]), leaf(4)...))
+	_, hashes, err := merkle.ValidateAndHashLeaves(root, leaves, proof, merkle.WithLeafNonces(nonces))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(hashes[4], expected[:]) {
+		t.Errorf("Expected leaf hash to be %x, got %x", expected, hashes[4])
+	}
+
+	tt := []struct {
+		name   string
+		nonces map[uint64][]byte
+		valid  bool
+		err    error
+	}{
+		{
+			name:   "nonces of proven leaves",
+			nonces: map[uint64][]byte{4: nonces[4]},
+			valid:  true,
+		},
+		{
+			name:   "wrong nonce",
+			nonces: map[uint64][]byte{4: nonces[5]},
+			valid:  false,
+		},
+		{
+			name:   "missing nonce",
+			nonces: map[uint64][]byte{5: nonces[5]},
+			err:    merkle.ErrMissingNonce,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			valid, err := merkle.ValidateProof(root, leaves, proof, merkle.WithLeafNonces(tc.nonces))
+			if !errors.Is(err, tc.err) {
+				t.Fatalf("Expected error %v, got %v", tc.err, err)
+			}
+			if valid != tc.valid {
+				t.Errorf("Expected valid to be %v, got %v", tc.valid, valid)
+			}
+		})
+	}
+}
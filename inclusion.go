@@ -0,0 +1,80 @@
+package merkle
+
+import (
+	"bytes"
+	"slices"
+)
+
+// WithLeafHashToProve sets the hash of a leaf a merkle proof should be generated for. In contrast to WithLeafToProve
+// the index of the leaf does not have to be known in advance: the first leaf added to the tree whose hash (i.e. the
+// output of the leaf hasher) equals the given hash is proven. Use Tree.RootAndProofByLeafHash to retrieve the proof
+// together with the index of the leaf.
+//
+// This can be combined with WithLeafToProve and WithLeavesToProve, the proof will be generated for all leaves.
+func (tb *Builder) WithLeafHashToProve(hash []byte) *Builder {
+	tb.leafHashToProve = slices.Clone(hash)
+	return tb
+}
+
+// matchLeafHash checks if the leaf with the given hash is the leaf to prove set with WithLeafHashToProve. If so the
+// index of the leaf is recorded and true is returned.
+func (t *Tree) matchLeafHash(value, leafHash []byte, onProvingPath bool) bool {
+	if t.targetLeafHash == nil || t.targetFound || !bytes.Equal(leafHash, t.targetLeafHash) {
+		return false
+	}
+
+	t.targetFound = true
+	t.targetIndex = t.indexOffset + t.currentLeaf
+	if onProvingPath {
+		// the leaf is already proven since its index was set as leaf to prove
+		return true
+	}
+
+	// the indices of all leaves to prove that were already added are lower than the current index, clip the slice
+	// since it might share its backing array with leavesToProve
+	pos, _ := slices.BinarySearch(t.provenIndices, t.currentLeaf)
+	t.provenIndices = slices.Insert(slices.Clip(t.provenIndices), pos, t.currentLeaf)
+	if t.provenLeaves != nil {
+		t.provenLeaves[t.currentLeaf] = slices.Clone(value)
+	}
+	return true
+}
+
+// RootAndProofByLeafHash returns the root hash and the proof for the leaf set with Builder.WithLeafHashToProve together
+// with the index of the leaf in the tree. If an index offset was set with WithLeafIndexOffset the index is the global
+// index of the leaf, like for ProvenLeaf. If no leaf with the hash was added ok is false and only the root is returned.
+//
+// If the tree was built with additional leaves to prove, the proof is a proof for all of them.
+func (t *Tree) RootAndProofByLeafHash() (root []byte, proof [][]byte, index uint64, ok bool) {
+	root, proof = t.RootAndProof()
+	if !t.targetFound {
+		return root, nil, 0, false
+	}
+	return root, proof, t.targetIndex, true
+}
+
+// VerifyInclusionByHash validates that the leaf with the given hash is part of a tree of the given size (number of
+// leaves) with the given root at the given index. The proof has to be a single leaf proof as returned by
+// Tree.RootAndProofByLeafHash.
+//
+// The leaf hash is used as is, i.e. any leaf hasher passed with WithLeafHasher is ignored. If the index is not within
+// the tree or the length of the proof doesn't match the size of the tree (see ImpliedTreeSize) false is returned. This
+// is also the case for trees built with a minimum height above their natural height, since their proofs are longer.
+func VerifyInclusionByHash(
+	leafHash []byte,
+	index, size uint64,
+	proof [][]byte,
+	root []byte,
+	opts ...ValidatorOpt,
+) (bool, error) {
+	if index >= size {
+		return false, nil
+	}
+	minSize, maxSize := ImpliedTreeSize(index, len(proof))
+	if size < minSize || size > maxSize {
+		return false, nil
+	}
+
+	opts = append(slices.Clip(opts), WithLeafHasher(ValueLeafs(len(leafHash))))
+	return ValidateProof(root, map[uint64][]byte{index: leafHash}, proof, opts...)
+}
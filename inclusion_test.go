@@ -0,0 +1,185 @@
+package merkle_test
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"testing"
+
+	"github.com/fasmat/merkle"
+)
+
+func TestTreeRootAndProofByLeafHash(t *testing.T) {
+	t.Parallel()
+
+	leafHash := make([]byte, 32)
+	binary.LittleEndian.PutUint64(leafHash, 4)
+
+	tree := merkle.TreeBuilder().
+		WithLeafHashToProve(leafHash).
+		Build()
+	for i := range 8 {
+		b := make([]byte, tree.NodeSize())
+		binary.LittleEndian.PutUint64(b, uint64(i))
+		tree.Add(b)
+	}
+
+	root, proof, index, ok := tree.RootAndProofByLeafHash()
+	if !ok {
+		t.Fatal("Expected leaf to be found")
+	}
+	if index != 4 {
+		t.Errorf("Expected index to be 4, got %d", index)
+	}
+	expectedProof := []string{
+		"0500000000000000000000000000000000000000000000000000000000000000",
+		"fa670379e5c2212ed93ff09769622f81f98a91e1ec8fb114d607dd25220b9088",
+		"ba94ffe7edabf26ef12736f8eb5ce74d15bedb6af61444ae2906e926b1a95084",
+	}
+	if len(proof) != len(expectedProof) {
+		t.Fatalf("Expected proof to be of length %d, got %d", len(expectedProof), len(proof))
+	}
+	for i, p := range proof {
+		if hex.EncodeToString(p) != expectedProof[i] {
+			t.Errorf("Expected proof[%d] to be %s, got %x", i, expectedProof[i], p)
+		}
+	}
+
+	valid, err := merkle.VerifyInclusionByHash(leafHash, index, 8, proof, root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !valid {
+		t.Error("proof is not valid")
+	}
+}
+
+func TestTreeRootAndProofByLeafHashNotFound(t *testing.T) {
+	t.Parallel()
+
+	tree := merkle.TreeBuilder().
+		WithLeafHashToProve(make([]byte, 32)).
+		Build()
+	for i := range 8 {
+		b := make([]byte, tree.NodeSize())
+		binary.LittleEndian.PutUint64(b, uint64(i+1))
+		tree.Add(b)
+	}
+
+	root, proof, _, ok := tree.RootAndProofByLeafHash()
+	if ok {
+		t.Error("Expected leaf not to be found")
+	}
+	if proof != nil {
+		t.Errorf("Expected no proof, got %x", proof)
+	}
+	if len(root) == 0 {
+		t.Error("Expected root to be returned")
+	}
+}
+
+func TestTreeRootAndProofByLeafHashIndexOffset(t *testing.T) {
+	t.Parallel()
+
+	// shard of a larger tree starting at leaf 16, the index is reported as global index like by ProvenLeaf
+	tree := merkle.TreeBuilder().
+		WithLeafHashToProve(leaf(21)).
+		WithLeafIndexOffset(16).
+		WithRetainProvenLeaves().
+		Build()
+	for i := range 8 {
+		tree.Add(leaf(uint64(16 + i)))
+	}
+
+	root, proof, index, ok := tree.RootAndProofByLeafHash()
+	if !ok {
+		t.Fatal("Expected leaf to be found")
+	}
+	if index != 21 {
+		t.Errorf("Expected index 21, got %d", index)
+	}
+	if _, ok := tree.ProvenLeaf(index); !ok {
+		t.Errorf("Expected leaf %d to be retained", index)
+	}
+
+	valid, err := merkle.ValidateProof(root, map[uint64][]byte{index: leaf(21)}, proof,
+		merkle.WithLeafIndexOffset(16))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !valid {
+		t.Error("proof is not valid")
+	}
+}
+
+func TestVerifyInclusionByHash(t *testing.T) {
+	t.Parallel()
+
+	leafHash := make([]byte, 32)
+	binary.LittleEndian.PutUint64(leafHash, 8)
+
+	// unbalanced tree with 10 leaves proving leaf 8, the index is determined while adding the leaves
+	tree := merkle.TreeBuilder().
+		WithLeafHashToProve(leafHash).
+		WithLeafToProve(2).
+		Build()
+	for i := range 10 {
+		tree.Add(leaf(uint64(i)))
+	}
+	root, proof, _, ok := tree.RootAndProofByLeafHash()
+	if !ok {
+		t.Fatal("Expected leaf to be found")
+	}
+	if hex.EncodeToString(root) != "59f32a43534fe4c4c0966421aef624267cdf65bd11f74998c60f27c7caccb12d" {
+		t.Errorf("Unexpected root %x", root)
+	}
+
+	// the proof proves leaves 2 and 8, so it is no single leaf proof
+	valid, err := merkle.ValidateProof(root, map[uint64][]byte{2: leaf(2), 8: leafHash}, proof)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !valid {
+		t.Error("proof is not valid")
+	}
+
+	tree = merkle.TreeBuilder().
+		WithLeafHashToProve(leafHash).
+		Build()
+	for i := range 10 {
+		tree.Add(leaf(uint64(i)))
+	}
+	root, proof, index, _ := tree.RootAndProofByLeafHash()
+
+	tt := []struct {
+		name  string
+		index uint64
+		size  uint64
+		valid bool
+	}{
+		{name: "valid", index: index, size: 10, valid: true},
+		{name: "smallest size of the proof length", index: index, size: 9, valid: true},
+		{name: "largest size of the proof length", index: index, size: 16, valid: true},
+		{name: "index out of range", index: index, size: 8, valid: false},
+		{name: "size too large", index: index, size: 17, valid: false},
+		{name: "wrong index", index: 9, size: 10, valid: false},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			valid, err := merkle.VerifyInclusionByHash(leafHash, tc.index, tc.size, proof, root)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if valid != tc.valid {
+				t.Errorf("Expected valid to be %t, got %t", tc.valid, valid)
+			}
+		})
+	}
+}
+
+func leaf(i uint64) []byte {
+	b := make([]byte, 32)
+	binary.LittleEndian.PutUint64(b, i)
+	return b
+}
@@ -0,0 +1,40 @@
+package merkle
+
+import (
+	"sync"
+
+	"golang.org/x/crypto/sha3"
+)
+
+// Keccak256 returns a Hasher that computes the parent node by hashing the concatenation of the two children with the
+// legacy Keccak-256 as used by Ethereum, using golang.org/x/crypto/sha3.NewLegacyKeccak256. This is not SHA3-256:
+// both use the same permutation, but the padding differs, so they produce different hashes for the same input. Use
+// Sha3_256 for the standardized SHA3-256 (FIPS 202).
+//
+// Like Sha256 it uses a sync.Pool to reuse hash.Hash instances, so it can be shared by multiple trees that are built
+// concurrently.
+func Keccak256() Hasher {
+	return &cryptoHasher{
+		size: 32,
+		pool: &sync.Pool{
+			New: func() any {
+				return sha3.NewLegacyKeccak256()
+			},
+		},
+	}
+}
+
+// Sha3_256 returns a Hasher that computes the parent node by hashing the concatenation of the two children with
+// SHA3-256 as standardized in FIPS 202, using golang.org/x/crypto/sha3.New256 like Keccak256. This is not the legacy
+// Keccak-256 used by Ethereum, use Keccak256 for that. Like Sha256 it uses a sync.Pool to reuse hash.Hash instances, so
+// it can be shared by multiple trees that are built concurrently.
+func Sha3_256() Hasher {
+	return &cryptoHasher{
+		size: 32,
+		pool: &sync.Pool{
+			New: func() any {
+				return sha3.New256()
+			},
+		},
+	}
+}
@@ -0,0 +1,120 @@
+package merkle_test
+
+import (
+	"bytes"
+	"crypto"
+	"encoding/binary"
+	"encoding/hex"
+	"testing"
+
+	"github.com/fasmat/merkle"
+)
+
+func TestKeccak256AndSha3_256(t *testing.T) {
+	t.Parallel()
+
+	// Keccak-256 and SHA3-256 only differ in the padding, which results in different hashes for the same input
+	tt := []struct {
+		name   string
+		lChild []byte
+		rChild []byte
+		keccak string
+		sha3   string
+	}{
+		{
+			name:   "empty",
+			keccak: "c5d2460186f7233c927e7db2dcc703c0e500b653ca82273b7bfad8045d85a470",
+			sha3:   "a7ffc6f8bf1ed76651c14756a061d662f580ff4de43b49fa82d80a4b80f8434a",
+		},
+		{
+			name:   "abc",
+			lChild: []byte("ab"),
+			rChild: []byte("c"),
+			keccak: "4e03657aea45a94fc7d47ba826c8d667c0d1e6e33a64a036ec44f58fa12d6c45",
+			sha3:   "3a985da74fe225b2045c172d6bd390bd855f086e3e9d525b46bfe24511431532",
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			keccak := hex.EncodeToString(merkle.Keccak256().Hash(nil, tc.lChild, tc.rChild))
+			if keccak != tc.keccak {
+				t.Errorf("Expected Keccak-256 hash to be %s, got %s", tc.keccak, keccak)
+			}
+			sha3 := hex.EncodeToString(merkle.Sha3_256().Hash(nil, tc.lChild, tc.rChild))
+			if sha3 != tc.sha3 {
+				t.Errorf("Expected SHA3-256 hash to be %s, got %s", tc.sha3, sha3)
+			}
+		})
+	}
+}
+
+func TestKeccak256MultipleBlocks(t *testing.T) {
+	t.Parallel()
+
+	// the input spans two blocks of the sponge
+	data := make([]byte, 200)
+	for i := range data {
+		data[i] = byte(i)
+	}
+
+	hasher := merkle.Keccak256()
+	if hasher.Size() != 32 {
+		t.Errorf("Expected size to be %d, got %d", 32, hasher.Size())
+	}
+	node := hex.EncodeToString(hasher.Hash(nil, data[:100], data[100:]))
+	expected := "bfb0aa97863e797943cf7c33bb7e880bb4543f3d2703c0923c6901c2af57b890"
+	if node != expected {
+		t.Errorf("Expected hash to be %s, got %s", expected, node)
+	}
+
+	// the hasher is reusable
+	if again := hex.EncodeToString(hasher.Hash(nil, data[:150], data[150:])); again != expected {
+		t.Errorf("Expected hash to be %s, got %s", expected, again)
+	}
+}
+
+func TestSha3_256(t *testing.T) {
+	t.Parallel()
+
+	builder := func(hasher merkle.Hasher) *merkle.Builder {
+		return merkle.TreeBuilder().
+			WithHasher(hasher).
+			WithLeafToProve(4)
+	}
+	tree := builder(merkle.Sha3_256()).Build()
+	expected := builder(merkle.FromCryptoHash(crypto.SHA3_256)).Build()
+	leaves := make(map[uint64][]byte)
+	for i := range 10 {
+		b := make([]byte, tree.NodeSize())
+		binary.LittleEndian.PutUint64(b, uint64(i))
+		tree.Add(b)
+		expected.Add(b)
+		if i == 4 {
+			leaves[uint64(i)] = b
+		}
+	}
+	root, proof := tree.RootAndProof()
+
+	if !bytes.Equal(root, expected.Root()) {
+		t.Errorf("Expected root to be %x, got %x", expected.Root(), root)
+	}
+
+	valid, err := merkle.ValidateProof(root, leaves, proof, merkle.WithHasher(merkle.Sha3_256()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !valid {
+		t.Error("proof is not valid")
+	}
+
+	valid, err = merkle.ValidateProof(root, leaves, proof, merkle.WithHasher(merkle.Keccak256()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if valid {
+		t.Error("Expected proof to be invalid with Keccak-256")
+	}
+}
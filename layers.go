@@ -0,0 +1,57 @@
+package merkle
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+)
+
+// ErrInvalidLayers is returned by TreeFromLayers when the given layers do not form a consistent Merkle tree.
+var ErrInvalidLayers = errors.New("invalid layers")
+
+// TreeFromLayers constructs a tree from the given in-memory layers, e.g. layers produced by another implementation or
+// written by hand for tests. layers[0] contains the leaves, every following layer the parents of the previous one and
+// the last layer the root. A node without right sibling is hashed with the padding node (all zeros), like in trees
+// built with Add.
+//
+// The tree is rebuilt from the leaves with the given hasher (SHA256 if nil) and every node of the layers is checked
+// against the rebuilt tree. An error wrapping ErrInvalidLayers is returned if the number of layers or nodes per layer
+// does not match the number of leaves or if any node differs. The returned tree produces the proof for the given
+// leaves to prove with RootAndProof and further leaves can be added to it.
+func TreeFromLayers(layers [][][]byte, hasher Hasher, leavesToProve ...uint64) (*Tree, error) {
+	if len(layers) == 0 || len(layers[0]) == 0 {
+		return nil, fmt.Errorf("%w: no leaves", ErrInvalidLayers)
+	}
+	for height := 1; height < len(layers); height++ {
+		if expected := (len(layers[height-1]) + 1) / 2; len(layers[height]) != expected {
+			return nil, fmt.Errorf("%w: layer %d has %d nodes, expected %d",
+				ErrInvalidLayers, height, len(layers[height]), expected)
+		}
+	}
+	if top := len(layers) - 1; len(layers[top]) != 1 || (top > 0 && len(layers[top-1]) == 1) {
+		return nil, fmt.Errorf("%w: layer %d is not the root", ErrInvalidLayers, top)
+	}
+
+	var mismatch error
+	builder := TreeBuilder().
+		WithHasher(hasher).
+		WithFullNodeSink(func(height int, index uint64, hash []byte) {
+			if mismatch == nil && !bytes.Equal(layers[height][index], hash) {
+				mismatch = fmt.Errorf("%w: node %d of layer %d is %x, expected %x",
+					ErrInvalidLayers, index, height, layers[height][index], hash)
+			}
+		})
+	for _, idx := range leavesToProve {
+		builder.WithLeafToProve(idx)
+	}
+	tree := builder.Build()
+	for _, leaf := range layers[0] {
+		tree.Add(leaf)
+	}
+	tree.Root() // report the nodes that depend on padding to the sink
+	tree.nodeSink = nil
+	if mismatch != nil {
+		return nil, mismatch
+	}
+	return tree, nil
+}
@@ -0,0 +1,148 @@
+package merkle_test
+
+import (
+	"encoding/hex"
+	"errors"
+	"testing"
+
+	"github.com/fasmat/merkle"
+)
+
+// knownLayers returns the layers of the tree with the leaves 0 to 7.
+func knownLayers() [][][]byte {
+	decode := func(nodes ...string) [][]byte {
+		layer := make([][]byte, len(nodes))
+		for i, node := range nodes {
+			layer[i], _ = hex.DecodeString(node)
+		}
+		return layer
+	}
+
+	leaves := make([][]byte, 8)
+	for i := range leaves {
+		leaves[i] = leaf(uint64(i))
+	}
+	return [][][]byte{
+		leaves,
+		decode(
+			"cb592844121d926f1ca3ad4e1d6fb9d8e260ed6e3216361f7732e975a0e8bbf6",
+			"0094579cfc7b716038d416a311465309bea202baa922b224a7b08f01599642fb",
+			"bd50456d5ad175ae99a1612a53ca229124b65d3eaabd9ff9c7ab979a385cf6b3",
+			"fa670379e5c2212ed93ff09769622f81f98a91e1ec8fb114d607dd25220b9088",
+		),
+		decode(
+			"ba94ffe7edabf26ef12736f8eb5ce74d15bedb6af61444ae2906e926b1a95084",
+			"633b26ee8a5d96d49a4861e9a5720492f0db5b6af305c0b5cfcc6a7ec9b676d4",
+		),
+		decode("89a0f1577268cc19b0a39c7a69f804fd140640c699585eb635ebb03c06154cce"),
+	}
+}
+
+func TestTreeFromLayers(t *testing.T) {
+	t.Parallel()
+
+	layers := knownLayers()
+	tree, err := merkle.TreeFromLayers(layers, merkle.Sha256(), 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	root, proof := tree.RootAndProof()
+	if hex.EncodeToString(root) != hex.EncodeToString(layers[3][0]) {
+		t.Errorf("Expected root to be %x, got %x", layers[3][0], root)
+	}
+
+	valid, err := merkle.ValidateProof(root, map[uint64][]byte{4: leaf(4)}, proof)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !valid {
+		t.Error("proof is not valid")
+	}
+}
+
+func TestTreeFromLayersInvalid(t *testing.T) {
+	t.Parallel()
+
+	tt := []struct {
+		name   string
+		modify func(layers [][][]byte) [][][]byte
+	}{
+		{
+			name:   "no layers",
+			modify: func([][][]byte) [][][]byte { return nil },
+		},
+		{
+			name:   "missing root",
+			modify: func(layers [][][]byte) [][][]byte { return layers[:3] },
+		},
+		{
+			name:   "extra layer",
+			modify: func(layers [][][]byte) [][][]byte { return append(layers, layers[3]) },
+		},
+		{
+			name: "missing node",
+			modify: func(layers [][][]byte) [][][]byte {
+				layers[1] = layers[1][:3]
+				return layers
+			},
+		},
+		{
+			name: "wrong node",
+			modify: func(layers [][][]byte) [][][]byte {
+				layers[2][1] = layers[2][0]
+				return layers
+			},
+		},
+		{
+			name: "wrong leaf",
+			modify: func(layers [][][]byte) [][][]byte {
+				layers[0][5] = leaf(8)
+				return layers
+			},
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			tree, err := merkle.TreeFromLayers(tc.modify(knownLayers()), nil)
+			if !errors.Is(err, merkle.ErrInvalidLayers) {
+				t.Errorf("Expected error %v, got %v", merkle.ErrInvalidLayers, err)
+			}
+			if tree != nil {
+				t.Error("Expected no tree")
+			}
+		})
+	}
+}
+
+func TestTreeFromLayersUnbalanced(t *testing.T) {
+	t.Parallel()
+
+	var layers [][][]byte
+	recorder := merkle.TreeBuilder().WithFullNodeSink(func(height int, index uint64, hash []byte) {
+		for len(layers) <= height {
+			layers = append(layers, nil)
+		}
+		if uint64(len(layers[height])) == index {
+			layers[height] = append(layers[height], append([]byte(nil), hash...))
+		}
+	}).Build()
+	leaves := make([][]byte, 10)
+	for i := range leaves {
+		leaves[i] = leaf(uint64(i))
+		recorder.Add(leaves[i])
+	}
+	root := recorder.Root()
+	layers[0] = leaves
+
+	restored, err := merkle.TreeFromLayers(layers, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hex.EncodeToString(restored.Root()) != hex.EncodeToString(root) {
+		t.Errorf("Expected root to be %x, got %x", root, restored.Root())
+	}
+}
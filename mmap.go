@@ -0,0 +1,54 @@
+package merkle
+
+import (
+	"errors"
+	"fmt"
+	"os"
+)
+
+// ErrInvalidRecordSize is returned when a file cannot be split into records of the requested size.
+var ErrInvalidRecordSize = errors.New("invalid record size")
+
+// BuildFromMmapFile constructs the Merkle tree with the specified properties and adds every record of the file at
+// path as leaf. The file has to consist of records of exactly recordSize bytes, otherwise an error wrapping
+// ErrInvalidRecordSize is returned.
+//
+// The file is memory mapped (on platforms that support it) and the records are passed to Add directly from the
+// mapping, so they are not copied onto the heap. This allows building trees over very large static inputs without
+// reading them into memory first.
+func (tb *Builder) BuildFromMmapFile(path string, recordSize int) (*Tree, error) {
+	if recordSize <= 0 {
+		return nil, fmt.Errorf("%w: %d", ErrInvalidRecordSize, recordSize)
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("open leaves file: %w", err)
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return nil, fmt.Errorf("stat leaves file: %w", err)
+	}
+	if info.Size()%int64(recordSize) != 0 {
+		return nil, fmt.Errorf("%w: file size %d is not a multiple of record size %d",
+			ErrInvalidRecordSize, info.Size(), recordSize)
+	}
+
+	tree := tb.Build()
+	if info.Size() == 0 {
+		return tree, nil
+	}
+
+	data, unmap, err := mmapFile(f, int(info.Size()))
+	if err != nil {
+		return nil, fmt.Errorf("map leaves file: %w", err)
+	}
+	defer unmap()
+
+	for offset := 0; offset < len(data); offset += recordSize {
+		tree.Add(data[offset : offset+recordSize])
+	}
+	return tree, nil
+}
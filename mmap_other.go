@@ -0,0 +1,18 @@
+//go:build !unix
+
+package merkle
+
+import (
+	"io"
+	"os"
+)
+
+// mmapFile reads the first size bytes of the given file into memory on platforms without support for memory mapped
+// files. The returned function is a no-op.
+func mmapFile(f *os.File, size int) ([]byte, func(), error) {
+	data := make([]byte, size)
+	if _, err := io.ReadFull(f, data); err != nil {
+		return nil, nil, err
+	}
+	return data, func() {}, nil
+}
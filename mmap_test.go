@@ -0,0 +1,93 @@
+package merkle_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/fasmat/merkle"
+)
+
+func TestBuildFromMmapFile(t *testing.T) {
+	t.Parallel()
+
+	tree := merkle.TreeBuilder().
+		WithLeafToProve(500).
+		Build()
+	data := make([]byte, 0, 1000*tree.NodeSize())
+	for i := range 1000 {
+		b := make([]byte, tree.NodeSize())
+		binary.LittleEndian.PutUint64(b, uint64(i))
+		tree.Add(b)
+		data = append(data, b...)
+	}
+	expectedRoot, expectedProof := tree.RootAndProof()
+
+	path := filepath.Join(t.TempDir(), "leaves")
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	tree, err := merkle.TreeBuilder().
+		WithLeafToProve(500).
+		BuildFromMmapFile(path, 32)
+	if err != nil {
+		t.Fatal(err)
+	}
+	root, proof := tree.RootAndProof()
+	if !bytes.Equal(root, expectedRoot) {
+		t.Errorf("Expected root to be %x, got %x", expectedRoot, root)
+	}
+	if len(proof) != len(expectedProof) {
+		t.Fatalf("Expected proof to be of length %d, got %d", len(expectedProof), len(proof))
+	}
+	for i := range proof {
+		if !bytes.Equal(proof[i], expectedProof[i]) {
+			t.Errorf("Expected proof[%d] to be %x, got %x", i, expectedProof[i], proof[i])
+		}
+	}
+}
+
+func TestBuildFromMmapFileInvalidSize(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "leaves")
+	if err := os.WriteFile(path, make([]byte, 100), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err := merkle.TreeBuilder().BuildFromMmapFile(path, 32)
+	if !errors.Is(err, merkle.ErrInvalidRecordSize) {
+		t.Errorf("Expected error to be %v, got %v", merkle.ErrInvalidRecordSize, err)
+	}
+
+	_, err = merkle.TreeBuilder().BuildFromMmapFile(path, 0)
+	if !errors.Is(err, merkle.ErrInvalidRecordSize) {
+		t.Errorf("Expected error to be %v, got %v", merkle.ErrInvalidRecordSize, err)
+	}
+
+	_, err = merkle.TreeBuilder().BuildFromMmapFile(filepath.Join(t.TempDir(), "missing"), 32)
+	if !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("Expected error to be %v, got %v", os.ErrNotExist, err)
+	}
+}
+
+func TestBuildFromMmapFileEmpty(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "leaves")
+	if err := os.WriteFile(path, nil, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	tree, err := merkle.TreeBuilder().BuildFromMmapFile(path, 32)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(tree.Root(), merkle.NewTree().Root()) {
+		t.Error("Expected root of empty file to be the root of an empty tree")
+	}
+}
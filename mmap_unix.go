@@ -0,0 +1,17 @@
+//go:build unix
+
+package merkle
+
+import (
+	"os"
+	"syscall"
+)
+
+// mmapFile maps the first size bytes of the given file read-only into memory. The returned function unmaps the file.
+func mmapFile(f *os.File, size int) ([]byte, func(), error) {
+	data, err := syscall.Mmap(int(f.Fd()), 0, size, syscall.PROT_READ, syscall.MAP_SHARED)
+	if err != nil {
+		return nil, nil, err
+	}
+	return data, func() { _ = syscall.Munmap(data) }, nil
+}
@@ -0,0 +1,63 @@
+package merkle
+
+import "bytes"
+
+// IsPaddingNode returns true if the given node equals the given padding value, e.g. the value returned by
+// Tree.Padding. A nil padding value stands for the default zero padding, i.e. the node is compared to zeros.
+//
+// A real node can have the same value as the padding, e.g. a leaf that consists of zeros. IsPaddingNode cannot tell
+// them apart, use Tree.RootAndProofWithPaddingMask to get the exact positions of the padding nodes of a proof.
+func IsPaddingNode(node, paddingValue []byte) bool {
+	if paddingValue != nil {
+		return bytes.Equal(node, paddingValue)
+	}
+	for _, b := range node {
+		if b != 0 {
+			return false
+		}
+	}
+	return len(node) > 0
+}
+
+// RootAndProofWithPaddingMask returns the root hash and the proof for the leaves to prove like RootAndProof and a mask
+// of the same length as the proof. An entry of the mask is true if the node at the same position in the proof is a
+// padding node, i.e. it was not derived from any leaf but stands in for a missing subtree of an unbalanced tree or
+// for a layer added for the minimum height. The mask is nil if the proof is nil.
+func (t *Tree) RootAndProofWithPaddingMask() ([]byte, [][]byte, []bool) {
+	root, proof := t.RootAndProof()
+	if proof == nil {
+		return root, nil, nil
+	}
+	return root, proof, t.paddingMask(len(proof))
+}
+
+// paddingMask replays the traversal of RootAndProof without hashing to determine which of the proof nodes of the given
+// number are padding. The nodes collected while adding leaves are always real nodes, the nodes added when calculating
+// the root are padding if the node on their layer is missing.
+func (t *Tree) paddingMask(n int) []bool {
+	mask := make([]bool, len(t.proof), n)
+
+	hasRoot := false
+	onProvingPath := false
+	for height, parkedNode := range t.parkedNodes {
+		if parkedNode != nil && !hasRoot && height == len(t.parkedNodes)-1 {
+			break
+		}
+
+		switch {
+		case t.onProvingPath[height] && !onProvingPath:
+			mask = append(mask, !hasRoot)
+			onProvingPath = true
+		case onProvingPath && !t.onProvingPath[height]:
+			mask = append(mask, parkedNode == nil)
+		default:
+			// either both or none are on the proving path, no node was added to the proof
+		}
+		hasRoot = hasRoot || parkedNode != nil
+	}
+	for len(mask) < n {
+		// the layers added for the minimum height
+		mask = append(mask, true)
+	}
+	return mask
+}
@@ -0,0 +1,119 @@
+package merkle_test
+
+import (
+	"encoding/binary"
+	"slices"
+	"testing"
+
+	"github.com/fasmat/merkle"
+)
+
+func TestTreeRootAndProofWithPaddingMask(t *testing.T) {
+	t.Parallel()
+
+	tt := []struct {
+		name         string
+		builder      *merkle.Builder
+		numLeaves    uint64
+		expectedMask []bool
+	}{
+		{
+			name:         "unbalanced",
+			builder:      merkle.TreeBuilder().WithLeafToProve(8),
+			numLeaves:    10,
+			expectedMask: []bool{false, true, true, false},
+		},
+		{
+			name:         "unbalanced without padding on path",
+			builder:      merkle.TreeBuilder().WithLeafToProve(1),
+			numLeaves:    10,
+			expectedMask: []bool{false, false, false, false},
+		},
+		{
+			name:         "multiple leaves",
+			builder:      merkle.TreeBuilder().WithLeavesToProve(map[uint64]struct{}{2: {}, 8: {}}),
+			numLeaves:    10,
+			expectedMask: []bool{false, false, false, false, true, true},
+		},
+		{
+			name:         "min height",
+			builder:      merkle.TreeBuilder().WithLeafToProve(4).WithMinHeight(6),
+			numLeaves:    8,
+			expectedMask: []bool{false, false, false, true, true},
+		},
+		{
+			name:         "last leaf of odd layer",
+			builder:      merkle.TreeBuilder().WithLeafToProve(4),
+			numLeaves:    5,
+			expectedMask: []bool{true, true, false},
+		},
+		{
+			name:      "no leaves to prove",
+			builder:   merkle.TreeBuilder(),
+			numLeaves: 10,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			tree := tc.builder.Build()
+			for i := range tc.numLeaves {
+				b := make([]byte, tree.NodeSize())
+				binary.LittleEndian.PutUint64(b, i)
+				tree.Add(b)
+			}
+
+			root, proof, mask := tree.RootAndProofWithPaddingMask()
+			expectedRoot, expectedProof := tree.RootAndProof()
+			if !slices.Equal(root, expectedRoot) {
+				t.Errorf("Expected root to be %x, got %x", expectedRoot, root)
+			}
+			if !slices.EqualFunc(proof, expectedProof, slices.Equal) {
+				t.Errorf("Expected proof to be %x, got %x", expectedProof, proof)
+			}
+			if !slices.Equal(mask, tc.expectedMask) {
+				t.Errorf("Expected mask to be %v, got %v", tc.expectedMask, mask)
+			}
+			for i, node := range proof {
+				if mask[i] && !merkle.IsPaddingNode(node, tree.Padding()) {
+					t.Errorf("Expected proof[%d] to be padding, got %x", i, node)
+				}
+			}
+		})
+	}
+}
+
+func TestIsPaddingNode(t *testing.T) {
+	t.Parallel()
+
+	zeros := make([]byte, 32)
+	custom := slices.Repeat([]byte{0xab}, 32)
+	node := slices.Repeat([]byte{0x01}, 32)
+
+	tt := []struct {
+		name     string
+		node     []byte
+		padding  []byte
+		expected bool
+	}{
+		{"zero padding", zeros, nil, true},
+		{"zero padding value", zeros, zeros, true},
+		{"custom padding", custom, custom, true},
+		{"node with zero padding", node, nil, false},
+		{"node with custom padding", node, custom, false},
+		{"zeros with custom padding", zeros, custom, false},
+		{"empty node", nil, nil, false},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			if got := merkle.IsPaddingNode(tc.node, tc.padding); got != tc.expected {
+				t.Errorf("Expected IsPaddingNode to be %v, got %v", tc.expected, got)
+			}
+		})
+	}
+}
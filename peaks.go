@@ -0,0 +1,151 @@
+package merkle
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"math/bits"
+	"slices"
+)
+
+// ErrInvalidPeaks is returned when a tree cannot be restored from the given peaks.
+var ErrInvalidPeaks = errors.New("invalid peaks")
+
+// Peak is the root of one of the balanced subtrees (the parked nodes) of a tree together with its height, i.e. the
+// subtree has 2^Height leaves.
+type Peak struct {
+	Height uint64
+	Hash   []byte
+}
+
+// PeaksWithHeights returns the peaks of the tree ordered from left to right, i.e. from the highest to the lowest. The
+// peaks are the roots of the balanced subtrees that make up the leaves added so far, in a Merkle Mountain Range
+// (MMR) they form the state of the accumulator. Together with the configuration of the tree they are sufficient to
+// continue adding leaves, see Builder.BuildFromPeaks.
+//
+// The hashes of the returned peaks are copies and can be modified by the caller.
+func (t *Tree) PeaksWithHeights() []Peak {
+	peaks := make([]Peak, 0, len(t.parkedNodes))
+	for height := len(t.parkedNodes) - 1; height >= 0; height-- {
+		if t.parkedNodes[height] == nil {
+			continue
+		}
+		peaks = append(peaks, Peak{
+			Height: uint64(height),
+			Hash:   slices.Clone(t.parkedNodes[height]),
+		})
+	}
+	return peaks
+}
+
+// BuildFromPeaks constructs a Merkle tree with the specified properties that continues a tree with the given peaks
+// (see Tree.PeaksWithHeights). The restored tree has the same root as the tree the peaks were taken from and adding
+// further leaves results in the same roots as adding them to the original tree.
+//
+// The peaks have to be ordered by strictly decreasing height and their hashes have to be of the size of the hasher,
+// otherwise an error wrapping ErrInvalidPeaks is returned. Leaves to prove with an index lower than the number of
+// leaves covered by the peaks are ignored, since their siblings are not known anymore.
+func (tb *Builder) BuildFromPeaks(peaks []Peak) (*Tree, error) {
+	tree := tb.Build()
+	for k, p := range peaks {
+		if p.Height >= 64 || (k > 0 && p.Height >= peaks[k-1].Height) {
+			return nil, fmt.Errorf("%w: peak %d has height %d, heights must be decreasing and below 64",
+				ErrInvalidPeaks, k, p.Height)
+		}
+		if len(p.Hash) != tree.NodeSize() {
+			return nil, fmt.Errorf("%w: peak %d has %d bytes, expected %d",
+				ErrInvalidPeaks, k, len(p.Hash), tree.NodeSize())
+		}
+		if k == 0 {
+			tree.parkedNodes = make([][]byte, p.Height+1)
+			tree.onProvingPath = make([]bool, p.Height+1)
+		}
+		tree.parkedNodes[p.Height] = slices.Clone(p.Hash)
+		tree.currentLeaf += 1 << p.Height
+	}
+
+	// drop all leaves to prove that are covered by the peaks
+	pos, _ := slices.BinarySearch(tree.leavesToProve, tree.currentLeaf)
+	tree.leavesToProve = tree.leavesToProve[pos:]
+	tree.provenIndices = tree.provenIndices[pos:]
+	if len(tree.provenIndices) == 0 {
+		tree.provenIndices = nil
+	}
+	return tree, nil
+}
+
+// BagPeaks calculates the root of a Merkle Mountain Range (MMR) from its peaks ordered from left to right by "bagging"
+// them from right to left: the two right-most peaks are hashed together, the result is hashed with the next peak to
+// the left and so on. If there are no peaks nil is returned.
+//
+// In contrast to the root of a Tree, which pads unbalanced layers, the bagged root only depends on the leaves added.
+func BagPeaks(peaks [][]byte, hasher Hasher) []byte {
+	if len(peaks) == 0 {
+		return nil
+	}
+
+	root := slices.Clone(peaks[len(peaks)-1])
+	for i := len(peaks) - 2; i >= 0; i-- {
+		root = hasher.Hash(root, peaks[i], root)
+	}
+	return root
+}
+
+// VerifyMMRProof validates that the given leaf is at the given index of a Merkle Mountain Range (MMR) with size leaves
+// and the given bagged root (see BagPeaks). The leaf is used as is, hashers can be configured with WithHasher and
+// WithMismatchError reports a proof that does not match the root as *ValidationError, all other options are ignored.
+//
+// The proof consists of the siblings on the path from the leaf to the peak of the balanced subtree that contains the
+// leaf, followed by all other peaks of the MMR ordered from left to right. If the proof is too short a
+// *ValidationError wrapping ErrShortProof is returned, if the index is not within the MMR one wrapping
+// ErrInvalidLeafIndex.
+func VerifyMMRProof(baggedRoot, leaf []byte, index, size uint64, proof [][]byte, opts ...ValidatorOpt) (bool, error) {
+	if index >= size {
+		return false, validationError(fmt.Errorf("%w: leaf %d is beyond the MMR size %d", ErrInvalidLeafIndex, index,
+			size))
+	}
+	validatorOpts := parseValidatorOpts(opts)
+	hasher := validatorOpts.Hasher()
+
+	// find the peak that contains the leaf, the peaks are the set bits of size from the highest to the lowest
+	var peakHeight, peakStart uint64
+	peakPos := 0
+	for height := 63; height >= 0; height-- {
+		peakSize := uint64(1) << height
+		if size&peakSize == 0 {
+			continue
+		}
+		if index < peakStart+peakSize {
+			peakHeight = uint64(height)
+			break
+		}
+		peakStart += peakSize
+		peakPos++
+	}
+
+	numPeaks := bits.OnesCount64(size)
+	proofLen := int(peakHeight) + numPeaks - 1
+	switch {
+	case len(proof) < proofLen:
+		return false, validationError(fmt.Errorf("%w: proof has %d nodes, expected %d", ErrShortProof, len(proof),
+			proofLen))
+	case len(proof) > proofLen:
+		return checkMatch(false, validatorOpts)
+	}
+
+	node := slices.Clone(leaf)
+	local := index - peakStart
+	for height := range peakHeight {
+		if (local>>height)&1 == 1 {
+			node = hasher.Hash(node, proof[height], node)
+		} else {
+			node = hasher.Hash(node, node, proof[height])
+		}
+	}
+
+	peaks := make([][]byte, 0, numPeaks)
+	peaks = append(peaks, proof[peakHeight:int(peakHeight)+peakPos]...)
+	peaks = append(peaks, node)
+	peaks = append(peaks, proof[int(peakHeight)+peakPos:]...)
+	return checkMatch(bytes.Equal(baggedRoot, BagPeaks(peaks, hasher)), validatorOpts)
+}
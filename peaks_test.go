@@ -0,0 +1,207 @@
+package merkle_test
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/fasmat/merkle"
+)
+
+func TestTreePeaksWithHeights(t *testing.T) {
+	t.Parallel()
+
+	tree := merkle.NewTree()
+	for i := range 10 {
+		tree.Add(leaf(uint64(i)))
+	}
+
+	peaks := tree.PeaksWithHeights()
+	if len(peaks) != 2 {
+		t.Fatalf("Expected 2 peaks, got %d", len(peaks))
+	}
+	if peaks[0].Height != 3 || peaks[1].Height != 1 {
+		t.Errorf("Expected peaks at heights 3 and 1, got %d and %d", peaks[0].Height, peaks[1].Height)
+	}
+	expected := "89a0f1577268cc19b0a39c7a69f804fd140640c699585eb635ebb03c06154cce"
+	if hex.EncodeToString(peaks[0].Hash) != expected {
+		t.Errorf("Expected first peak to be %s, got %x", expected, peaks[0].Hash)
+	}
+	expectedPeak := merkle.Sha256().Hash(nil, leaf(8), leaf(9))
+	if !bytes.Equal(peaks[1].Hash, expectedPeak) {
+		t.Errorf("Expected second peak to be %x, got %x", expectedPeak, peaks[1].Hash)
+	}
+}
+
+func TestBuildFromPeaks(t *testing.T) {
+	t.Parallel()
+
+	original := merkle.NewTree()
+	for i := range 10 {
+		original.Add(leaf(uint64(i)))
+	}
+
+	restored, err := merkle.TreeBuilder().
+		WithLeafToProve(4). // ignored, leaf 4 is covered by the peaks
+		WithLeafToProve(12).
+		BuildFromPeaks(original.PeaksWithHeights())
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := "59f32a43534fe4c4c0966421aef624267cdf65bd11f74998c60f27c7caccb12d"
+	if root := hex.EncodeToString(restored.Root()); root != expected {
+		t.Errorf("Expected root of restored tree to be %s, got %s", expected, root)
+	}
+
+	for i := range uint64(6) {
+		original.Add(leaf(10 + i))
+		restored.Add(leaf(10 + i))
+		if !bytes.Equal(original.Root(), restored.Root()) {
+			t.Errorf("Expected roots after %d leaves to match: %x != %x", 11+i, original.Root(), restored.Root())
+		}
+	}
+
+	root, proof := restored.RootAndProof()
+	valid, err := merkle.ValidateProof(root, map[uint64][]byte{12: leaf(12)}, proof)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !valid {
+		t.Error("proof is not valid")
+	}
+}
+
+func TestBuildFromPeaksInvalid(t *testing.T) {
+	t.Parallel()
+
+	tt := []struct {
+		name  string
+		peaks []merkle.Peak
+	}{
+		{
+			name:  "increasing heights",
+			peaks: []merkle.Peak{{Height: 1, Hash: leaf(0)}, {Height: 3, Hash: leaf(1)}},
+		},
+		{
+			name:  "duplicate heights",
+			peaks: []merkle.Peak{{Height: 3, Hash: leaf(0)}, {Height: 3, Hash: leaf(1)}},
+		},
+		{
+			name:  "invalid hash size",
+			peaks: []merkle.Peak{{Height: 3, Hash: leaf(0)[:16]}},
+		},
+		{
+			name:  "height too large",
+			peaks: []merkle.Peak{{Height: 64, Hash: leaf(0)}},
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			_, err := merkle.TreeBuilder().BuildFromPeaks(tc.peaks)
+			if !errors.Is(err, merkle.ErrInvalidPeaks) {
+				t.Errorf("Expected error to be %v, got %v", merkle.ErrInvalidPeaks, err)
+			}
+		})
+	}
+}
+
+// mmrProof returns the MMR proof for the leaf with the given index in an MMR with size leaves created with leaf().
+func mmrProof(t *testing.T, size, index uint64) [][]byte {
+	t.Helper()
+
+	tree := merkle.NewTree()
+	for i := range size {
+		tree.Add(leaf(i))
+	}
+
+	var proof, otherPeaks [][]byte
+	start := uint64(0)
+	for _, p := range tree.PeaksWithHeights() {
+		end := start + 1<<p.Height
+		if index < start || index >= end {
+			otherPeaks = append(otherPeaks, p.Hash)
+			start = end
+			continue
+		}
+
+		peakTree := merkle.TreeBuilder().
+			WithLeafToProve(index - start).
+			Build()
+		for i := start; i < end; i++ {
+			peakTree.Add(leaf(i))
+		}
+		_, proof = peakTree.RootAndProof()
+		start = end
+	}
+	return append(proof, otherPeaks...)
+}
+
+func TestVerifyMMRProof(t *testing.T) {
+	t.Parallel()
+
+	// bagged roots of MMRs with the leaves created by leaf(), computed independently
+	tt := []struct {
+		size uint64
+		root string
+	}{
+		{size: 1, root: "0000000000000000000000000000000000000000000000000000000000000000"},
+		{size: 2, root: "cb592844121d926f1ca3ad4e1d6fb9d8e260ed6e3216361f7732e975a0e8bbf6"},
+		{size: 3, root: "975d8dfa71d715cead145c4b80c474d210471dbc7ff614e9dab53887d61bc957"},
+		{size: 7, root: "47a288cb996bfaaa0703d976c338841884f938c06e62a161e4772d6fb68a4a69"},
+		{size: 10, root: "949b5fe5fbfbf99df09ac17c9109b0403ddd27f434d0a6e878d9d74181cfb014"},
+		{size: 11, root: "14163ec5b5a4f5eb825ef92f9a9b2b0a0137dfdd4bed4f9c7e1c89b86e55ecee"},
+	}
+
+	for _, tc := range tt {
+		t.Run(fmt.Sprintf("size %d", tc.size), func(t *testing.T) {
+			t.Parallel()
+
+			tree := merkle.NewTree()
+			for i := range tc.size {
+				tree.Add(leaf(i))
+			}
+			var peaks [][]byte
+			for _, p := range tree.PeaksWithHeights() {
+				peaks = append(peaks, p.Hash)
+			}
+			root := merkle.BagPeaks(peaks, merkle.Sha256())
+			if hex.EncodeToString(root) != tc.root {
+				t.Fatalf("Expected bagged root to be %s, got %x", tc.root, root)
+			}
+
+			for index := range tc.size {
+				proof := mmrProof(t, tc.size, index)
+				valid, err := merkle.VerifyMMRProof(root, leaf(index), index, tc.size, proof)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if !valid {
+					t.Errorf("proof for leaf %d is not valid", index)
+				}
+
+				valid, err = merkle.VerifyMMRProof(root, leaf(index+1), index, tc.size, proof)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if valid {
+					t.Errorf("Expected proof for leaf %d to be invalid with a different leaf", index)
+				}
+			}
+		})
+	}
+}
+
+func TestVerifyMMRProofShort(t *testing.T) {
+	t.Parallel()
+
+	proof := mmrProof(t, 10, 4)
+	_, err := merkle.VerifyMMRProof(make([]byte, 32), leaf(4), 4, 10, proof[:len(proof)-1])
+	if !errors.Is(err, merkle.ErrShortProof) {
+		t.Errorf("Expected error to be %v, got %v", merkle.ErrShortProof, err)
+	}
+}
@@ -0,0 +1,61 @@
+package merkle
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+)
+
+// ErrInvalidProofReference is returned when a proof references a node that is not part of the ProofTable.
+var ErrInvalidProofReference = errors.New("invalid proof reference")
+
+// ProofTable deduplicates the nodes of many proofs of the same tree. Proofs for different leaves of a tree share most
+// of their nodes (especially the ones close to the root), by interning them every unique node is stored only once and
+// each proof is expressed as a list of references into the table.
+//
+// A ProofTable is not safe for concurrent use.
+type ProofTable struct {
+	nodes [][]byte
+	index map[string]int
+}
+
+// NewProofTable creates a new empty ProofTable.
+func NewProofTable() *ProofTable {
+	return &ProofTable{
+		index: make(map[string]int),
+	}
+}
+
+// Intern adds the nodes of the given proof to the table and returns the proof as references to the nodes in the
+// table. Nodes that are already part of the table are not stored again.
+func (pt *ProofTable) Intern(proof [][]byte) []int {
+	refs := make([]int, len(proof))
+	for i, node := range proof {
+		ref, ok := pt.index[string(node)]
+		if !ok {
+			ref = len(pt.nodes)
+			pt.nodes = append(pt.nodes, slices.Clone(node))
+			pt.index[string(node)] = ref
+		}
+		refs[i] = ref
+	}
+	return refs
+}
+
+// Proof reconstructs a proof from the given references. The nodes of the returned proof are shared with the table
+// and must not be modified.
+func (pt *ProofTable) Proof(refs []int) ([][]byte, error) {
+	proof := make([][]byte, len(refs))
+	for i, ref := range refs {
+		if ref < 0 || ref >= len(pt.nodes) {
+			return nil, fmt.Errorf("%w: %d", ErrInvalidProofReference, ref)
+		}
+		proof[i] = pt.nodes[ref]
+	}
+	return proof, nil
+}
+
+// Len returns the number of unique nodes in the table.
+func (pt *ProofTable) Len() int {
+	return len(pt.nodes)
+}
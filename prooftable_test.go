@@ -0,0 +1,68 @@
+package merkle_test
+
+import (
+	"encoding/binary"
+	"errors"
+	"testing"
+
+	"github.com/fasmat/merkle"
+)
+
+func TestProofTable(t *testing.T) {
+	t.Parallel()
+
+	const numLeaves = 64
+
+	table := merkle.NewProofTable()
+	refs := make([][]int, numLeaves)
+	roots := make([][]byte, numLeaves)
+	totalNodes := 0
+	for leaf := range uint64(numLeaves) {
+		tree := merkle.TreeBuilder().
+			WithLeafToProve(leaf).
+			Build()
+		buf := make([]byte, tree.NodeSize())
+		for i := range numLeaves {
+			binary.LittleEndian.PutUint64(buf, uint64(i))
+			tree.Add(buf)
+		}
+
+		var proof [][]byte
+		roots[leaf], proof = tree.RootAndProof()
+		refs[leaf] = table.Intern(proof)
+		totalNodes += len(proof)
+	}
+
+	// every node of the tree except the root is part of a proof exactly once
+	if table.Len() != 2*numLeaves-2 {
+		t.Errorf("Expected table to contain %d nodes, got %d", 2*numLeaves-2, table.Len())
+	}
+	if table.Len() >= totalNodes {
+		t.Errorf("Expected table to contain less than %d nodes, got %d", totalNodes, table.Len())
+	}
+
+	for leaf := range uint64(numLeaves) {
+		proof, err := table.Proof(refs[leaf])
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		leaves := map[uint64][]byte{leaf: make([]byte, 32)}
+		binary.LittleEndian.PutUint64(leaves[leaf], leaf)
+		valid, err := merkle.ValidateProof(roots[leaf], leaves, proof)
+		if err != nil {
+			t.Error(err)
+		}
+		if !valid {
+			t.Errorf("proof for leaf %d is not valid", leaf)
+		}
+	}
+
+	proof, err := table.Proof([]int{0, table.Len()})
+	if !errors.Is(err, merkle.ErrInvalidProofReference) {
+		t.Errorf("expected error: %v, got: %v", merkle.ErrInvalidProofReference, err)
+	}
+	if proof != nil {
+		t.Error("expected no proof")
+	}
+}
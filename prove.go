@@ -0,0 +1,32 @@
+package merkle
+
+import (
+	"fmt"
+)
+
+// Prove builds a tree with the given configuration from the leaves and returns its root, the proven leaves and the
+// proof for the leaves with the given indices. The results can be passed to ValidateProof as they are, together with
+// the validator options matching the configuration (e.g. WithHasher if the hasher was changed).
+//
+// The indices are positions in leaves. An error wrapping ErrInvalidLeafIndex is returned if an index is out of range
+// and ErrNoLeaves if no indices are given. Leaves to prove set by the configuration are overridden. The values of the
+// proven leaves are not copied.
+func Prove(
+	leaves [][]byte,
+	indices []uint64,
+	opts ...BuilderOpt,
+) (root []byte, provenLeaves map[uint64][]byte, proof [][]byte, err error) {
+	if len(indices) == 0 {
+		return nil, nil, nil, ErrNoLeaves
+	}
+	provenLeaves = make(map[uint64][]byte, len(indices))
+	for _, idx := range indices {
+		if idx >= uint64(len(leaves)) {
+			return nil, nil, nil, fmt.Errorf("%w: %d (tree has %d leaves)", ErrInvalidLeafIndex, idx, len(leaves))
+		}
+		provenLeaves[idx] = leaves[idx]
+	}
+
+	root, proof = buildWithConfig(leaves, opts, indices...)
+	return root, provenLeaves, proof, nil
+}
@@ -0,0 +1,72 @@
+package merkle_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/fasmat/merkle"
+)
+
+func TestProve(t *testing.T) {
+	t.Parallel()
+
+	leaves := make([][]byte, 8)
+	for i := range leaves {
+		leaves[i] = leaf(uint64(i))
+	}
+
+	root, provenLeaves, proof, err := merkle.Prove(leaves, []uint64{0, 4, 7})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(provenLeaves) != 3 {
+		t.Errorf("Expected 3 proven leaves, got %d", len(provenLeaves))
+	}
+
+	valid, err := merkle.ValidateProof(root, provenLeaves, proof)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !valid {
+		t.Error("proof is not valid")
+	}
+}
+
+func TestProveWithOptions(t *testing.T) {
+	t.Parallel()
+
+	leaves := make([][]byte, 10)
+	for i := range leaves {
+		leaves[i] = leaf(uint64(i))
+	}
+
+	root, provenLeaves, proof, err := merkle.Prove(leaves, []uint64{3, 9},
+		func(b *merkle.Builder) { b.WithLeafHasher(merkle.SequentialWorkHasher()) })
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	valid, err := merkle.ValidateProof(root, provenLeaves, proof, merkle.WithLeafHasher(merkle.SequentialWorkHasher()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !valid {
+		t.Error("proof is not valid")
+	}
+}
+
+func TestProveInvalid(t *testing.T) {
+	t.Parallel()
+
+	leaves := [][]byte{leaf(0), leaf(1)}
+
+	_, _, _, err := merkle.Prove(leaves, nil)
+	if !errors.Is(err, merkle.ErrNoLeaves) {
+		t.Errorf("Expected error %v, got %v", merkle.ErrNoLeaves, err)
+	}
+
+	_, _, _, err = merkle.Prove(leaves, []uint64{2})
+	if !errors.Is(err, merkle.ErrInvalidLeafIndex) {
+		t.Errorf("Expected error %v, got %v", merkle.ErrInvalidLeafIndex, err)
+	}
+}
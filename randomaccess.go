@@ -0,0 +1,86 @@
+package merkle
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+)
+
+var (
+	// ErrDuplicateLeaf is returned when a leaf is added to a RandomAccessBuilder more than once.
+	ErrDuplicateLeaf = errors.New("duplicate leaf")
+
+	// ErrInvalidLeafIndex is returned when a leaf is added at an index that is not part of the tree.
+	ErrInvalidLeafIndex = errors.New("invalid leaf index")
+
+	// ErrMissingLeaf is returned when a RandomAccessBuilder is finalized while there are gaps in the added leaves.
+	ErrMissingLeaf = errors.New("missing leaf")
+)
+
+// RandomAccessBuilder builds a Merkle tree from leaves that are added in arbitrary order. Leaves are buffered until
+// all leaves before them have been added, afterwards they are added to the underlying tree in order. Only leaves that
+// arrive ahead of a missing leaf are kept in memory.
+//
+// Use Builder.BuildRandomAccess to create a RandomAccessBuilder.
+type RandomAccessBuilder struct {
+	tree    *Tree
+	pending map[uint64][]byte
+}
+
+// BuildRandomAccess constructs a RandomAccessBuilder for a Merkle tree with the specified properties. If the number of
+// leaves was declared with WithExpectedLeafCount, AddAt rejects indices beyond it and Finalize also reports leaves
+// missing at the end of the tree.
+func (tb *Builder) BuildRandomAccess() *RandomAccessBuilder {
+	return &RandomAccessBuilder{
+		tree:    tb.Build(),
+		pending: make(map[uint64][]byte),
+	}
+}
+
+// AddAt adds the leaf with the given value at the given index. If an index offset was set with WithLeafIndexOffset
+// the index is interpreted as global index.
+//
+// An error wrapping ErrDuplicateLeaf is returned if a leaf was already added at the index and an error wrapping
+// ErrInvalidLeafIndex if the index is lower than the index offset of the tree or not lower than the expected leaf
+// count.
+func (b *RandomAccessBuilder) AddAt(index uint64, value []byte) error {
+	if index < b.tree.indexOffset {
+		return fmt.Errorf("%w: index %d is before the first leaf %d", ErrInvalidLeafIndex, index, b.tree.indexOffset)
+	}
+	local := index - b.tree.indexOffset
+	if b.tree.checkLeafCount && local >= b.tree.expectedLeafCount {
+		return fmt.Errorf("%w: index %d is beyond the expected %d leaves", ErrInvalidLeafIndex, index,
+			b.tree.expectedLeafCount)
+	}
+	if _, ok := b.pending[local]; ok || local < b.tree.currentLeaf {
+		return fmt.Errorf("%w: index %d", ErrDuplicateLeaf, index)
+	}
+
+	if local != b.tree.currentLeaf {
+		b.pending[local] = slices.Clone(value)
+		return nil
+	}
+
+	b.tree.Add(value)
+	for {
+		next, ok := b.pending[b.tree.currentLeaf]
+		if !ok {
+			return nil
+		}
+		delete(b.pending, b.tree.currentLeaf)
+		b.tree.Add(next)
+	}
+}
+
+// Finalize returns the tree built from all added leaves. If leaves were added after a gap (i.e. there is a missing
+// leaf at a lower index than an added leaf), an error wrapping ErrMissingLeaf is returned. Without an expected leaf
+// count, leaves missing at the end of the tree cannot be detected; with WithExpectedLeafCount they are reported as
+// missing as well.
+//
+// The returned tree can be used to add more leaves sequentially.
+func (b *RandomAccessBuilder) Finalize() (*Tree, error) {
+	if len(b.pending) > 0 || (b.tree.checkLeafCount && b.tree.currentLeaf < b.tree.expectedLeafCount) {
+		return nil, fmt.Errorf("%w: index %d", ErrMissingLeaf, b.tree.indexOffset+b.tree.currentLeaf)
+	}
+	return b.tree, nil
+}
@@ -0,0 +1,134 @@
+package merkle_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"testing"
+
+	"github.com/fasmat/merkle"
+)
+
+func TestRandomAccessBuilder(t *testing.T) {
+	t.Parallel()
+
+	builder := merkle.TreeBuilder().
+		WithLeafToProve(4).
+		BuildRandomAccess()
+
+	for _, i := range []uint64{5, 2, 7, 0, 4, 1, 6, 3} {
+		b := make([]byte, 32)
+		binary.LittleEndian.PutUint64(b, i)
+		if err := builder.AddAt(i, b); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tree, err := builder.Finalize()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	root, proof := tree.RootAndProof()
+	rootString := hex.EncodeToString(root)
+	if rootString != "89a0f1577268cc19b0a39c7a69f804fd140640c699585eb635ebb03c06154cce" {
+		t.Errorf(
+			"Expected hash to be 89a0f1577268cc19b0a39c7a69f804fd140640c699585eb635ebb03c06154cce, got %s",
+			rootString,
+		)
+	}
+
+	expectedProof := []string{
+		"0500000000000000000000000000000000000000000000000000000000000000",
+		"fa670379e5c2212ed93ff09769622f81f98a91e1ec8fb114d607dd25220b9088",
+		"ba94ffe7edabf26ef12736f8eb5ce74d15bedb6af61444ae2906e926b1a95084",
+	}
+	if len(proof) != len(expectedProof) {
+		t.Fatalf("Expected proof to be of length %d, got %d", len(expectedProof), len(proof))
+	}
+	for i, p := range proof {
+		pString := hex.EncodeToString(p)
+		if pString != expectedProof[i] {
+			t.Errorf("Expected proof[%d] to be %s, got %s", i, expectedProof[i], pString)
+		}
+	}
+}
+
+func TestRandomAccessBuilderInvalid(t *testing.T) {
+	t.Parallel()
+
+	builder := merkle.TreeBuilder().
+		WithLeafIndexOffset(8).
+		BuildRandomAccess()
+
+	b := make([]byte, 32)
+	for _, i := range []uint64{8, 10} {
+		binary.LittleEndian.PutUint64(b, i)
+		if err := builder.AddAt(i, b); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, i := range []uint64{8, 10} {
+		if err := builder.AddAt(i, b); !errors.Is(err, merkle.ErrDuplicateLeaf) {
+			t.Errorf("expected error: %v, got: %v", merkle.ErrDuplicateLeaf, err)
+		}
+	}
+
+	if err := builder.AddAt(7, b); !errors.Is(err, merkle.ErrInvalidLeafIndex) {
+		t.Errorf("expected error: %v, got: %v", merkle.ErrInvalidLeafIndex, err)
+	}
+
+	tree, err := builder.Finalize()
+	if !errors.Is(err, merkle.ErrMissingLeaf) {
+		t.Errorf("expected error: %v, got: %v", merkle.ErrMissingLeaf, err)
+	}
+	if tree != nil {
+		t.Error("expected no tree")
+	}
+}
+
+func TestRandomAccessBuilderExpectedLeafCount(t *testing.T) {
+	t.Parallel()
+
+	builder := merkle.TreeBuilder().
+		WithExpectedLeafCount(8).
+		BuildRandomAccess()
+
+	b := make([]byte, 32)
+	for _, i := range []uint64{3, 0, 5, 1, 4, 2} {
+		binary.LittleEndian.PutUint64(b, i)
+		if err := builder.AddAt(i, b); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := builder.AddAt(8, b); !errors.Is(err, merkle.ErrInvalidLeafIndex) {
+		t.Errorf("expected error: %v, got: %v", merkle.ErrInvalidLeafIndex, err)
+	}
+
+	tree, err := builder.Finalize()
+	if !errors.Is(err, merkle.ErrMissingLeaf) {
+		t.Errorf("expected error: %v, got: %v", merkle.ErrMissingLeaf, err)
+	}
+	if tree != nil {
+		t.Error("expected no tree")
+	}
+
+	for _, i := range []uint64{7, 6} {
+		binary.LittleEndian.PutUint64(b, i)
+		if err := builder.AddAt(i, b); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tree, err = builder.Finalize()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected, _ := hex.DecodeString("89a0f1577268cc19b0a39c7a69f804fd140640c699585eb635ebb03c06154cce")
+	if root := tree.Root(); !bytes.Equal(root, expected) {
+		t.Errorf("expected root: %x, got: %x", expected, root)
+	}
+}
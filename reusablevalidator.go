@@ -0,0 +1,79 @@
+package merkle
+
+import (
+	"fmt"
+	"slices"
+)
+
+// ReusableValidator validates single leaf proofs against a fixed root. The options are parsed and the buffers needed
+// for validation are allocated once and reused for every call to Validate, which makes it suitable to validate many
+// proofs of the same tree, e.g. in a service that answers a high number of requests.
+//
+// A ReusableValidator is not safe for concurrent use, use one per goroutine instead.
+type ReusableValidator struct {
+	root []byte
+	opts *validatorOpts
+
+	v       *validator
+	leaves  map[uint64][]byte
+	indices []uint64
+	buf     []byte
+}
+
+// NewReusableValidator creates a ReusableValidator for proofs against the given root configured with the given options.
+func NewReusableValidator(root []byte, opts ...ValidatorOpt) *ReusableValidator {
+	validatorOpts := parseValidatorOpts(opts)
+	v := newValidator(validatorOpts)
+	return &ReusableValidator{
+		root: slices.Clone(root),
+		opts: validatorOpts,
+
+		v:       v,
+		leaves:  make(map[uint64][]byte, 1),
+		indices: make([]uint64, 0, 1),
+		buf:     make([]byte, 0, max(v.leafHasher.Size(), v.hasher.Size())),
+	}
+}
+
+// Validate validates the proof for the leaf with the given index against the root of the validator. It behaves like
+// ValidateProof with a single leaf.
+func (r *ReusableValidator) Validate(index uint64, leaf []byte, proof [][]byte) (bool, error) {
+	valid, err := r.validate(r.root, index, leaf, proof)
+	return valid, validationError(err)
+}
+
+// Reset drops the references to the leaf and proof of the last call to Validate, so they can be garbage collected
+// while the validator is idle. The buffers of the validator are kept and reused by the next call to Validate.
+func (r *ReusableValidator) Reset() {
+	clear(r.leaves)
+	r.indices = r.indices[:0]
+	r.v.clearState()
+}
+
+// validate validates the proof for the leaf with the given index against the given root.
+func (r *ReusableValidator) validate(root []byte, index uint64, leaf []byte, proof [][]byte) (bool, error) {
+	if index < r.opts.indexOffset {
+		return false, fmt.Errorf("%w: leaf %d is below the index offset %d", ErrInvalidLeafIndex, index,
+			r.opts.indexOffset)
+	}
+	index -= r.opts.indexOffset
+	if r.opts.copyLeaves {
+		leaf = slices.Clone(leaf)
+	}
+	clear(r.leaves)
+	r.leaves[index] = leaf
+	root, proof, rootMatch, err := rootFromProof(root, proof, r.opts)
+	if err != nil {
+		return false, err
+	}
+	if err := checkRootLength(root, r.leaves, proof, r.opts); err != nil {
+		return false, err
+	}
+
+	r.indices = append(r.indices[:0], index)
+	calculatedRoot, height, err := r.v.reconstruct(r.leaves, r.indices, proof, r.opts, r.buf[:0])
+	if err != nil {
+		return false, err
+	}
+	return checkMatch(rootMatch && matchRoot(root, calculatedRoot, height, r.opts), r.opts)
+}
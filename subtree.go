@@ -0,0 +1,296 @@
+package merkle
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"math/bits"
+	"slices"
+)
+
+var (
+	// ErrNoSubtrees is returned when Combine is called without any subtrees.
+	ErrNoSubtrees = errors.New("no subtrees to combine")
+
+	// ErrInvalidSubtree is returned when the given subtrees cannot be combined into a single tree.
+	ErrInvalidSubtree = errors.New("invalid subtree")
+
+	// ErrIncompleteSubtree is returned when the given leaves do not form a complete (balanced) subtree.
+	ErrIncompleteSubtree = errors.New("leaves do not form a complete subtree")
+
+	// ErrUnsupportedLayerHasher is returned by VerifySubtreeRoot when a layer hasher is set with WithLayerHasher.
+	ErrUnsupportedLayerHasher = errors.New("layer hasher is not supported")
+)
+
+// Subtree is a Merkle tree that covers a contiguous range of leaves of a larger tree. Subtrees can be built
+// independently (e.g. concurrently in multiple goroutines) and afterwards be merged into a single tree with Combine.
+type Subtree struct {
+	*Tree
+}
+
+// BuildSubtree constructs a subtree with the specified properties whose first leaf has the given global index. The
+// leaves to prove are interpreted as global indices, see WithLeafIndexOffset.
+//
+// The builder is not modified, so the same builder can be used to build all subtrees of a tree. A node sink set with
+// WithFullNodeSink receives the indices of the nodes in the layers of the larger tree, so the nodes of different
+// subtrees do not collide.
+func (tb *Builder) BuildSubtree(start uint64) *Subtree {
+	sb := *tb
+	sb.indexOffset = start
+	if sink := tb.nodeSink; sink != nil && start != 0 {
+		sb.nodeSink = func(height int, index uint64, hash []byte) {
+			sink(height, index+start>>height, hash)
+		}
+	}
+	return &Subtree{Tree: sb.Build()}
+}
+
+// Range returns the global index of the first leaf of the subtree and the index after the last leaf that was added.
+func (s *Subtree) Range() (uint64, uint64) {
+	return s.indexOffset, s.indexOffset + s.currentLeaf
+}
+
+// Combine merges the given subtrees into a single tree. The subtrees must be balanced, have the same number of
+// leaves and cover contiguous ranges of leaves in the order they are given. The first subtree has to be aligned to its
+// size, i.e. its first leaf index has to be a multiple of the number of leaves in the subtree.
+//
+// The root of the combined tree and its proof are identical to those of a tree that was built by adding all leaves
+// sequentially. Further leaves can be added to the combined tree after merging. The settings of the combined tree are
+// those of the first subtree: the checkpoints it recorded (see WithRecordedCheckpoints) are kept and the checkpoints
+// of the combined tree are recorded while merging, a leaf found by its hash (see WithLeafHashToProve) is proven by the
+// combined tree and the node sink receives the nodes above the subtrees.
+//
+// All subtrees have to be built with the same settings, e.g. by the same Builder, otherwise an error wrapping
+// ErrInvalidSubtree is returned. Subtrees built with a sequential leaf hasher cannot be combined, since their leaves
+// depend on all their left siblings.
+func Combine(subtrees []*Subtree) (*Tree, error) {
+	if len(subtrees) == 0 {
+		return nil, ErrNoSubtrees
+	}
+	if err := validateSubtrees(subtrees); err != nil {
+		return nil, err
+	}
+
+	first := subtrees[0].Tree
+	size := first.currentLeaf
+	height := bits.TrailingZeros64(size)
+	tree := &Tree{
+		hasher:     first.hasher,
+		leafHasher: first.leafHasher,
+
+		buf:     make([]byte, first.hasher.Size()),
+		leafBuf: make([]byte, first.leafHasher.Size()),
+		padding: slices.Clone(first.padding),
+
+		emptyLeaf: first.emptyLeaf,
+
+		minHeight:   first.minHeight,
+		indexOffset: first.indexOffset,
+
+		targetLeafHash: first.targetLeafHash,
+		requireTargets: first.requireTargets,
+
+		checkLeafCount:    first.checkLeafCount,
+		expectedLeafCount: first.expectedLeafCount,
+
+		layerHasher: first.layerHasher,
+		nodeSink:    first.nodeSink,
+		liveRoot:    first.liveRoot,
+		sizeBinding: first.sizeBinding,
+	}
+	if first.provenLeaves != nil {
+		tree.provenLeaves = make(map[uint64][]byte)
+	}
+	if first.checkpoints != nil {
+		// the first subtree holds the first leaves of the combined tree, so its checkpoints are checkpoints of the tree
+		tree.checkpoints = make(map[uint64][]byte, len(first.checkpoints))
+		for count, root := range first.checkpoints {
+			tree.checkpoints[count] = slices.Clone(root)
+		}
+	}
+
+	for k, s := range subtrees {
+		offset := uint64(k) * size
+		onProvingPath := false
+		for _, idx := range s.provenIndices {
+			if idx >= size {
+				break
+			}
+			tree.provenIndices = append(tree.provenIndices, idx+offset)
+			onProvingPath = true
+		}
+		if tree.provenLeaves != nil {
+			for idx, leaf := range s.provenLeaves {
+				tree.provenLeaves[idx+offset] = slices.Clone(leaf)
+			}
+		}
+
+		// the proof nodes of the subtree are collected before any node above the subtree is added to the proof
+		for _, p := range s.proof {
+			tree.proof = append(tree.proof, slices.Clone(p))
+		}
+		if s.targetFound && !tree.targetFound {
+			tree.targetFound = true
+			tree.targetIndex = s.targetIndex
+		}
+
+		tree.currentLeaf += size
+		tree.addNode(height, slices.Clone(s.parkedNodes[height]), onProvingPath)
+		if tree.checkpoints != nil {
+			tree.recordCheckpoint()
+		}
+	}
+
+	// leaves to prove after the last subtree can still be proven when adding more leaves to the combined tree
+	offset := uint64(len(subtrees)-1) * size
+	for _, idx := range subtrees[len(subtrees)-1].leavesToProve {
+		tree.leavesToProve = append(tree.leavesToProve, idx+offset)
+		tree.provenIndices = append(tree.provenIndices, idx+offset)
+	}
+	return tree, nil
+}
+
+// validateSubtrees checks that the given subtrees can be combined into a single tree.
+func validateSubtrees(subtrees []*Subtree) error {
+	first := subtrees[0].Tree
+	size := first.currentLeaf
+	if size == 0 || size&(size-1) != 0 {
+		return fmt.Errorf("%w: subtree has %d leaves, expected a power of two", ErrInvalidSubtree, size)
+	}
+	if first.indexOffset%size != 0 {
+		return fmt.Errorf("%w: subtree starting at %d is not aligned to its size %d",
+			ErrInvalidSubtree, first.indexOffset, size)
+	}
+
+	for k, s := range subtrees {
+		if s.leafHasher.Sequential() {
+			return fmt.Errorf("%w: subtree %d uses a sequential leaf hasher", ErrInvalidSubtree, k)
+		}
+		if _, ok := s.leafHasher.(IndexedLeafHasher); ok {
+			return fmt.Errorf("%w: subtree %d uses an indexed leaf hasher", ErrInvalidSubtree, k)
+		}
+		if s.currentLeaf != size {
+			return fmt.Errorf("%w: subtree %d has %d leaves, expected %d", ErrInvalidSubtree, k, s.currentLeaf, size)
+		}
+		if start := first.indexOffset + uint64(k)*size; s.indexOffset != start {
+			return fmt.Errorf("%w: subtree %d starts at %d, expected %d", ErrInvalidSubtree, k, s.indexOffset, start)
+		}
+	}
+	return validateSubtreeSettings(subtrees)
+}
+
+// validateSubtreeSettings checks that all subtrees are built with the same settings as the first subtree, so the
+// combined tree can take over its settings, and that the leaf hash to prove was found in at most one subtree.
+func validateSubtreeSettings(subtrees []*Subtree) error {
+	found := -1 // the subtree that found the leaf hash to prove
+	for k, s := range subtrees {
+		if setting := subtreeMismatch(subtrees[0].Tree, s.Tree); setting != "" {
+			return fmt.Errorf("%w: subtree %d uses a different %s than subtree 0", ErrInvalidSubtree, k, setting)
+		}
+		if !s.targetFound {
+			continue
+		}
+		if found >= 0 {
+			return fmt.Errorf("%w: leaf hash to prove was found in subtrees %d and %d", ErrInvalidSubtree, found, k)
+		}
+		found = k
+	}
+	return nil
+}
+
+// subtreeMismatch returns the name of the first setting the given subtree is built with that differs from the first
+// subtree, or an empty string if both are built with the same settings. Hashers are compared by their output, since
+// each subtree built by the same builder might use its own instance.
+func subtreeMismatch(first, s *Tree) string {
+	switch {
+	case !sameHasher(first.hasher, s.hasher):
+		return "hasher"
+	case !sameLeafHasher(first.leafHasher, s.leafHasher):
+		return "leaf hasher"
+	case (first.layerHasher == nil) != (s.layerHasher == nil):
+		return "layer hasher"
+	case !bytes.Equal(first.padding, s.padding):
+		return "padding"
+	case !bytes.Equal(first.emptyLeaf, s.emptyLeaf):
+		return "empty leaf"
+	case first.minHeight != s.minHeight:
+		return "minimum height"
+	case first.liveRoot != s.liveRoot, first.sizeBinding != s.sizeBinding:
+		return "root configuration"
+	case (first.nodeSink == nil) != (s.nodeSink == nil):
+		return "node sink"
+	case (first.checkpoints == nil) != (s.checkpoints == nil):
+		return "checkpoint recording"
+	case (first.provenLeaves == nil) != (s.provenLeaves == nil):
+		return "retention of proven leaves"
+	case !bytes.Equal(first.targetLeafHash, s.targetLeafHash), first.requireTargets != s.requireTargets:
+		return "leaf to prove configuration"
+	case first.checkLeafCount != s.checkLeafCount, first.expectedLeafCount != s.expectedLeafCount:
+		return "expected leaf count"
+	}
+	return ""
+}
+
+// sameHasher returns true if both hashers produce the same nodes.
+func sameHasher(a, b Hasher) bool {
+	if a.Size() != b.Size() {
+		return false
+	}
+	probe := make([]byte, a.Size())
+	return bytes.Equal(a.Hash(nil, probe, probe), b.Hash(nil, probe, probe))
+}
+
+// sameLeafHasher returns true if both leaf hashers produce the same leaves. The leaf hashers of subtrees that can be
+// combined neither depend on the index nor on the siblings of a leaf.
+func sameLeafHasher(a, b LeafHasher) bool {
+	if a.Size() != b.Size() || a.Sequential() != b.Sequential() {
+		return false
+	}
+	return bytes.Equal(a.Hash(nil, []byte{}, nil), b.Hash(nil, []byte{}, nil))
+}
+
+// SubtreeRootFromLeaves computes the root of the complete subtree formed by the given leaves with the given hasher.
+// The leaves are used as is (see ValueLeafs) and their number has to be a power of two, otherwise an error wrapping
+// ErrIncompleteSubtree is returned. If hasher is nil the default SHA256 hasher is used.
+//
+// In a tree sharded into subtrees of s leaves the leaves [k*s, (k+1)*s) form the subtree with index k at height
+// log2(s). Its root can be verified against the root of the whole tree with VerifySubtreeRoot.
+func SubtreeRootFromLeaves(leaves [][]byte, hasher Hasher) ([]byte, error) {
+	if len(leaves) == 0 {
+		return nil, ErrNoLeaves
+	}
+	if len(leaves)&(len(leaves)-1) != 0 {
+		return nil, fmt.Errorf("%w: %d leaves", ErrIncompleteSubtree, len(leaves))
+	}
+
+	if hasher == nil {
+		hasher = Sha256()
+	}
+	// the leaf hasher is set explicitly, otherwise hashers like RFC6962Hasher provide their own
+	tree := TreeBuilder().WithHasher(hasher).WithLeafHasher(ValueLeafs(hasher.Size())).Build()
+	for _, leaf := range leaves {
+		tree.Add(leaf)
+	}
+	return tree.Root(), nil
+}
+
+// VerifySubtreeRoot validates that the root of the subtree with the given index in its layer is part of the tree
+// with the given root. The path contains the siblings of the subtree root and its ancestors, i.e. it is the proof
+// of the first leaf of the subtree without the nodes inside the subtree. For a subtree of s leaves these are all
+// nodes of the proof after the first log2(s) nodes.
+//
+// The subtree root is used as is, so a leaf hasher set with the options (including WithLeafNonces) or provided by the
+// hasher (e.g. RFC6962Hasher) is ignored. Layer hashers are not supported, since the height of the subtree is not
+// known to the validator: if one is set with WithLayerHasher a *ValidationError wrapping ErrUnsupportedLayerHasher is
+// returned.
+func VerifySubtreeRoot(root, subtreeRoot []byte, index uint64, path [][]byte, opts ...ValidatorOpt) (bool, error) {
+	validatorOpts := parseValidatorOpts(opts)
+	if validatorOpts.layerHasher != nil {
+		return false, validationError(ErrUnsupportedLayerHasher)
+	}
+
+	// the identity leaf hasher is set explicitly, a nil leaf hasher would fall back to the leaf hasher of the hasher
+	size := validatorOpts.Hasher().Size()
+	opts = append(slices.Clone(opts), WithLeafHasher(ValueLeafs(size)))
+	return ValidateProof(root, map[uint64][]byte{index: subtreeRoot}, path, opts...)
+}
@@ -0,0 +1,527 @@
+package merkle_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"slices"
+	"sync"
+	"testing"
+
+	"github.com/fasmat/merkle"
+)
+
+func TestCombine(t *testing.T) {
+	t.Parallel()
+
+	builder := merkle.TreeBuilder().
+		WithLeafToProve(3).
+		WithLeafToProve(300).
+		WithLeafToProve(1000).
+		WithRetainProvenLeaves()
+
+	// build four subtrees with 256 leaves each concurrently
+	subtrees := make([]*merkle.Subtree, 4)
+	var wg sync.WaitGroup
+	for k := range subtrees {
+		subtrees[k] = builder.BuildSubtree(uint64(k) * 256)
+		wg.Go(func() {
+			buf := make([]byte, subtrees[k].NodeSize())
+			for i := range 256 {
+				binary.LittleEndian.PutUint64(buf, uint64(k*256+i))
+				subtrees[k].Add(buf)
+			}
+		})
+	}
+	wg.Wait()
+
+	for k, s := range subtrees {
+		start, end := s.Range()
+		if start != uint64(k)*256 || end != uint64(k+1)*256 {
+			t.Errorf("Expected subtree %d to cover [%d, %d), got [%d, %d)", k, k*256, (k+1)*256, start, end)
+		}
+	}
+
+	tree, err := merkle.Combine(subtrees)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := merkle.TreeBuilder().
+		WithLeafToProve(3).
+		WithLeafToProve(300).
+		WithLeafToProve(1000).
+		Build()
+	buf := make([]byte, expected.NodeSize())
+	for i := range 1024 {
+		binary.LittleEndian.PutUint64(buf, uint64(i))
+		expected.Add(buf)
+	}
+
+	root, proof := tree.RootAndProof()
+	expectedRoot, expectedProof := expected.RootAndProof()
+	if !bytes.Equal(root, expectedRoot) {
+		t.Errorf("Expected root to be %x, got %x", expectedRoot, root)
+	}
+	if len(proof) != len(expectedProof) {
+		t.Fatalf("Expected proof to be of length %d, got %d", len(expectedProof), len(proof))
+	}
+	for i, p := range proof {
+		if !bytes.Equal(p, expectedProof[i]) {
+			t.Errorf("Expected proof[%d] to be %x, got %x", i, expectedProof[i], p)
+		}
+	}
+
+	valid, err := tree.Verify()
+	if err != nil {
+		t.Error(err)
+	}
+	if !valid {
+		t.Error("proof is not valid")
+	}
+}
+
+func TestCombineInvalid(t *testing.T) {
+	t.Parallel()
+
+	buildWith := func(builder *merkle.Builder, start uint64, numLeaves int) *merkle.Subtree {
+		s := builder.BuildSubtree(start)
+		buf := make([]byte, s.NodeSize())
+		for i := range numLeaves {
+			binary.LittleEndian.PutUint64(buf, start+uint64(i))
+			s.Add(buf)
+		}
+		return s
+	}
+	build := func(start uint64, numLeaves int) *merkle.Subtree {
+		return buildWith(merkle.TreeBuilder(), start, numLeaves)
+	}
+
+	tt := []struct {
+		name     string
+		subtrees []*merkle.Subtree
+		err      error
+	}{
+		{
+			name: "no subtrees",
+			err:  merkle.ErrNoSubtrees,
+		},
+		{
+			name:     "unbalanced",
+			subtrees: []*merkle.Subtree{build(0, 3), build(3, 3)},
+			err:      merkle.ErrInvalidSubtree,
+		},
+		{
+			name:     "unaligned",
+			subtrees: []*merkle.Subtree{build(2, 4), build(6, 4)},
+			err:      merkle.ErrInvalidSubtree,
+		},
+		{
+			name:     "different size",
+			subtrees: []*merkle.Subtree{build(0, 4), build(4, 2)},
+			err:      merkle.ErrInvalidSubtree,
+		},
+		{
+			name:     "not contiguous",
+			subtrees: []*merkle.Subtree{build(0, 4), build(8, 4)},
+			err:      merkle.ErrInvalidSubtree,
+		},
+		{
+			name: "indexed leaf hasher",
+			subtrees: func() []*merkle.Subtree {
+				builder := merkle.TreeBuilder().WithLeafHasher(merkle.CounterLeafHasher(merkle.Sha256()))
+				subtrees := []*merkle.Subtree{builder.BuildSubtree(0), builder.BuildSubtree(1)}
+				subtrees[0].Add(leaf(0))
+				subtrees[1].Add(leaf(1))
+				return subtrees
+			}(),
+			err: merkle.ErrInvalidSubtree,
+		},
+		{
+			name: "different hasher",
+			subtrees: []*merkle.Subtree{
+				build(0, 4),
+				buildWith(merkle.TreeBuilder().WithHasher(merkle.Keccak256()), 4, 4),
+			},
+			err: merkle.ErrInvalidSubtree,
+		},
+		{
+			name: "different leaf hasher",
+			subtrees: []*merkle.Subtree{
+				build(0, 4),
+				buildWith(merkle.TreeBuilder().WithLeafContentHashing(), 4, 4),
+			},
+			err: merkle.ErrInvalidSubtree,
+		},
+		{
+			name: "different padding",
+			subtrees: func() []*merkle.Subtree {
+				// every subtree reads its own padding from the source
+				padding := make([]byte, 64)
+				for i := range padding {
+					padding[i] = byte(i)
+				}
+				builder := merkle.TreeBuilder().WithRandomPadding(bytes.NewReader(padding))
+				return []*merkle.Subtree{buildWith(builder, 0, 4), buildWith(builder, 4, 4)}
+			}(),
+			err: merkle.ErrInvalidSubtree,
+		},
+		{
+			name: "different min height",
+			subtrees: []*merkle.Subtree{
+				build(0, 4),
+				buildWith(merkle.TreeBuilder().WithMinHeight(5), 4, 4),
+			},
+			err: merkle.ErrInvalidSubtree,
+		},
+		{
+			name: "different options",
+			subtrees: []*merkle.Subtree{
+				build(0, 4),
+				buildWith(merkle.TreeBuilder().WithRecordedCheckpoints(), 4, 4),
+			},
+			err: merkle.ErrInvalidSubtree,
+		},
+		{
+			name: "leaf hash found twice",
+			subtrees: func() []*merkle.Subtree {
+				builder := merkle.TreeBuilder().WithLeafHashToProve(leaf(0))
+				subtrees := []*merkle.Subtree{builder.BuildSubtree(0), builder.BuildSubtree(1)}
+				subtrees[0].Add(leaf(0))
+				subtrees[1].Add(leaf(0))
+				return subtrees
+			}(),
+			err: merkle.ErrInvalidSubtree,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			tree, err := merkle.Combine(tc.subtrees)
+			if !errors.Is(err, tc.err) {
+				t.Errorf("expected error: %v, got: %v", tc.err, err)
+			}
+			if tree != nil {
+				t.Error("expected no tree")
+			}
+		})
+	}
+}
+
+// combineSubtrees builds numSubtrees subtrees of 4 leaves with the given builder and combines them.
+func combineSubtrees(t *testing.T, builder *merkle.Builder, numSubtrees int) *merkle.Tree {
+	t.Helper()
+
+	subtrees := make([]*merkle.Subtree, numSubtrees)
+	for k := range subtrees {
+		subtrees[k] = builder.BuildSubtree(uint64(k) * 4)
+		for i := range 4 {
+			subtrees[k].Add(leaf(uint64(k*4 + i)))
+		}
+	}
+	tree, err := merkle.Combine(subtrees)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tree
+}
+
+// sequentialTree builds a tree with the given number of leaves with the given builder by adding them sequentially.
+func sequentialTree(builder *merkle.Builder, numLeaves int) *merkle.Tree {
+	tree := builder.Build()
+	for i := range numLeaves {
+		tree.Add(leaf(uint64(i)))
+	}
+	return tree
+}
+
+func TestCombineCheckpoints(t *testing.T) {
+	t.Parallel()
+
+	builder := merkle.TreeBuilder().WithRecordedCheckpoints()
+	tree, expected := combineSubtrees(t, builder, 4), sequentialTree(builder, 16)
+	checkpoints, expectedCheckpoints := tree.Checkpoints(), expected.Checkpoints()
+	if len(checkpoints) != len(expectedCheckpoints) {
+		t.Fatalf("Expected %d checkpoints, got %d", len(expectedCheckpoints), len(checkpoints))
+	}
+	for count, root := range expectedCheckpoints {
+		if !bytes.Equal(checkpoints[count], root) {
+			t.Errorf("Expected checkpoint at %d leaves to be %x, got %x", count, root, checkpoints[count])
+		}
+	}
+
+	// checkpoints are recorded when adding to the combined tree
+	for i := range 16 {
+		tree.Add(leaf(uint64(16 + i)))
+		expected.Add(leaf(uint64(16 + i)))
+	}
+	if !bytes.Equal(tree.Checkpoints()[32], expected.Checkpoints()[32]) {
+		t.Errorf("Expected checkpoint at 32 leaves to be %x, got %x", expected.Checkpoints()[32],
+			tree.Checkpoints()[32])
+	}
+}
+
+func TestCombineLeafHashToProve(t *testing.T) {
+	t.Parallel()
+
+	t.Run("found in subtree", func(t *testing.T) {
+		t.Parallel()
+
+		builder := merkle.TreeBuilder().WithLeafHashToProve(leaf(9))
+		tree, expected := combineSubtrees(t, builder, 4), sequentialTree(builder, 16)
+		root, proof, index, ok := tree.RootAndProofByLeafHash()
+		if !ok {
+			t.Fatal("Expected leaf to be found")
+		}
+		if index != 9 {
+			t.Errorf("Expected index 9, got %d", index)
+		}
+		expectedRoot, expectedProof, _, _ := expected.RootAndProofByLeafHash()
+		if !bytes.Equal(root, expectedRoot) {
+			t.Errorf("Expected root to be %x, got %x", expectedRoot, root)
+		}
+		if len(proof) != len(expectedProof) {
+			t.Fatalf("Expected proof of length %d, got %d", len(expectedProof), len(proof))
+		}
+		for i := range proof {
+			if !bytes.Equal(proof[i], expectedProof[i]) {
+				t.Errorf("Expected proof[%d] to be %x, got %x", i, expectedProof[i], proof[i])
+			}
+		}
+	})
+
+	t.Run("found after combining", func(t *testing.T) {
+		t.Parallel()
+
+		tree := combineSubtrees(t, merkle.TreeBuilder().WithLeafHashToProve(leaf(18)), 4)
+		tree.Add(leaf(16))
+		tree.Add(leaf(17))
+		tree.Add(leaf(18))
+		_, _, index, ok := tree.RootAndProofByLeafHash()
+		if !ok || index != 18 {
+			t.Errorf("Expected leaf to be found at index 18, got %d (found: %t)", index, ok)
+		}
+	})
+}
+
+func TestCombineRequireProofTargets(t *testing.T) {
+	t.Parallel()
+
+	tree := combineSubtrees(t, merkle.TreeBuilder().WithRequireProofTargets(), 2)
+	_, _, err := tree.RootAndProofErr()
+	if !errors.Is(err, merkle.ErrNoProofTargets) {
+		t.Errorf("Expected error %v, got %v", merkle.ErrNoProofTargets, err)
+	}
+}
+
+func TestCombineExpectedLeafCount(t *testing.T) {
+	t.Parallel()
+
+	tree := combineSubtrees(t, merkle.TreeBuilder().WithExpectedLeafCount(12), 2)
+	_, _, err := tree.RootAndProofErr()
+	if !errors.Is(err, merkle.ErrIncompleteTree) {
+		t.Errorf("Expected error %v, got %v", merkle.ErrIncompleteTree, err)
+	}
+
+	for i := range 4 {
+		tree.Add(leaf(uint64(8 + i)))
+	}
+	if _, _, err := tree.RootAndProofErr(); err != nil {
+		t.Errorf("Expected no error for the expected leaf count, got %v", err)
+	}
+}
+
+func TestCombineNodeSink(t *testing.T) {
+	t.Parallel()
+
+	type node struct {
+		height int
+		index  uint64
+	}
+	sink := func(nodes map[node][]byte) func(height int, index uint64, hash []byte) {
+		return func(height int, index uint64, hash []byte) {
+			if _, ok := nodes[node{height, index}]; ok {
+				t.Errorf("Node at height %d and index %d was reported twice", height, index)
+			}
+			nodes[node{height, index}] = slices.Clone(hash)
+		}
+	}
+
+	// the subtrees are built sequentially, so the sink is not called concurrently
+	nodes := make(map[node][]byte)
+	tree := combineSubtrees(t, merkle.TreeBuilder().WithFullNodeSink(sink(nodes)), 4)
+	tree.Root()
+
+	expectedNodes := make(map[node][]byte)
+	sequentialTree(merkle.TreeBuilder().WithFullNodeSink(sink(expectedNodes)), 16).Root()
+
+	if len(nodes) != len(expectedNodes) {
+		t.Fatalf("Expected %d nodes, got %d", len(expectedNodes), len(nodes))
+	}
+	for n, hash := range expectedNodes {
+		if !bytes.Equal(nodes[n], hash) {
+			t.Errorf("Expected node at height %d and index %d to be %x, got %x", n.height, n.index, hash, nodes[n])
+		}
+	}
+}
+
+func TestSubtreeRootFromLeaves(t *testing.T) {
+	t.Parallel()
+
+	tree := merkle.TreeBuilder().
+		WithLeafToProve(4).
+		Build()
+	for i := range uint64(8) {
+		tree.Add(leaf(i))
+	}
+	root, proof := tree.RootAndProof()
+
+	leaves := [][]byte{leaf(4), leaf(5), leaf(6), leaf(7)}
+	subtreeRoot, err := merkle.SubtreeRootFromLeaves(leaves, merkle.Sha256())
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := "633b26ee8a5d96d49a4861e9a5720492f0db5b6af305c0b5cfcc6a7ec9b676d4"
+	if hex.EncodeToString(subtreeRoot) != expected {
+		t.Fatalf("Expected subtree root to be %s, got %x", expected, subtreeRoot)
+	}
+
+	// the first two nodes of the proof of leaf 4 are inside the subtree
+	path := proof[2:]
+	valid, err := merkle.VerifySubtreeRoot(root, subtreeRoot, 1, path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !valid {
+		t.Error("Expected subtree root to be valid")
+	}
+
+	valid, err = merkle.VerifySubtreeRoot(root, subtreeRoot, 0, path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if valid {
+		t.Error("Expected subtree root at wrong index to be invalid")
+	}
+
+	other, err := merkle.SubtreeRootFromLeaves([][]byte{leaf(4), leaf(5), leaf(7), leaf(6)}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	valid, err = merkle.VerifySubtreeRoot(root, other, 1, path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if valid {
+		t.Error("Expected root of reordered leaves to be invalid")
+	}
+}
+
+func TestVerifySubtreeRootRFC6962(t *testing.T) {
+	t.Parallel()
+
+	hasher := merkle.RFC6962Hasher()
+	tree := merkle.TreeBuilder().WithHasher(hasher).Build()
+	leafHashes := make([][]byte, 4)
+	for i := range uint64(4) {
+		tree.Add(leaf(i))
+		leafHashes[i] = merkle.RFC6962LeafHasher().Hash(nil, leaf(i), nil)
+	}
+	root := tree.Root()
+
+	// the subtree roots are computed from the leaf hashes, which must not be hashed again
+	s0, err := merkle.SubtreeRootFromLeaves(leafHashes[:2], hasher)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s1, err := merkle.SubtreeRootFromLeaves(leafHashes[2:], hasher)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := hasher.Hash(nil, s0, s1); !bytes.Equal(root, expected) {
+		t.Fatalf("Expected root to be %x, got %x", expected, root)
+	}
+
+	valid, err := merkle.VerifySubtreeRoot(root, s0, 0, [][]byte{s1}, merkle.WithHasher(hasher))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !valid {
+		t.Error("Expected subtree root to be valid")
+	}
+}
+
+func TestVerifySubtreeRootLeafNonces(t *testing.T) {
+	t.Parallel()
+
+	nonces := make(map[uint64][]byte)
+	for i := range uint64(4) {
+		nonces[i] = []byte{byte(i), 0xff}
+	}
+	leafHasher := merkle.NoncedLeafHasher(merkle.Sha256(), nonces)
+	tree := merkle.TreeBuilder().WithLeafHasher(leafHasher).Build()
+	leafHashes := make([][]byte, 4)
+	for i := range uint64(4) {
+		tree.Add(leaf(i))
+		leafHashes[i] = leafHasher.(merkle.IndexedLeafHasher).HashIndexed(nil, i, leaf(i), nil)
+	}
+
+	s0, err := merkle.SubtreeRootFromLeaves(leafHashes[:2], nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s1, err := merkle.SubtreeRootFromLeaves(leafHashes[2:], nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the nonces only apply to the leaves, the subtree root is used as is
+	for _, tc := range []struct {
+		index uint64
+		root  []byte
+		path  []byte
+	}{
+		{0, s0, s1},
+		{1, s1, s0},
+	} {
+		valid, err := merkle.VerifySubtreeRoot(tree.Root(), tc.root, tc.index, [][]byte{tc.path},
+			merkle.WithLeafNonces(nonces))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !valid {
+			t.Errorf("Expected subtree root %d to be valid", tc.index)
+		}
+	}
+}
+
+func TestSubtreeRootFromLeavesIncomplete(t *testing.T) {
+	t.Parallel()
+
+	_, err := merkle.SubtreeRootFromLeaves([][]byte{leaf(4), leaf(5), leaf(6)}, nil)
+	if !errors.Is(err, merkle.ErrIncompleteSubtree) {
+		t.Errorf("Expected error %v, got %v", merkle.ErrIncompleteSubtree, err)
+	}
+
+	_, err = merkle.SubtreeRootFromLeaves(nil, nil)
+	if !errors.Is(err, merkle.ErrNoLeaves) {
+		t.Errorf("Expected error %v, got %v", merkle.ErrNoLeaves, err)
+	}
+}
+
+func TestVerifySubtreeRootLayerHasher(t *testing.T) {
+	t.Parallel()
+
+	layerHasher := func(uint64) merkle.Hasher { return merkle.Sha256() }
+	valid, err := merkle.VerifySubtreeRoot(make([]byte, 32), leaf(0), 0, [][]byte{leaf(1)},
+		merkle.WithLayerHasher(layerHasher))
+	if !errors.Is(err, merkle.ErrUnsupportedLayerHasher) {
+		t.Errorf("Expected error %v, got %v", merkle.ErrUnsupportedLayerHasher, err)
+	}
+	if valid {
+		t.Error("Expected subtree root to be invalid")
+	}
+}
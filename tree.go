@@ -1,7 +1,34 @@
 package merkle
 
 import (
+	"errors"
+	"fmt"
+	"io"
+	"math"
 	"math/bits"
+	"slices"
+	"sync/atomic"
+)
+
+var (
+	// ErrProvenLeavesNotRetained is returned when an operation requires the values of the proven leaves, but the tree
+	// was not built with Builder.WithRetainProvenLeaves.
+	ErrProvenLeavesNotRetained = errors.New("proven leaves are not retained")
+
+	// ErrPrecomputedProvenLeaf is returned by Tree.AddLeafHash when a precomputed leaf of a tree with a sequential
+	// leaf hasher is a leaf to prove.
+	ErrPrecomputedProvenLeaf = errors.New("precomputed leaf cannot be proven with a sequential leaf hasher")
+
+	// ErrNoProofTargets is returned by Tree.RootAndProofErr if the tree was built with Builder.WithRequireProofTargets
+	// but without any leaves to prove.
+	ErrNoProofTargets = errors.New("no leaves to prove")
+
+	// ErrTooManyLeaves is returned when a leaf is added to a tree that already holds the maximum number of leaves.
+	ErrTooManyLeaves = errors.New("tree cannot hold more leaves")
+
+	// ErrIncompleteTree is returned by Tree.RootAndProofErr if the tree was built with Builder.WithExpectedLeafCount
+	// and does not hold the expected number of leaves.
+	ErrIncompleteTree = errors.New("tree does not hold the expected number of leaves")
 )
 
 // Tree represents a Merkle tree.
@@ -13,13 +40,45 @@ type Tree struct {
 	leafBuf []byte // Buffer for temporary storage of leaf hashes
 	padding []byte // Padding for the tree
 
+	emptyLeaf []byte // Value of absent leaves, the padding is used if nil
+
 	minHeight     uint64   // Minimum height of the tree
-	leavesToProve []uint64 // leavesToProve is sorted set of indices of leaves to prove
+	indexOffset   uint64   // Global index of the first leaf of the tree
+	provenIndices []uint64 // provenIndices is the sorted set of indices of all leaves to prove
+	leavesToProve []uint64 // leavesToProve is sorted set of indices of leaves to prove not yet added
+
+	provenLeaves map[uint64][]byte // The values of the proven leaves, only set if they are retained
+
+	targetLeafHash []byte // The hash of a leaf to prove that is looked up while adding leaves, nil if not set
+	targetIndex    uint64 // The global index of the leaf with the target hash
+	targetFound    bool   // Indicates if a leaf with the target hash was added
+	requireTargets bool   // Indicates if RootAndProofErr fails without leaves to prove
+
+	checkLeafCount    bool   // Indicates if RootAndProofErr fails if the tree does not hold expectedLeafCount leaves
+	expectedLeafCount uint64 // The number of leaves the tree is expected to hold, only used with checkLeafCount
+
+	layerHasher func(height uint64) Hasher                  // Returns the hasher for each layer if set
+	nodeSink    func(height int, index uint64, hash []byte) // Called for every interior node if set
+
+	paddedEmitted   bool   // Indicates if the nodes that depend on padding were passed to the node sink
+	paddedLeafCount uint64 // The number of leaves when the padded nodes were passed, only used with paddedEmitted
+
+	liveRoot    bool   // Indicates if the root is kept up to date on every Add
+	sizeBinding bool   // Indicates if the root is bound to the number of leaves
+	root        []byte // The current root of the tree, only used with a live root
+	rootValid   bool   // Indicates if root is up to date with the added leaves
+
+	checkpoints map[uint64][]byte // The roots at every power of two number of leaves, only set if they are recorded
 
 	parkedNodes   [][]byte // The parked nodes of the tree
 	onProvingPath []bool   // Indicates if the parked nodes are on the proving path
 	currentLeaf   uint64   // The current leaf index
 	proof         [][]byte // The proof of the leaves to prove
+	proofNodes    []byte   // Preallocated memory for the nodes added to the proof
+
+	reuseRoot []byte // Buffer for the root returned by RootAndProofReuse
+
+	adding atomic.Bool // Set while a leaf is added, used to detect concurrent calls to Add
 }
 
 // NodeSize returns the length of the hash used for the nodes in the tree.
@@ -27,23 +86,352 @@ func (t *Tree) NodeSize() int {
 	return t.hasher.Size()
 }
 
+// TreeStats is a snapshot of the shape of a tree, see Tree.Stats.
+type TreeStats struct {
+	LeafCount     uint64 // The number of leaves added to the tree
+	Capacity      uint64 // The number of leaves of the balanced tree that contains all added leaves
+	PaddingLeaves uint64 // The number of leaves that are filled with padding, i.e. Capacity - LeafCount
+	Height        uint64 // The number of layers below the root, including layers added for the minimum height
+	IsBalanced    bool   // Whether the tree is balanced, i.e. no padding is needed to calculate the root
+}
+
+// Stats returns statistics about the current shape of the tree.
+func (t *Tree) Stats() TreeStats {
+	height := uint64(0)
+	if layers := t.layers(); layers > 0 {
+		height = layers - 1
+	}
+	capacity := uint64(1) << height
+
+	return TreeStats{
+		LeafCount:     t.currentLeaf,
+		Capacity:      capacity,
+		PaddingLeaves: capacity - t.currentLeaf,
+		Height:        height,
+		IsBalanced:    t.currentLeaf == capacity,
+	}
+}
+
+// Height returns the number of populated layers of the tree, including the layer of the leaves and the layer of the
+// root: an empty tree has height 0, a tree with a single leaf height 1 and a tree with 8 leaves height 4. Once a leaf
+// was added the height is at least the minimum height of the tree (see Builder.WithMinHeight), which counts the
+// layers the same way. The layers for the minimum height are added on top of the highest parked node, so an
+// unbalanced tree can have one layer more than the minimum height, e.g. a tree with 5 leaves and minimum height 4
+// has height 5.
+func (t *Tree) Height() uint64 {
+	return t.layers()
+}
+
+// layers returns the number of layers of the tree including the leaves and the root, the same way RootAndProof builds
+// them: the root of an unbalanced tree is one layer above the highest parked node and padToMinHeight adds layers until
+// the parked nodes and the added layers reach the minimum height.
+func (t *Tree) layers() uint64 {
+	if t.currentLeaf == 0 {
+		return 0
+	}
+	parked := uint64(len(t.parkedNodes))
+	layers := parked
+	if t.currentLeaf&(t.currentLeaf-1) != 0 {
+		layers++
+	}
+	return layers + t.minHeight - min(t.minHeight, parked)
+}
+
+// Padding returns the padding node of the tree that is used as sibling for nodes in unbalanced layers.
+func (t *Tree) Padding() []byte {
+	return slices.Clone(t.padding)
+}
+
+// Clone returns a deep copy of the tree. The clone can be extended independently of the original tree, e.g. to
+// explore different continuations of the same prefix of leaves. The hashers are shared between the original and the
+// clone, since they are safe for concurrent use.
+func (t *Tree) Clone() *Tree {
+	clone := &Tree{
+		hasher:     t.hasher,
+		leafHasher: t.leafHasher,
+
+		buf:     slices.Clone(t.buf),
+		leafBuf: slices.Clone(t.leafBuf),
+		padding: slices.Clone(t.padding),
+
+		emptyLeaf: t.emptyLeaf,
+
+		minHeight:     t.minHeight,
+		indexOffset:   t.indexOffset,
+		provenIndices: slices.Clone(t.provenIndices),
+		leavesToProve: slices.Clone(t.leavesToProve),
+
+		targetLeafHash: t.targetLeafHash,
+		targetIndex:    t.targetIndex,
+		targetFound:    t.targetFound,
+		requireTargets: t.requireTargets,
+
+		checkLeafCount:    t.checkLeafCount,
+		expectedLeafCount: t.expectedLeafCount,
+
+		layerHasher: t.layerHasher,
+		nodeSink:    t.nodeSink,
+
+		paddedEmitted:   t.paddedEmitted,
+		paddedLeafCount: t.paddedLeafCount,
+
+		liveRoot:    t.liveRoot,
+		sizeBinding: t.sizeBinding,
+		root:        slices.Clone(t.root),
+		rootValid:   t.rootValid,
+
+		onProvingPath: slices.Clone(t.onProvingPath),
+		currentLeaf:   t.currentLeaf,
+	}
+	if t.provenLeaves != nil {
+		clone.provenLeaves = make(map[uint64][]byte, len(t.provenLeaves))
+		for idx, leaf := range t.provenLeaves {
+			clone.provenLeaves[idx] = slices.Clone(leaf)
+		}
+	}
+	if t.checkpoints != nil {
+		clone.checkpoints = make(map[uint64][]byte, len(t.checkpoints))
+		for count, root := range t.checkpoints {
+			clone.checkpoints[count] = slices.Clone(root)
+		}
+	}
+	clone.parkedNodes = make([][]byte, len(t.parkedNodes))
+	for i, node := range t.parkedNodes {
+		clone.parkedNodes[i] = slices.Clone(node)
+	}
+	clone.proof = make([][]byte, len(t.proof))
+	for i, node := range t.proof {
+		clone.proof[i] = slices.Clone(node)
+	}
+	return clone
+}
+
 // Add adds a new value (leaf) to the tree.
 //
 // Call this method for each leaf you want to add to the tree before retrieving the root hash with Root() or
 // RootAndProof().
+//
+// A tree supports only a single writer: Add must not be called concurrently. Concurrent calls are detected and cause
+// a panic instead of silently corrupting the tree.
+//
+// A tree can hold up to 2^64-1 leaves. Adding more panics with an error wrapping ErrTooManyLeaves, use AddChecked to
+// get the error returned instead.
 func (t *Tree) Add(value []byte) {
-	curNode := t.leafHasher.Hash(t.leafBuf, value, t.parkedNodes)
+	t.startWrite("Tree.Add")
+	defer t.adding.Store(false)
+
+	if err := t.checkCapacity(); err != nil {
+		panic(err)
+	}
+
+	t.addLeaf(value, hashLeaf(t.leafHasher, t.leafBuf, t.currentLeaf, value, t.parkedNodes))
+}
+
+// AddBatch adds the given values (leaves) to the tree in order, the result is the same as calling Add for every value.
+// The values are not retained by the tree and can be reused by the caller after the call returns.
+//
+// Like Add it panics if it is called concurrently or if the tree cannot hold all values, in the latter case no value
+// is added.
+func (t *Tree) AddBatch(values [][]byte) {
+	t.startWrite("Tree.AddBatch")
+	defer t.adding.Store(false)
+
+	if uint64(len(values)) > math.MaxUint64-t.currentLeaf {
+		panic(fmt.Errorf("%w: tree has %d leaves, cannot add %d more", ErrTooManyLeaves, t.currentLeaf, len(values)))
+	}
+
+	for _, value := range values {
+		t.addLeaf(value, hashLeaf(t.leafHasher, t.leafBuf, t.currentLeaf, value, t.parkedNodes))
+	}
+}
 
+// AddAndPath adds a new value (leaf) to the tree like Add and returns the left siblings on the path of the leaf to the
+// root, ordered from the leaf to the root. These are the nodes parked in the tree when the leaf is added and they are
+// part of the eventual proof of the leaf: the sibling at height h is present if bit h of the index of the leaf is set.
+//
+// The right siblings are not known until later leaves are added, so they are not part of the returned path. The
+// returned nodes are copies and can be retained by the caller.
+func (t *Tree) AddAndPath(value []byte) [][]byte {
+	t.startWrite("Tree.AddAndPath")
+	defer t.adding.Store(false)
+
+	if err := t.checkCapacity(); err != nil {
+		panic(err)
+	}
+
+	index := t.currentLeaf
+	path := make([][]byte, 0, bits.OnesCount64(index))
+	for height, node := range t.parkedNodes {
+		if index>>height&1 == 1 {
+			path = append(path, slices.Clone(node))
+		}
+	}
+	t.addLeaf(value, hashLeaf(t.leafHasher, t.leafBuf, t.currentLeaf, value, t.parkedNodes))
+	return path
+}
+
+// AddLeafHash adds a leaf whose hash was already calculated to the tree, i.e. the leaf hasher of the tree is not
+// applied to the given hash. This allows mixing leaves that are hashed by the tree with leaves that were hashed
+// elsewhere, e.g. in parallel.
+//
+// For trees with a sequential leaf hasher (see SequentialWorkHasher) the precomputed hash has to embed the parked
+// nodes of the tree at the time the leaf is added, exactly like the leaf hasher does. Otherwise the root will differ
+// and proofs of later leaves will not validate. Since the validator hashes the proven leaves with the leaf hasher, a
+// precomputed leaf of such a tree cannot be proven: ErrPrecomputedProvenLeaf is returned without adding the leaf if
+// it is a leaf to prove. For other trees the proven value of a precomputed leaf is its hash.
+func (t *Tree) AddLeafHash(hash []byte) error {
+	t.startWrite("Tree.AddLeafHash")
+	defer t.adding.Store(false)
+
+	if err := t.checkCapacity(); err != nil {
+		return err
+	}
+	if t.leafHasher.Sequential() && len(t.leavesToProve) > 0 && t.leavesToProve[0] == t.currentLeaf {
+		return fmt.Errorf("%w: index %d", ErrPrecomputedProvenLeaf, t.indexOffset+t.currentLeaf)
+	}
+	t.addLeaf(hash, append(t.leafBuf[:0], hash...))
+	return nil
+}
+
+// checkCapacity returns an error wrapping ErrTooManyLeaves if no more leaves can be added to the tree.
+func (t *Tree) checkCapacity() error {
+	if t.currentLeaf == math.MaxUint64 {
+		return fmt.Errorf("%w: tree has %d leaves", ErrTooManyLeaves, t.currentLeaf)
+	}
+	return nil
+}
+
+// startWrite marks the tree as being written to by the given method. It panics if another goroutine is already adding
+// a leaf.
+func (t *Tree) startWrite(method string) {
+	if !t.adding.CompareAndSwap(false, true) {
+		panic("merkle: concurrent call to " + method + ", a tree must only be written by a single goroutine")
+	}
+}
+
+// addLeaf adds the leaf with the given value and hash to the tree. The buffer of the hash is used by the tree.
+func (t *Tree) addLeaf(value, curNode []byte) {
 	// If needed, check if the current leaf is on the proving path
 	curOnProvingPath := false
 	if len(t.leavesToProve) > 0 && t.currentLeaf == t.leavesToProve[0] {
 		curOnProvingPath = true
 		t.leavesToProve = t.leavesToProve[1:]
+		if t.provenLeaves != nil {
+			t.provenLeaves[t.currentLeaf] = append([]byte(nil), value...)
+		}
+	}
+	if t.matchLeafHash(value, curNode, curOnProvingPath) {
+		curOnProvingPath = true
 	}
 	t.currentLeaf++
+	t.addNode(0, curNode, curOnProvingPath)
+	if t.liveRoot {
+		t.updateRoot()
+	}
+	if t.checkpoints != nil {
+		t.recordCheckpoint()
+	}
+}
+
+// AddChecked adds a new value (leaf) to the tree like Add. If the leaf hasher of the tree implements LeafChecker
+// the value is checked first and if it is rejected the error is returned without adding the value to the tree. If the
+// tree already holds the maximum number of leaves an error wrapping ErrTooManyLeaves is returned.
+func (t *Tree) AddChecked(value []byte) error {
+	if err := t.checkCapacity(); err != nil {
+		return err
+	}
+	if checker, ok := t.leafHasher.(LeafChecker); ok {
+		if err := checker.Check(value); err != nil {
+			return err
+		}
+	}
+	t.Add(value)
+	return nil
+}
+
+// AddFrom reads blocks of chunk bytes from r until it is exhausted and adds each block as a leaf to the tree, like
+// AddChecked. It returns the number of leaves added. Reaching the end of r is not an error. If the data of r is not a
+// multiple of chunk bytes the final short block is added as is, it is not padded. A block is not retained by the tree,
+// so the data of r is never held in memory as a whole.
+//
+// If reading from r or adding a block fails the error is returned together with the number of leaves added before,
+// a block that was only read partially is not added in that case. AddFrom panics if chunk is not positive.
+func (t *Tree) AddFrom(r io.Reader, chunk int) (uint64, error) {
+	if chunk <= 0 {
+		panic(fmt.Sprintf("merkle: chunk size must be positive, got %d", chunk))
+	}
+
+	buf := make([]byte, chunk)
+	var added uint64
+	for {
+		n, err := io.ReadFull(r, buf)
+		switch {
+		case errors.Is(err, io.EOF):
+			return added, nil
+		case err != nil && !errors.Is(err, io.ErrUnexpectedEOF):
+			return added, err
+		}
+		if err := t.AddChecked(buf[:n]); err != nil {
+			return added, err
+		}
+		added++
+		if n < chunk {
+			return added, nil
+		}
+	}
+}
+
+// updateRoot updates the live root after a leaf was added. If the tree is balanced the root is the parked node of the
+// top layer and copied to the root buffer, otherwise the root is marked as outdated and recalculated when requested.
+func (t *Tree) updateRoot() {
+	t.rootValid = false
+	top := len(t.parkedNodes) - 1
+	if t.currentLeaf&(t.currentLeaf-1) != 0 || uint64(top) < t.minHeight {
+		return
+	}
+	t.root = t.bindSize(append(t.root[:0], t.parkedNodes[top]...))
+	t.rootValid = true
+}
+
+// recordCheckpoint records the root of the tree if the number of leaves is a power of two. The tree is balanced at
+// these points, so the root is the parked node of the top layer, padded like in padToMinHeight.
+func (t *Tree) recordCheckpoint() {
+	if t.currentLeaf&(t.currentLeaf-1) != 0 {
+		return
+	}
+	root := slices.Clone(t.parkedNodes[len(t.parkedNodes)-1])
+	for i := len(t.parkedNodes); uint64(i) < t.minHeight; i++ {
+		root = t.hasherAt(i+1).Hash(root, root, t.paddingAt(i))
+	}
+	t.checkpoints[t.currentLeaf] = t.bindSize(root)
+}
+
+// Checkpoints returns the roots the tree had when the number of added leaves was a power of two (1, 2, 4, 8, ...),
+// keyed by the number of leaves. The tree has to be built with Builder.WithRecordedCheckpoints, otherwise nil is
+// returned. The returned map is a copy and can be modified by the caller.
+func (t *Tree) Checkpoints() map[uint64][]byte {
+	if t.checkpoints == nil {
+		return nil
+	}
+	checkpoints := make(map[uint64][]byte, len(t.checkpoints))
+	for count, root := range t.checkpoints {
+		checkpoints[count] = slices.Clone(root)
+	}
+	return checkpoints
+}
+
+// addNode adds a node at the given height to the tree. If a node is already parked at that height the two nodes are
+// hashed together and the result is added one layer higher, until a layer without a parked node is reached.
+//
+// The buffer of curNode is used to store intermediate results and must not be used by the caller afterwards.
+func (t *Tree) addNode(height int, curNode []byte, curOnProvingPath bool) {
+	for len(t.parkedNodes) < height {
+		t.parkedNodes = append(t.parkedNodes, nil)
+		t.onProvingPath = append(t.onProvingPath, false)
+	}
 
 	// Loop through the layers (parked nodes) of the tree
-	for height := 0; ; height++ {
+	for ; ; height++ {
 		// If there is no layer at current height, add one
 		if height == len(t.parkedNodes) {
 			t.parkedNodes = append(t.parkedNodes, nil)
@@ -54,8 +442,9 @@ func (t *Tree) Add(value []byte) {
 
 		// If no node is parking, then the current node is a left sibling
 		// add it as the parking node and keep information on it being on the proving path or not
+		// A non-nil empty slice is used for empty nodes, since nil marks a layer without a parked node
 		if *parkingNode == nil {
-			*parkingNode = append((*parkingNode)[:0], curNode...)
+			*parkingNode = append([]byte{}, curNode...)
 			*parkingOnProvingPath = curOnProvingPath
 			break
 		}
@@ -64,14 +453,10 @@ func (t *Tree) Add(value []byte) {
 		switch {
 		case *parkingOnProvingPath && !curOnProvingPath:
 			// add the right child (current node) to the proof
-			proofNode := make([]byte, len(curNode))
-			copy(proofNode, curNode)
-			t.proof = append(t.proof, proofNode)
+			t.addProofNode(curNode)
 		case !*parkingOnProvingPath && curOnProvingPath:
 			// add the left child (parking node) to the proof
-			proofNode := make([]byte, len(*parkingNode))
-			copy(proofNode, *parkingNode)
-			t.proof = append(t.proof, proofNode)
+			t.addProofNode(*parkingNode)
 		default:
 			// either both or none are on the proving path
 			// do not add anything to the proof
@@ -79,44 +464,100 @@ func (t *Tree) Add(value []byte) {
 
 		// Hash the parking node (left child) and the current node (right child) together
 		// store the result in the current node and move to the next layer
-		root := t.hasher.Hash(t.buf, *parkingNode, curNode)
+		root := t.hasherAt(height+1).Hash(t.buf, *parkingNode, curNode)
 		curNode = append(curNode[:0], root...)
+		t.emitNode(height+1, curNode)
 		curOnProvingPath = *parkingOnProvingPath || curOnProvingPath
 		*parkingNode = nil
 		*parkingOnProvingPath = false
 	}
 }
 
-// Root returns the root hash of the tree.
+// Root returns the root hash of the tree. Unlike RootAndProof the proof is not assembled, so calculating the root of
+// a tree with leaves to prove is as cheap as for a tree without.
 func (t *Tree) Root() []byte {
-	root, _ := t.RootAndProof()
+	root, _ := t.rootAndProof([]byte{}, nil, nil)
 	return root
 }
 
+// CurrentRoot returns the root hash of the tree like Root, but for trees built with Builder.WithLiveRoot the returned
+// slice is an internal buffer of the tree that is only valid until the next call to Add and must not be modified.
+//
+// With a live root the root is updated on every Add that results in a balanced tree. For unbalanced trees the root is
+// calculated on the first call after an Add, subsequent calls return the same buffer without calculating or allocating.
+// Without a live root CurrentRoot is equivalent to Root.
+func (t *Tree) CurrentRoot() []byte {
+	if !t.liveRoot {
+		return t.Root()
+	}
+	if !t.rootValid {
+		t.root = append(t.root[:0], t.Root()...)
+		t.rootValid = true
+	}
+	return t.root
+}
+
 // RootAndProof returns the root hash and the proof for the leaves to prove.
 func (t *Tree) RootAndProof() ([]byte, [][]byte) {
-	proof := t.makeProof()
+	proof, nodes := t.makeProof()
+	return t.rootAndProof([]byte{}, proof, nodes)
+}
+
+// RootAndProofReuse returns the root hash and the proof for the leaves to prove like RootAndProof, but reuses the
+// given dst slice and the node buffers it contains (up to its capacity) for the returned proof. This avoids allocations
+// when a proof is requested repeatedly, e.g. while the tree grows: pass the proof returned by the previous call as dst.
+//
+// The returned proof aliases dst and its nodes, and the returned root aliases an internal buffer of the tree. Both are
+// only valid until the next call to RootAndProofReuse and must be copied if they are retained.
+func (t *Tree) RootAndProofReuse(dst [][]byte) ([]byte, [][]byte) {
+	var proof [][]byte
+	if t.provenIndices != nil {
+		proofLen := t.proofLen()
+		if cap(dst) < proofLen {
+			// keep the node buffers of dst when growing it
+			dst = append(dst[:cap(dst)], make([][]byte, proofLen-cap(dst))...)
+		}
+		proof = dst[:0]
+		if proof == nil {
+			// a nil proof only calculates the root, like RootAndProof return an empty proof instead
+			proof = [][]byte{}
+		}
+		for _, p := range t.proof {
+			proof = append(proof, t.proofNode(proof, nil, p))
+		}
+	}
+
+	if t.reuseRoot == nil {
+		t.reuseRoot = []byte{}
+	}
+	root, proof := t.rootAndProof(t.reuseRoot, proof, nil)
+	t.reuseRoot = root
+	return root, proof
+}
 
+// rootAndProof calculates the root of the tree and completes the given proof. Root is calculated in rootBuf, which has
+// to be non-nil, the nodes added to the proof are taken from the given nodes buffer if possible (see proofNode). If
+// proof is nil only the root is calculated.
+func (t *Tree) rootAndProof(rootBuf []byte, proof [][]byte, nodes []byte) ([]byte, [][]byte) {
 	var root []byte
 	onProvingPath := false
+	emit := t.emitPadded()
 	for height, parkedNode := range t.parkedNodes {
 		// If this is a balanced tree, the parking node is the root and the proof is complete
 		if parkedNode != nil && root == nil && height == len(t.parkedNodes)-1 {
-			root = append(root[:0], parkedNode...) // Copy the parking node to the root
+			root = append(rootBuf[:0], parkedNode...) // Copy the parking node to the root
 			break
 		}
 
 		// Otherwise check if we are on the proving path and need to add one of the nodes to the proof
 		switch {
+		case proof == nil:
+			// only the root is requested
 		case t.onProvingPath[height] && !onProvingPath:
-			proofNode := make([]byte, t.hasher.Size())
-			copy(proofNode, root)
-			proof = append(proof, proofNode)
+			proof = append(proof, t.proofNode(proof, &nodes, t.nodeOrPadding(root, height)))
 			onProvingPath = true
 		case onProvingPath && !t.onProvingPath[height]:
-			proofNode := make([]byte, t.hasher.Size())
-			copy(proofNode, parkedNode)
-			proof = append(proof, proofNode)
+			proof = append(proof, t.proofNode(proof, &nodes, t.nodeOrPadding(parkedNode, height)))
 		default:
 			// either both or none are on the proving path, do not add anything to the proof
 		}
@@ -126,32 +567,243 @@ func (t *Tree) RootAndProof() ([]byte, [][]byte) {
 		// If both are nil continue with next layer
 		switch {
 		case parkedNode != nil && root != nil:
-			root = t.hasher.Hash(root, parkedNode, root)
+			root = t.hasherAt(height+1).Hash(root, parkedNode, root)
 		case parkedNode != nil:
-			root = t.hasher.Hash(root, parkedNode, t.padding)
+			root = t.hasherAt(height+1).Hash(rootBuf, parkedNode, t.paddingAt(height))
 		case root != nil:
-			root = t.hasher.Hash(root, root, t.padding)
+			root = t.hasherAt(height+1).Hash(root, root, t.paddingAt(height))
+		default:
+			continue
 		}
+		if emit {
+			t.emitNode(height+1, root)
+		}
+	}
+	root, proof = t.padToMinHeight(root, rootBuf, proof, nodes, emit)
+	return t.bindSize(root), proof
+}
+
+// bindSize binds the given root to the number of leaves of the tree if the tree was built with
+// Builder.WithSizeBinding. The result is calculated in the buffer of the given root.
+func (t *Tree) bindSize(root []byte) []byte {
+	if !t.sizeBinding || root == nil {
+		return root
 	}
-	// If the height is less than the minimum height, add padding nodes
+	return bindSize(t.hasher, root, t.currentLeaf, root)
+}
+
+// padToMinHeight adds padding layers on top of the given root until the tree has its minimum height and adds the
+// padding nodes to the proof. If the root is nil (the tree is empty) rootBuf is used to calculate the new root. The
+// padded nodes are passed to the node sink if emit is true.
+func (t *Tree) padToMinHeight(root, rootBuf []byte, proof [][]byte, nodes []byte, emit bool) ([]byte, [][]byte) {
 	for i := uint64(len(t.parkedNodes)); i < t.minHeight; i++ {
-		root = t.hasher.Hash(root, root, t.padding)
-		proof = append(proof, t.padding)
+		buf := root
+		if buf == nil {
+			buf = rootBuf
+		}
+		root = t.hasherAt(int(i)+1).Hash(buf, root, t.paddingAt(int(i)))
+		if emit {
+			t.emitNode(int(i)+1, root)
+		}
+		if proof != nil {
+			proof = append(proof, t.proofNode(proof, &nodes, t.paddingAt(int(i))))
+		}
 	}
 	return root, proof
 }
 
+// RootAndProofErr returns the root hash and the proof for the leaves to prove like RootAndProof. If the tree was built
+// with Builder.WithRequireProofTargets and no leaves to prove were set, ErrNoProofTargets is returned instead. If the
+// tree was built with Builder.WithExpectedLeafCount and does not hold the expected number of leaves, an error wrapping
+// ErrIncompleteTree is returned.
+func (t *Tree) RootAndProofErr() ([]byte, [][]byte, error) {
+	if t.requireTargets && t.provenIndices == nil && t.targetLeafHash == nil {
+		return nil, nil, ErrNoProofTargets
+	}
+	if t.checkLeafCount && t.currentLeaf != t.expectedLeafCount {
+		return nil, nil, fmt.Errorf("%w: tree has %d leaves, expected %d",
+			ErrIncompleteTree, t.currentLeaf, t.expectedLeafCount)
+	}
+	root, proof := t.RootAndProof()
+	return root, proof, nil
+}
+
+// Verify validates the proof of the tree against its root and the retained values of the proven leaves using the same
+// hasher and leaf hasher the tree was built with. This is useful to self-test a tree before handing out its proof.
+//
+// The tree has to be built with Builder.WithRetainProvenLeaves, otherwise ErrProvenLeavesNotRetained is returned.
+func (t *Tree) Verify() (bool, error) {
+	if t.provenLeaves == nil {
+		return false, ErrProvenLeavesNotRetained
+	}
+
+	opts := []ValidatorOpt{WithHasher(t.hasher), WithLeafHasher(t.leafHasher), WithLayerHasher(t.layerHasher)}
+	if t.sizeBinding {
+		opts = append(opts, WithSizeBinding(t.currentLeaf))
+	}
+	root, proof := t.RootAndProof()
+	return ValidateProof(root, t.provenLeaves, proof, opts...)
+}
+
+// ProvenLeaf returns the value of the proven leaf with the given index. The tree has to be built with
+// Builder.WithRetainProvenLeaves, otherwise no leaf is found. If an index offset was set with WithLeafIndexOffset the
+// index is interpreted as global index.
+//
+// The second return value is false if the index is not one of the leaves to prove or the leaf was not added yet.
+func (t *Tree) ProvenLeaf(index uint64) ([]byte, bool) {
+	if index < t.indexOffset {
+		return nil, false
+	}
+	leaf, ok := t.provenLeaves[index-t.indexOffset]
+	return leaf, ok
+}
+
+// ProofStep is a single step of a directed proof. It contains the hash of the sibling node and whether the sibling is
+// the left child when hashing it together with the current node.
+type ProofStep struct {
+	Hash   []byte
+	IsLeft bool
+}
+
+// RootAndDirectedProof returns the root hash and the proof for the leaf with the given index as a list of directed
+// steps. Each step contains the sibling on the path from the leaf to the root and whether it has to be hashed as
+// left or right child. This format is useful for position-aware verifiers (e.g. on-chain) that do not want to derive
+// the directions from the index themselves.
+//
+// The tree has to be built with the given index as the only leaf to prove, otherwise the returned steps are nil.
+func (t *Tree) RootAndDirectedProof(index uint64) ([]byte, []ProofStep) {
+	root, proof := t.RootAndProof()
+	if index < t.indexOffset {
+		return root, nil
+	}
+	index -= t.indexOffset
+	if len(t.provenIndices) != 1 || t.provenIndices[0] != index {
+		return root, nil
+	}
+
+	steps := make([]ProofStep, len(proof))
+	for height, node := range proof {
+		steps[height] = ProofStep{
+			Hash:   node,
+			IsLeft: (index>>height)&1 == 1,
+		}
+	}
+	return root, steps
+}
+
+// hasherAt returns the hasher used to calculate the nodes at the given height.
+func (t *Tree) hasherAt(height int) Hasher {
+	return hasherAt(t.layerHasher, uint64(height), t.hasher)
+}
+
+// emitNode passes an interior node at the given height to the node sink of the tree if one is set. The node is always
+// the right-most node of its layer, i.e. the ancestor of the last added leaf.
+func (t *Tree) emitNode(height int, node []byte) {
+	if t.nodeSink == nil {
+		return
+	}
+	index := uint64(0)
+	if t.currentLeaf > 0 && height < 64 {
+		index = (t.currentLeaf - 1) >> height
+	}
+	t.nodeSink(height, index, node)
+}
+
+// emitPadded returns true if the nodes that depend on padding have to be passed to the node sink, i.e. a sink is set
+// and they were not passed for the current number of leaves yet. Calling it records that they are passed now.
+func (t *Tree) emitPadded() bool {
+	if t.nodeSink == nil || (t.paddedEmitted && t.paddedLeafCount == t.currentLeaf) {
+		return false
+	}
+	t.paddedEmitted = true
+	t.paddedLeafCount = t.currentLeaf
+	return true
+}
+
+// addProofNode adds a copy of the given node to the proof collected while adding leaves. The copies are taken from
+// preallocated chunks of memory that grow with the proof, so collecting the proof doesn't allocate for every node.
+func (t *Tree) addProofNode(node []byte) {
+	size := len(node)
+	// the proof nodes must not be nil, since nil marks padding nodes in the proof (e.g. for empty leaves)
+	if len(t.proofNodes) < size || t.proofNodes == nil {
+		// the proof grows by one node per layer and proven leaf, double the chunk size with the length of the proof
+		t.proofNodes = make([]byte, max(len(t.proof), 4)*size)
+	}
+	proofNode := t.proofNodes[:size:size]
+	t.proofNodes = t.proofNodes[size:]
+	copy(proofNode, node)
+	t.proof = append(t.proof, proofNode)
+}
+
+// paddingAt returns the padding node for missing nodes at the given height: the empty leaf for the layer of the leaves
+// if it is set, otherwise the padding of the tree.
+func (t *Tree) paddingAt(height int) []byte {
+	if height == 0 && t.emptyLeaf != nil {
+		return t.emptyLeaf
+	}
+	return t.padding
+}
+
+// nodeOrPadding returns the given node or the padding for the given height if the node is nil.
+func (t *Tree) nodeOrPadding(node []byte, height int) []byte {
+	if node == nil {
+		return t.paddingAt(height)
+	}
+	return node
+}
+
+// proofNode returns a copy of the given node to be appended to the given proof. If the node is nil the padding is used
+// instead. The copy reuses the buffer of the node in the proof beyond its length that will be overwritten by appending
+// if it is large enough (see RootAndProofReuse), otherwise it is taken from the preallocated nodes buffer if it has
+// enough space left. Leaves can differ in size from the other nodes (e.g. empty leaves), so the copy always has the
+// length of the given node.
+func (t *Tree) proofNode(proof [][]byte, nodes *[]byte, node []byte) []byte {
+	if node == nil {
+		node = t.padding
+	}
+
+	size := len(node)
+	var proofNode []byte
+	if len(proof) < cap(proof) {
+		proofNode = proof[:len(proof)+1][len(proof)]
+	}
+	switch {
+	case proofNode != nil && cap(proofNode) >= size:
+		proofNode = proofNode[:size]
+	case nodes != nil && len(*nodes) >= size:
+		proofNode = (*nodes)[:size:size]
+		*nodes = (*nodes)[size:]
+	default:
+		proofNode = make([]byte, size)
+	}
+	copy(proofNode, node)
+	return proofNode
+}
+
 // makeProof allocates a proof object with a size that fits the requested proof without reallocating while building it.
-func (t *Tree) makeProof() [][]byte {
-	if t.leavesToProve == nil {
-		return nil
+// The nodes of the proof are allocated in a single buffer, the part of the buffer that is not used by the nodes
+// already collected is returned to be used for the nodes added while building the proof. Zeroing the buffer up front
+// costs less than allocating every node separately, and the nodes of a proof are retained or dropped together anyway.
+func (t *Tree) makeProof() ([][]byte, []byte) {
+	if t.provenIndices == nil {
+		return nil, nil
 	}
 
-	proofLen := max(int(t.minHeight), bits.Len64(t.currentLeaf)-1, len(t.proof))
-	proof := make([][]byte, len(t.proof), proofLen)
-	for i, p := range t.proof {
-		proof[i] = make([]byte, len(p))
-		copy(proof[i], p)
+	proofLen := t.proofLen()
+	proof := make([][]byte, 0, proofLen)
+	nodes := make([]byte, proofLen*t.hasher.Size())
+	for _, p := range t.proof {
+		proof = append(proof, t.proofNode(proof, &nodes, p))
+	}
+	return proof, nodes
+}
+
+// proofLen returns an upper bound for the length of the proof of the tree.
+func (t *Tree) proofLen() int {
+	// the height of the tree is the number of layers below the root, i.e. ceil(log2(currentLeaf))
+	height := 0
+	if t.currentLeaf > 0 {
+		height = bits.Len64(t.currentLeaf - 1)
 	}
-	return proof
+	return max(int(t.minHeight), height, len(t.proof))
 }
@@ -0,0 +1,107 @@
+//go:build race
+
+package merkle_test
+
+import (
+	"encoding/binary"
+	"strings"
+	"testing"
+
+	"github.com/fasmat/merkle"
+)
+
+// blockingLeafs is a leaf hasher that blocks in Hash until it is released. It is used to hold a call to Add open
+// while another goroutine calls Add on the same tree.
+type blockingLeafs struct {
+	entered chan struct{}
+	release chan struct{}
+}
+
+func (b *blockingLeafs) Hash(buf, data []byte, _ [][]byte) []byte {
+	b.entered <- struct{}{}
+	<-b.release
+	return append(buf[:0], data...)
+}
+
+func (*blockingLeafs) Sequential() bool {
+	return false
+}
+
+func (*blockingLeafs) Size() int {
+	return 32
+}
+
+// TestTreeConcurrentAdd is run with the race detector to confirm that concurrent calls to Add are detected by the
+// tree before they access its state, i.e. the misuse results in a panic instead of a data race.
+func TestTreeConcurrentAdd(t *testing.T) {
+	t.Parallel()
+
+	leafHasher := &blockingLeafs{
+		entered: make(chan struct{}),
+		release: make(chan struct{}),
+	}
+	tree := merkle.TreeBuilder().
+		WithLeafHasher(leafHasher).
+		Build()
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		tree.Add(make([]byte, 32))
+	}()
+	<-leafHasher.entered
+
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Error("Expected concurrent Add to panic")
+			}
+		}()
+		b := make([]byte, 32)
+		binary.LittleEndian.PutUint64(b, 1)
+		tree.Add(b)
+	}()
+
+	close(leafHasher.release)
+	<-done
+	if stats := tree.Stats(); stats.LeafCount != 1 {
+		t.Errorf("Expected tree to contain 1 leaf, got %d", stats.LeafCount)
+	}
+}
+
+// TestTreeConcurrentAddAndPath confirms that AddAndPath detects a concurrent Add before it reads the parked nodes of
+// the tree and that the panic names the method that was called concurrently.
+func TestTreeConcurrentAddAndPath(t *testing.T) {
+	t.Parallel()
+
+	leafHasher := &blockingLeafs{
+		entered: make(chan struct{}),
+		release: make(chan struct{}),
+	}
+	tree := merkle.TreeBuilder().
+		WithLeafHasher(leafHasher).
+		Build()
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		tree.Add(make([]byte, 32))
+	}()
+	<-leafHasher.entered
+
+	func() {
+		defer func() {
+			msg, _ := recover().(string)
+			if !strings.Contains(msg, "Tree.AddAndPath") {
+				t.Errorf("Expected concurrent AddAndPath to panic naming Tree.AddAndPath, got %q", msg)
+			}
+		}()
+		tree.AddAndPath(make([]byte, 32))
+	}()
+
+	close(leafHasher.release)
+	<-done
+	if stats := tree.Stats(); stats.LeafCount != 1 {
+		t.Errorf("Expected tree to contain 1 leaf, got %d", stats.LeafCount)
+	}
+}
@@ -1,10 +1,19 @@
 package merkle_test
 
 import (
+	"bytes"
+	"crypto/sha256"
+	"crypto/sha512"
 	"encoding/binary"
 	"encoding/hex"
+	"errors"
 	"fmt"
+	"io"
+	"math"
+	"slices"
+	"sync"
 	"testing"
+	"testing/iotest"
 
 	"github.com/fasmat/merkle"
 )
@@ -296,6 +305,173 @@ func TestTreeMinHeightGreater(t *testing.T) {
 	}
 }
 
+func TestTreeHeight(t *testing.T) {
+	t.Parallel()
+
+	tt := []struct {
+		name      string
+		leaves    int
+		minHeight uint64
+		height    uint64
+	}{
+		{name: "empty", leaves: 0, height: 0},
+		{name: "empty with min height", leaves: 0, minHeight: 5, height: 0},
+		{name: "single leaf", leaves: 1, height: 1},
+		{name: "balanced", leaves: 8, height: 4},
+		{name: "unbalanced", leaves: 9, height: 5},
+		{name: "min height lower", leaves: 8, minHeight: 2, height: 4},
+		{name: "min height greater", leaves: 8, minHeight: 5, height: 5},
+		{name: "min height greater by two", leaves: 8, minHeight: 6, height: 6},
+		{name: "unbalanced with min height", leaves: 5, minHeight: 4, height: 5},
+		{name: "unbalanced with min height lower", leaves: 5, minHeight: 3, height: 4},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			tree := merkle.TreeBuilder().
+				WithHasher(concatHasher{}).
+				WithMinHeight(tc.minHeight).
+				WithLeafToProve(0).
+				Build()
+			for i := range tc.leaves {
+				tree.Add([]byte{byte(i)})
+			}
+
+			if height := tree.Height(); height != tc.height {
+				t.Errorf("Expected height to be %d, got %d", tc.height, height)
+			}
+			// the proof has one node per layer below the root
+			if _, proof := tree.RootAndProof(); tc.leaves > 0 && uint64(len(proof)) != tc.height-1 {
+				t.Errorf("Expected proof to be of length %d, got %d", tc.height-1, len(proof))
+			}
+		})
+	}
+}
+
+func TestTreeAddBatch(t *testing.T) {
+	t.Parallel()
+
+	values := make([][]byte, 13)
+	for i := range values {
+		values[i] = leaf(uint64(i))
+	}
+
+	builder := func() *merkle.Builder {
+		return merkle.TreeBuilder().
+			WithLeavesToProve(map[uint64]struct{}{2: {}, 7: {}, 12: {}}).
+			WithLeafHasher(merkle.SequentialWorkHasher())
+	}
+
+	single := builder().Build()
+	for _, value := range values {
+		single.Add(value)
+	}
+	expectedRoot, expectedProof := single.RootAndProof()
+
+	batched := builder().Build()
+	batched.AddBatch(values[:5])
+	batched.AddBatch(nil)
+	batched.AddBatch(values[5:])
+	root, proof := batched.RootAndProof()
+
+	if !bytes.Equal(root, expectedRoot) {
+		t.Errorf("Expected root to be %x, got %x", expectedRoot, root)
+	}
+	if !slices.EqualFunc(proof, expectedProof, bytes.Equal) {
+		t.Errorf("Expected proof to be %x, got %x", expectedProof, proof)
+	}
+}
+
+func TestTreeAddFrom(t *testing.T) {
+	t.Parallel()
+
+	const chunk = 64
+	data := make([]byte, 9*chunk+20)
+	for i := range data {
+		data[i] = byte(i)
+	}
+
+	builder := func() *merkle.Builder {
+		return merkle.TreeBuilder().
+			WithLeafContentHashing().
+			WithLeafToProve(9)
+	}
+
+	expected := builder().Build()
+	for i := 0; i < len(data); i += chunk {
+		expected.Add(data[i:min(i+chunk, len(data))])
+	}
+	expectedRoot, expectedProof := expected.RootAndProof()
+
+	tree := builder().Build()
+	n, err := tree.AddFrom(bytes.NewReader(data), chunk)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 10 {
+		t.Errorf("Expected %d leaves to be added, got %d", 10, n)
+	}
+	root, proof := tree.RootAndProof()
+	if !bytes.Equal(root, expectedRoot) {
+		t.Errorf("Expected root to be %x, got %x", expectedRoot, root)
+	}
+	if !slices.EqualFunc(proof, expectedProof, bytes.Equal) {
+		t.Errorf("Expected proof to be %x, got %x", expectedProof, proof)
+	}
+}
+
+func TestTreeAddFromReadError(t *testing.T) {
+	t.Parallel()
+
+	errRead := errors.New("read failed")
+	data := make([]byte, 3*32+10)
+	r := io.MultiReader(bytes.NewReader(data), iotest.ErrReader(errRead))
+
+	tree := merkle.TreeBuilder().WithLeafContentHashing().Build()
+	n, err := tree.AddFrom(r, 32)
+	if !errors.Is(err, errRead) {
+		t.Errorf("Expected error %v, got %v", errRead, err)
+	}
+	if n != 3 {
+		t.Errorf("Expected %d leaves to be added, got %d", 3, n)
+	}
+	if stats := tree.Stats(); stats.LeafCount != 3 {
+		t.Errorf("Expected tree to have %d leaves, got %d", 3, stats.LeafCount)
+	}
+}
+
+func TestTreeBuildFromCursor(t *testing.T) {
+	t.Parallel()
+
+	values := make([][]byte, 10)
+	for i := range values {
+		values[i] = make([]byte, 32)
+		binary.LittleEndian.PutUint64(values[i], uint64(i))
+	}
+
+	expected := merkle.NewTree()
+	for _, v := range values {
+		expected.Add(v)
+	}
+
+	idx := 0
+	tree := merkle.TreeBuilder().BuildFromCursor(func() ([]byte, bool) {
+		if idx >= len(values) {
+			return nil, false
+		}
+		idx++
+		return values[idx-1], true
+	})
+
+	rootString := hex.EncodeToString(tree.Root())
+	expectedString := hex.EncodeToString(expected.Root())
+	if rootString != expectedString {
+		t.Errorf("Expected hash to be %s, got %s", expectedString, rootString)
+	}
+}
+
 type concatHasher struct{}
 
 func (concatHasher) Size() int {
@@ -629,141 +805,1493 @@ func TestTreeMultiProofUnbalancedSequentialWork(t *testing.T) {
 	}
 }
 
-// Benchmark results
-//
-// goos: linux
-// goarch: arm64
-// pkg: github.com/fasmat/merkle
-// BenchmarkTreeAdd-10                            6180907             198.2 ns/op          32 B/op        1 allocs/op
-// BenchmarkTreeAddWithProof-10                   5955980             195.7 ns/op          32 B/op        1 allocs/op
-// BenchmarkTreeRootBalanced-10                  23883130              45.61 ns/op         32 B/op        1 allocs/op
-// BenchmarkTreeRootUnbalancedSmall-10             617790            1911 ns/op            32 B/op        1 allocs/op
-// BenchmarkTreeRootUnbalancedBig-10               571573            2120 ns/op            32 B/op        1 allocs/op
-// BenchmarkTreeProofBalanced-10                  3861918             305.8 ns/op         672 B/op       13 allocs/op
-// BenchmarkTreeProofUnbalancedSmall-10            506768            2366 ns/op          1104 B/op       14 allocs/op
-// BenchmarkTreeProofUnbalancedBig-10              468398            2527 ns/op          1280 B/op       15 allocs/op
-// BenchmarkTreeAddSequentialWork-10              1757840             695.8 ns/op          32 B/op        1 allocs/op
-// PASS
-
-func BenchmarkTreeAdd(b *testing.B) {
-	tree := merkle.NewTree()
-	buf := make([]byte, tree.NodeSize())
-	for i := 0; b.Loop(); i++ {
-		binary.LittleEndian.PutUint64(buf, uint64(i))
-		tree.Add(buf)
-	}
-}
+func TestTreeDirectedProof(t *testing.T) {
+	t.Parallel()
 
-func BenchmarkTreeAddWithProof(b *testing.B) {
 	tree := merkle.TreeBuilder().
 		WithLeafToProve(4).
 		Build()
 	buf := make([]byte, tree.NodeSize())
-	for i := 0; b.Loop(); i++ {
+	for i := range 8 {
 		binary.LittleEndian.PutUint64(buf, uint64(i))
 		tree.Add(buf)
 	}
-}
 
-func BenchmarkTreeRootBalanced(b *testing.B) {
-	tree := merkle.NewTree()
-	buf := make([]byte, tree.NodeSize())
+	root, steps := tree.RootAndDirectedProof(4)
+	rootString := hex.EncodeToString(root)
+	if rootString != "89a0f1577268cc19b0a39c7a69f804fd140640c699585eb635ebb03c06154cce" {
+		t.Errorf(
+			"Expected hash to be 89a0f1577268cc19b0a39c7a69f804fd140640c699585eb635ebb03c06154cce, got %s",
+			rootString,
+		)
+	}
 
-	// Generate a balanced tree, in this case tree.Root() will only return the (already calculated) root
-	for i := range 2048 {
-		binary.LittleEndian.PutUint64(buf, uint64(i))
-		tree.Add(buf)
+	expectedSteps := []struct {
+		hash   string
+		isLeft bool
+	}{
+		{"0500000000000000000000000000000000000000000000000000000000000000", false},
+		{"fa670379e5c2212ed93ff09769622f81f98a91e1ec8fb114d607dd25220b9088", false},
+		{"ba94ffe7edabf26ef12736f8eb5ce74d15bedb6af61444ae2906e926b1a95084", true},
+	}
+	if len(steps) != len(expectedSteps) {
+		t.Fatalf("Expected proof to be of length %d, got %d", len(expectedSteps), len(steps))
+	}
+	for i, s := range steps {
+		sString := hex.EncodeToString(s.Hash)
+		if sString != expectedSteps[i].hash {
+			t.Errorf("Expected steps[%d] to be %s, got %s", i, expectedSteps[i].hash, sString)
+		}
+		if s.IsLeft != expectedSteps[i].isLeft {
+			t.Errorf("Expected steps[%d].IsLeft to be %t, got %t", i, expectedSteps[i].isLeft, s.IsLeft)
+		}
 	}
 
-	for b.Loop() {
-		tree.Root()
+	_, steps = tree.RootAndDirectedProof(3)
+	if steps != nil {
+		t.Errorf("Expected no steps for a leaf that is not proven, got %v", steps)
 	}
 }
 
-func BenchmarkTreeRootUnbalancedSmall(b *testing.B) {
-	tree := merkle.NewTree()
-	buf := make([]byte, tree.NodeSize())
+func TestTreeProofWithLeafIndexOffset(t *testing.T) {
+	t.Parallel()
 
-	// Generate an tree that has 1 fewer than a power of 2 leaves, in this case tree.Root() will have to calculate the
-	// root by walking the tree up to the root.
-	for i := range 2047 {
+	// build the second shard of a 16 leaf tree split into two shards of 8 leaves
+	shard := merkle.TreeBuilder().
+		WithLeafIndexOffset(8).
+		WithLeafToProve(9).
+		Build()
+	tree := merkle.TreeBuilder().
+		WithLeafToProve(9).
+		Build()
+
+	buf := make([]byte, tree.NodeSize())
+	for i := range 16 {
 		binary.LittleEndian.PutUint64(buf, uint64(i))
 		tree.Add(buf)
+		if i >= 8 {
+			shard.Add(buf)
+		}
 	}
 
-	for b.Loop() {
-		tree.Root()
+	shardRoot, shardProof := shard.RootAndProof()
+	_, proof := tree.RootAndProof()
+
+	// the proof of the global tree extends the proof of the shard by the root of the first shard
+	if len(proof) != len(shardProof)+1 {
+		t.Fatalf("Expected proof to be of length %d, got %d", len(shardProof)+1, len(proof))
+	}
+	for i, p := range shardProof {
+		if !bytes.Equal(p, proof[i]) {
+			t.Errorf("Expected shard proof[%d] to be %x, got %x", i, proof[i], p)
+		}
 	}
-}
 
-func BenchmarkTreeRootUnbalancedBig(b *testing.B) {
-	tree := merkle.NewTree()
-	buf := make([]byte, tree.NodeSize())
+	leaves := make(map[uint64][]byte)
+	leaves[1] = make([]byte, tree.NodeSize())
+	binary.LittleEndian.PutUint64(leaves[1], 9)
 
-	// Generate an tree that has 1 more than a power of 2 leaves, in this case tree.Root() will have to calculate the
-	// root by walking the tree up to the root and padding on the way.
-	for i := range 2049 {
-		binary.LittleEndian.PutUint64(buf, uint64(i))
-		tree.Add(buf)
+	valid, err := merkle.ValidateProof(shardRoot, leaves, shardProof)
+	if err != nil {
+		t.Error(err)
 	}
-
-	for b.Loop() {
-		tree.Root()
+	if !valid {
+		t.Error("proof is not valid")
 	}
 }
 
-func BenchmarkTreeProofBalanced(b *testing.B) {
+func TestTreeVerify(t *testing.T) {
+	t.Parallel()
+
 	tree := merkle.TreeBuilder().
-		WithLeafToProve(1000).
+		WithLeafHasher(merkle.SequentialWorkHasher()).
+		WithLeafToProve(0).
+		WithLeafToProve(4).
+		WithLeafToProve(8).
+		WithRetainProvenLeaves().
 		Build()
 	buf := make([]byte, tree.NodeSize())
-	for i := range 2048 {
+	for i := range 10 {
 		binary.LittleEndian.PutUint64(buf, uint64(i))
 		tree.Add(buf)
 	}
 
-	for b.Loop() {
-		tree.RootAndProof()
+	valid, err := tree.Verify()
+	if err != nil {
+		t.Error(err)
+	}
+	if !valid {
+		t.Error("proof is not valid")
+	}
+
+	merkle.CorruptProof(tree)
+
+	valid, err = tree.Verify()
+	if err != nil {
+		t.Error(err)
+	}
+	if valid {
+		t.Error("expected proof of corrupted tree to be invalid")
 	}
 }
 
-func BenchmarkTreeProofUnbalancedSmall(b *testing.B) {
+func TestTreeVerifyNotRetained(t *testing.T) {
+	t.Parallel()
+
 	tree := merkle.TreeBuilder().
-		WithLeafToProve(1001).
+		WithLeafToProve(4).
 		Build()
 	buf := make([]byte, tree.NodeSize())
-	for i := range 2047 {
+	for i := range 8 {
 		binary.LittleEndian.PutUint64(buf, uint64(i))
 		tree.Add(buf)
 	}
 
-	for b.Loop() {
-		tree.RootAndProof()
+	valid, err := tree.Verify()
+	if !errors.Is(err, merkle.ErrProvenLeavesNotRetained) {
+		t.Errorf("expected error: %v, got: %v", merkle.ErrProvenLeavesNotRetained, err)
+	}
+	if valid {
+		t.Error("expected proof to be invalid")
 	}
 }
 
-func BenchmarkTreeProofUnbalancedBig(b *testing.B) {
+func TestTreeLeafContentHashing(t *testing.T) {
+	t.Parallel()
+
 	tree := merkle.TreeBuilder().
-		WithLeafToProve(1000).
+		WithLeafContentHashing().
+		WithLeafToProve(2).
+		WithRetainProvenLeaves().
 		Build()
-	buf := make([]byte, tree.NodeSize())
-	for i := range 2049 {
-		binary.LittleEndian.PutUint64(buf, uint64(i))
-		tree.Add(buf)
+
+	expected := merkle.TreeBuilder().
+		WithLeafToProve(2).
+		Build()
+
+	blobs := make([][]byte, 5)
+	for i := range blobs {
+		blobs[i] = bytes.Repeat([]byte{byte(i)}, 4096+i)
+		tree.Add(blobs[i])
+
+		hash := sha256.Sum256(blobs[i])
+		expected.Add(hash[:])
 	}
 
-	for b.Loop() {
-		tree.RootAndProof()
+	root, proof := tree.RootAndProof()
+	expectedRoot, expectedProof := expected.RootAndProof()
+	if !bytes.Equal(root, expectedRoot) {
+		t.Errorf("Expected root to be %x, got %x", expectedRoot, root)
+	}
+	if len(proof) != len(expectedProof) {
+		t.Fatalf("Expected proof to be of length %d, got %d", len(expectedProof), len(proof))
+	}
+	for i, p := range proof {
+		if !bytes.Equal(p, expectedProof[i]) {
+			t.Errorf("Expected proof[%d] to be %x, got %x", i, expectedProof[i], p)
+		}
+	}
+
+	valid, err := tree.Verify()
+	if err != nil {
+		t.Error(err)
+	}
+	if !valid {
+		t.Error("proof is not valid")
 	}
 }
 
-func BenchmarkTreeAddSequentialWork(b *testing.B) {
+func TestTreeClone(t *testing.T) {
+	t.Parallel()
+
 	tree := merkle.TreeBuilder().
-		WithLeafHasher(merkle.SequentialWorkHasher()).
+		WithLeafToProve(4).
+		WithLeafToProve(8).
 		Build()
 	buf := make([]byte, tree.NodeSize())
-	for i := 0; b.Loop(); i++ {
+	for i := range 8 {
 		binary.LittleEndian.PutUint64(buf, uint64(i))
 		tree.Add(buf)
 	}
+
+	clone := tree.Clone()
+	binary.LittleEndian.PutUint64(buf, 8)
+	tree.Add(buf)
+	binary.LittleEndian.PutUint64(buf, 9)
+	clone.Add(buf)
+
+	rootString := hex.EncodeToString(tree.Root())
+	if rootString != "cb71c80ee780788eedb819ec125a41e0cde57bd0955cdd3157ca363193ab5ff1" {
+		t.Errorf(
+			"Expected hash to be cb71c80ee780788eedb819ec125a41e0cde57bd0955cdd3157ca363193ab5ff1, got %s",
+			rootString,
+		)
+	}
+
+	expected := merkle.TreeBuilder().
+		WithLeafToProve(4).
+		WithLeafToProve(8).
+		Build()
+	for _, i := range []uint64{0, 1, 2, 3, 4, 5, 6, 7, 9} {
+		binary.LittleEndian.PutUint64(buf, i)
+		expected.Add(buf)
+	}
+
+	root, proof := clone.RootAndProof()
+	expectedRoot, expectedProof := expected.RootAndProof()
+	if !bytes.Equal(root, expectedRoot) {
+		t.Errorf("Expected root to be %x, got %x", expectedRoot, root)
+	}
+	if bytes.Equal(root, tree.Root()) {
+		t.Error("Expected roots of tree and clone to differ")
+	}
+	if len(proof) != len(expectedProof) {
+		t.Fatalf("Expected proof to be of length %d, got %d", len(expectedProof), len(proof))
+	}
+	for i, p := range proof {
+		if !bytes.Equal(p, expectedProof[i]) {
+			t.Errorf("Expected proof[%d] to be %x, got %x", i, expectedProof[i], p)
+		}
+	}
+}
+
+func TestTreeCloneFork(t *testing.T) {
+	t.Parallel()
+
+	builder := func() *merkle.Builder {
+		return merkle.TreeBuilder().
+			WithLeafHasher(merkle.SequentialWorkHasher()).
+			WithLeafToProve(4)
+	}
+
+	tree := builder().Build()
+	for i := range uint64(5) {
+		tree.Add(leaf(i))
+	}
+
+	// the fork and the original are extended concurrently with different 6th leaves
+	fork := tree.Clone()
+	var wg sync.WaitGroup
+	wg.Go(func() { tree.Add(leaf(5)) })
+	wg.Go(func() { fork.Add(leaf(100)) })
+	wg.Wait()
+
+	for _, tc := range []struct {
+		tree *merkle.Tree
+		last uint64
+	}{
+		{tree, 5},
+		{fork, 100},
+	} {
+		expected := builder().Build()
+		for i := range uint64(5) {
+			expected.Add(leaf(i))
+		}
+		expected.Add(leaf(tc.last))
+
+		root, proof := tc.tree.RootAndProof()
+		expectedRoot, expectedProof := expected.RootAndProof()
+		if !bytes.Equal(root, expectedRoot) {
+			t.Errorf("Expected root with leaf %d to be %x, got %x", tc.last, expectedRoot, root)
+		}
+		if !slices.EqualFunc(proof, expectedProof, bytes.Equal) {
+			t.Errorf("Expected proof with leaf %d to be %x, got %x", tc.last, expectedProof, proof)
+		}
+	}
+
+	if bytes.Equal(tree.Root(), fork.Root()) {
+		t.Error("Expected roots of tree and fork to differ")
+	}
+}
+
+func TestTreeProofSmall(t *testing.T) {
+	t.Parallel()
+
+	tt := []struct {
+		numLeaves   int
+		leafToProve uint64
+		proofLen    int
+	}{
+		{1, 0, 0},
+		{2, 0, 1},
+		{2, 1, 1},
+		{3, 0, 2},
+		{3, 2, 2},
+		{5, 4, 3},
+	}
+
+	for _, tc := range tt {
+		t.Run(fmt.Sprintf("numLeaves=%d/leaf=%d", tc.numLeaves, tc.leafToProve), func(t *testing.T) {
+			t.Parallel()
+
+			tree := merkle.TreeBuilder().
+				WithLeafToProve(tc.leafToProve).
+				Build()
+			leaves := make(map[uint64][]byte)
+			for i := range tc.numLeaves {
+				b := make([]byte, tree.NodeSize())
+				binary.LittleEndian.PutUint64(b, uint64(i))
+				tree.Add(b)
+				if uint64(i) == tc.leafToProve {
+					leaves[uint64(i)] = b
+				}
+			}
+
+			root, proof := tree.RootAndProof()
+			if len(proof) != tc.proofLen {
+				t.Fatalf("Expected proof to be of length %d, got %d", tc.proofLen, len(proof))
+			}
+			if cap(proof) != tc.proofLen {
+				t.Errorf("Expected proof to have capacity %d, got %d", tc.proofLen, cap(proof))
+			}
+
+			valid, err := merkle.ValidateProof(root, leaves, proof)
+			if err != nil {
+				t.Error(err)
+			}
+			if !valid {
+				t.Error("proof is not valid")
+			}
+		})
+	}
+}
+
+func TestTreeMinHeightProofPadding(t *testing.T) {
+	t.Parallel()
+
+	padding := bytes.Repeat([]byte{0xab}, 32)
+	tree := merkle.TreeBuilder().
+		WithRandomPadding(bytes.NewReader(padding)).
+		WithMinHeight(5).
+		WithLeafToProve(3).
+		Build()
+	for i := range 8 {
+		tree.Add(leaf(uint64(i)))
+	}
+
+	root, proof := tree.RootAndProof()
+	if len(proof) != 4 {
+		t.Fatalf("Expected proof of length 4, got %d", len(proof))
+	}
+	if !bytes.Equal(proof[3], padding) {
+		t.Errorf("Expected padding node %x, got %x", padding, proof[3])
+	}
+
+	// the padding node of the proof is a copy, modifying it does not affect the tree
+	proof[3][0] ^= 0xff
+	if !bytes.Equal(tree.Padding(), padding) {
+		t.Errorf("Expected padding to be %x, got %x", padding, tree.Padding())
+	}
+	root2, proof2 := tree.RootAndProof()
+	if !bytes.Equal(root, root2) {
+		t.Errorf("Expected root %x, got %x", root, root2)
+	}
+	if !bytes.Equal(proof2[3], padding) {
+		t.Errorf("Expected padding node %x, got %x", padding, proof2[3])
+	}
+
+	// without leaves to prove no padding nodes are collected
+	tree = merkle.TreeBuilder().
+		WithMinHeight(5).
+		Build()
+	for i := range 8 {
+		tree.Add(leaf(uint64(i)))
+	}
+	if _, proof := tree.RootAndProof(); proof != nil {
+		t.Errorf("Expected no proof, got %x", proof)
+	}
+}
+
+func TestTreeRandomPadding(t *testing.T) {
+	t.Parallel()
+
+	padding := bytes.Repeat([]byte{0xab}, 32)
+	tree := merkle.TreeBuilder().
+		WithRandomPadding(bytes.NewReader(padding)).
+		WithLeafToProve(8).
+		Build()
+	leaves := make(map[uint64][]byte)
+	for i := range 10 {
+		b := make([]byte, tree.NodeSize())
+		binary.LittleEndian.PutUint64(b, uint64(i))
+		tree.Add(b)
+		if i == 8 {
+			leaves[uint64(i)] = b
+		}
+	}
+
+	if !bytes.Equal(tree.Padding(), padding) {
+		t.Errorf("Expected padding to be %x, got %x", padding, tree.Padding())
+	}
+
+	root, proof := tree.RootAndProof()
+	rootString := hex.EncodeToString(root)
+	if rootString == "59f32a43534fe4c4c0966421aef624267cdf65bd11f74998c60f27c7caccb12d" {
+		t.Error("Expected root with random padding to differ from root with zero padding")
+	}
+	if len(proof) != 4 {
+		t.Fatalf("Expected proof to be of length %d, got %d", 4, len(proof))
+	}
+	for _, i := range []int{1, 2} {
+		if !bytes.Equal(proof[i], padding) {
+			t.Errorf("Expected proof[%d] to be the padding %x, got %x", i, padding, proof[i])
+		}
+	}
+
+	valid, err := merkle.ValidateProof(root, leaves, proof)
+	if err != nil {
+		t.Error(err)
+	}
+	if !valid {
+		t.Error("proof is not valid")
+	}
+}
+
+func TestTreeWithEmptyLeaf(t *testing.T) {
+	t.Parallel()
+
+	emptyLeaf := bytes.Repeat([]byte{0xee}, 32)
+	tree := merkle.TreeBuilder().
+		WithEmptyLeaf(emptyLeaf).
+		WithLeafToProve(10).
+		Build()
+	for i := range uint64(11) {
+		tree.Add(leaf(i))
+	}
+
+	root, proof := tree.RootAndProof()
+	if len(proof) != 4 {
+		t.Fatalf("Expected proof to be of length %d, got %d", 4, len(proof))
+	}
+	if !bytes.Equal(proof[0], emptyLeaf) {
+		t.Errorf("Expected proof[0] to be the empty leaf %x, got %x", emptyLeaf, proof[0])
+	}
+	// the padding of the layers above the leaves is not affected
+	if !bytes.Equal(proof[2], make([]byte, 32)) {
+		t.Errorf("Expected proof[2] to be zero padding, got %x", proof[2])
+	}
+
+	zeroPadded := merkle.TreeBuilder().Build()
+	for i := range uint64(11) {
+		zeroPadded.Add(leaf(i))
+	}
+	if bytes.Equal(root, zeroPadded.Root()) {
+		t.Error("Expected root with empty leaf to differ from root with zero padding")
+	}
+
+	other := merkle.TreeBuilder().WithEmptyLeaf(emptyLeaf).Build()
+	for i := range uint64(11) {
+		other.Add(leaf(i))
+	}
+	if !bytes.Equal(other.Clone().Root(), root) {
+		t.Error("Expected clone to use the empty leaf")
+	}
+
+	valid, err := merkle.ValidateProof(root, map[uint64][]byte{10: leaf(10)}, proof)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !valid {
+		t.Error("proof is not valid")
+	}
+}
+
+func TestTreeStats(t *testing.T) {
+	t.Parallel()
+
+	tt := []struct {
+		name      string
+		leaves    int
+		minHeight uint64
+		expected  merkle.TreeStats
+	}{
+		{
+			name:     "empty",
+			leaves:   0,
+			expected: merkle.TreeStats{Capacity: 1, PaddingLeaves: 1},
+		},
+		{
+			name:     "8 leaves",
+			leaves:   8,
+			expected: merkle.TreeStats{LeafCount: 8, Capacity: 8, Height: 3, IsBalanced: true},
+		},
+		{
+			name:     "10 leaves",
+			leaves:   10,
+			expected: merkle.TreeStats{LeafCount: 10, Capacity: 16, PaddingLeaves: 6, Height: 4},
+		},
+		{
+			name:      "8 leaves with min height",
+			leaves:    8,
+			minHeight: 5,
+			expected:  merkle.TreeStats{LeafCount: 8, Capacity: 16, PaddingLeaves: 8, Height: 4},
+		},
+		{
+			name:      "8 leaves with min height of the tree",
+			leaves:    8,
+			minHeight: 4,
+			expected:  merkle.TreeStats{LeafCount: 8, Capacity: 8, Height: 3, IsBalanced: true},
+		},
+		{
+			name:      "single leaf with min height",
+			leaves:    1,
+			minHeight: 3,
+			expected:  merkle.TreeStats{LeafCount: 1, Capacity: 4, PaddingLeaves: 3, Height: 2},
+		},
+		{
+			name:      "5 leaves with min height",
+			leaves:    5,
+			minHeight: 4,
+			expected:  merkle.TreeStats{LeafCount: 5, Capacity: 16, PaddingLeaves: 11, Height: 4},
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			tree := merkle.TreeBuilder().
+				WithMinHeight(tc.minHeight).
+				WithLeafToProve(0).
+				Build()
+			for i := range tc.leaves {
+				b := make([]byte, tree.NodeSize())
+				binary.LittleEndian.PutUint64(b, uint64(i))
+				tree.Add(b)
+			}
+
+			if stats := tree.Stats(); stats != tc.expected {
+				t.Errorf("Expected stats to be %+v, got %+v", tc.expected, stats)
+			}
+			// the proof has one node per layer below the root
+			if _, proof := tree.RootAndProof(); tc.leaves > 0 && uint64(len(proof)) != tc.expected.Height {
+				t.Errorf("Expected proof to be of length %d, got %d", tc.expected.Height, len(proof))
+			}
+		})
+	}
+}
+
+func TestTreeLiveRoot(t *testing.T) {
+	t.Parallel()
+
+	for _, minHeight := range []uint64{0, 5} {
+		t.Run(fmt.Sprintf("minHeight=%d", minHeight), func(t *testing.T) {
+			t.Parallel()
+
+			tree := merkle.TreeBuilder().
+				WithMinHeight(minHeight).
+				Build()
+			liveTree := merkle.TreeBuilder().
+				WithMinHeight(minHeight).
+				WithLiveRoot().
+				Build()
+			for i := range 20 {
+				b := make([]byte, tree.NodeSize())
+				binary.LittleEndian.PutUint64(b, uint64(i))
+				tree.Add(b)
+				liveTree.Add(b)
+
+				expected := tree.Root()
+				root := liveTree.CurrentRoot()
+				if !bytes.Equal(root, expected) {
+					t.Errorf("Expected root after %d leaves to be %x, got %x", i+1, expected, root)
+				}
+				if !bytes.Equal(liveTree.CurrentRoot(), expected) {
+					t.Errorf("Expected repeated root after %d leaves to be %x, got %x", i+1, expected, root)
+				}
+			}
+		})
+	}
+}
+
+func TestTreeEmptyLeaf(t *testing.T) {
+	t.Parallel()
+
+	tt := []struct {
+		name       string
+		leafHasher merkle.LeafHasher
+	}{
+		{
+			name:       "value leafs",
+			leafHasher: merkle.ValueLeafs(32),
+		},
+		{
+			name:       "sequential work",
+			leafHasher: merkle.SequentialWorkHasher(),
+		},
+	}
+
+	for _, tc := range tt {
+		for _, proven := range []uint64{2, 3, 4, 8} {
+			t.Run(fmt.Sprintf("%s/leaf %d", tc.name, proven), func(t *testing.T) {
+				t.Parallel()
+
+				tree := merkle.TreeBuilder().
+					WithLeafHasher(tc.leafHasher).
+					WithLeafToProve(proven).
+					Build()
+				leaves := make(map[uint64][]byte)
+				for i := range 9 {
+					b := make([]byte, tree.NodeSize())
+					binary.LittleEndian.PutUint64(b, uint64(i+1))
+					if i == 3 || i == 8 {
+						b = []byte{} // leaves 3 and 8 are empty
+					}
+					tree.Add(b)
+					if uint64(i) == proven {
+						leaves[uint64(i)] = b
+					}
+				}
+
+				root, proof := tree.RootAndProof()
+				valid, err := merkle.ValidateProof(root, leaves, proof, merkle.WithLeafHasher(tc.leafHasher))
+				if err != nil {
+					t.Fatal(err)
+				}
+				if !valid {
+					t.Error("proof is not valid")
+				}
+			})
+		}
+	}
+}
+
+func TestTreeFullNodeSink(t *testing.T) {
+	t.Parallel()
+
+	type node struct {
+		height int
+		index  uint64
+		hash   string
+	}
+	var nodes []node
+	tree := merkle.TreeBuilder().
+		WithFullNodeSink(func(height int, index uint64, hash []byte) {
+			nodes = append(nodes, node{height, index, hex.EncodeToString(hash)})
+		}).
+		Build()
+	for i := range 8 {
+		b := make([]byte, tree.NodeSize())
+		binary.LittleEndian.PutUint64(b, uint64(i))
+		tree.Add(b)
+	}
+	tree.Root()
+
+	expected := []node{
+		{1, 0, "cb592844121d926f1ca3ad4e1d6fb9d8e260ed6e3216361f7732e975a0e8bbf6"},
+		{1, 1, "0094579cfc7b716038d416a311465309bea202baa922b224a7b08f01599642fb"},
+		{2, 0, "ba94ffe7edabf26ef12736f8eb5ce74d15bedb6af61444ae2906e926b1a95084"},
+		{1, 2, "bd50456d5ad175ae99a1612a53ca229124b65d3eaabd9ff9c7ab979a385cf6b3"},
+		{1, 3, "fa670379e5c2212ed93ff09769622f81f98a91e1ec8fb114d607dd25220b9088"},
+		{2, 1, "633b26ee8a5d96d49a4861e9a5720492f0db5b6af305c0b5cfcc6a7ec9b676d4"},
+		{3, 0, "89a0f1577268cc19b0a39c7a69f804fd140640c699585eb635ebb03c06154cce"},
+	}
+	if !slices.Equal(nodes, expected) {
+		t.Errorf("Expected nodes to be %v, got %v", expected, nodes)
+	}
+}
+
+func TestTreeFullNodeSinkUnbalanced(t *testing.T) {
+	t.Parallel()
+
+	var heights []int
+	var indices []uint64
+	var last []byte
+	tree := merkle.TreeBuilder().
+		WithFullNodeSink(func(height int, index uint64, hash []byte) {
+			heights = append(heights, height)
+			indices = append(indices, index)
+			last = slices.Clone(hash)
+		}).
+		Build()
+	for i := range 10 {
+		b := make([]byte, tree.NodeSize())
+		binary.LittleEndian.PutUint64(b, uint64(i))
+		tree.Add(b)
+	}
+	root := tree.Root()
+
+	// 8 nodes are completed while adding the leaves, the tail (2, 2), (3, 1) and (4, 0) is emitted by Root
+	expectedHeights := []int{1, 1, 2, 1, 1, 2, 3, 1, 2, 3, 4}
+	expectedIndices := []uint64{0, 1, 0, 2, 3, 1, 0, 4, 2, 1, 0}
+	if !slices.Equal(heights, expectedHeights) {
+		t.Errorf("Expected heights to be %v, got %v", expectedHeights, heights)
+	}
+	if !slices.Equal(indices, expectedIndices) {
+		t.Errorf("Expected indices to be %v, got %v", expectedIndices, indices)
+	}
+	if !bytes.Equal(last, root) {
+		t.Errorf("Expected last node to be the root %x, got %x", root, last)
+	}
+
+	// the padded nodes are only reported once for the same number of leaves
+	tree.Root()
+	tree.RootAndProof()
+	tree.CurrentRoot()
+	if len(heights) != len(expectedHeights) {
+		t.Errorf("Expected %d nodes, got %d", len(expectedHeights), len(heights))
+	}
+
+	// after adding a leaf the new tail (1, 5), (2, 2), (3, 1) and (4, 0) is reported
+	b := make([]byte, tree.NodeSize())
+	binary.LittleEndian.PutUint64(b, 10)
+	tree.Add(b)
+	tree.Root()
+	tree.Root()
+	expectedHeights = append(expectedHeights, 1, 2, 3, 4)
+	expectedIndices = append(expectedIndices, 5, 2, 1, 0)
+	if !slices.Equal(heights, expectedHeights) {
+		t.Errorf("Expected heights to be %v, got %v", expectedHeights, heights)
+	}
+	if !slices.Equal(indices, expectedIndices) {
+		t.Errorf("Expected indices to be %v, got %v", expectedIndices, indices)
+	}
+}
+
+type sha512_256Hasher struct{}
+
+func (sha512_256Hasher) Size() int {
+	return sha512.Size256
+}
+
+func (sha512_256Hasher) Hash(buf, lChild, rChild []byte) []byte {
+	h := sha512.New512_256()
+	h.Write(lChild)
+	h.Write(rChild)
+	return h.Sum(buf[:0])
+}
+
+func TestTreeLayerHasher(t *testing.T) {
+	t.Parallel()
+
+	layerHasher := func(height uint64) merkle.Hasher {
+		if height <= 1 {
+			return merkle.Sha256()
+		}
+		return sha512_256Hasher{}
+	}
+
+	for _, tc := range []struct {
+		leaves uint64
+		proven uint64
+	}{
+		{leaves: 8, proven: 4},
+		{leaves: 10, proven: 8},
+	} {
+		t.Run(fmt.Sprintf("%d leaves", tc.leaves), func(t *testing.T) {
+			t.Parallel()
+
+			tree := merkle.TreeBuilder().
+				WithLayerHasher(layerHasher).
+				WithLeafToProve(tc.proven).
+				WithRetainProvenLeaves().
+				Build()
+			leaves := make(map[uint64][]byte)
+			for i := range tc.leaves {
+				b := make([]byte, tree.NodeSize())
+				binary.LittleEndian.PutUint64(b, i)
+				tree.Add(b)
+				if i == tc.proven {
+					leaves[i] = b
+				}
+			}
+
+			root, proof := tree.RootAndProof()
+			valid, err := merkle.ValidateProof(root, leaves, proof, merkle.WithLayerHasher(layerHasher))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !valid {
+				t.Error("proof is not valid")
+			}
+
+			valid, err = merkle.ValidateProof(root, leaves, proof)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if valid {
+				t.Error("Expected proof to be invalid without layer hasher")
+			}
+
+			valid, err = tree.Verify()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !valid {
+				t.Error("tree does not verify its own proof")
+			}
+		})
+	}
+}
+
+func TestTreeProvenLeaf(t *testing.T) {
+	t.Parallel()
+
+	tree := merkle.TreeBuilder().
+		WithLeavesToProve(map[uint64]struct{}{0: {}, 4: {}, 7: {}}).
+		WithRetainProvenLeaves().
+		Build()
+	values := make([][]byte, 0, 8)
+	for i := range 8 {
+		b := make([]byte, tree.NodeSize())
+		binary.LittleEndian.PutUint64(b, uint64(i))
+		tree.Add(b)
+		values = append(values, b)
+	}
+
+	for i := range uint64(8) {
+		leaf, ok := tree.ProvenLeaf(i)
+		switch i {
+		case 0, 4, 7:
+			if !ok {
+				t.Errorf("Expected leaf %d to be retained", i)
+			}
+			if !bytes.Equal(leaf, values[i]) {
+				t.Errorf("Expected leaf %d to be %x, got %x", i, values[i], leaf)
+			}
+		default:
+			if ok {
+				t.Errorf("Expected leaf %d not to be retained", i)
+			}
+		}
+	}
+
+	tree = merkle.TreeBuilder().
+		WithLeafToProve(4).
+		Build()
+	for _, value := range values {
+		tree.Add(value)
+	}
+	if _, ok := tree.ProvenLeaf(4); ok {
+		t.Error("Expected leaf not to be retained without WithRetainProvenLeaves")
+	}
+}
+
+func TestTreeRootAndProofErr(t *testing.T) {
+	t.Parallel()
+
+	tt := []struct {
+		name     string
+		builder  *merkle.Builder
+		proofLen int
+		err      error
+	}{
+		{
+			name:    "permissive without targets",
+			builder: merkle.TreeBuilder(),
+		},
+		{
+			name:    "required without targets",
+			builder: merkle.TreeBuilder().WithRequireProofTargets(),
+			err:     merkle.ErrNoProofTargets,
+		},
+		{
+			name:     "required with targets",
+			builder:  merkle.TreeBuilder().WithRequireProofTargets().WithLeafToProve(4),
+			proofLen: 3,
+		},
+		{
+			name:     "complete",
+			builder:  merkle.TreeBuilder().WithExpectedLeafCount(8).WithLeafToProve(4),
+			proofLen: 3,
+		},
+		{
+			name:    "incomplete",
+			builder: merkle.TreeBuilder().WithExpectedLeafCount(10).WithLeafToProve(4),
+			err:     merkle.ErrIncompleteTree,
+		},
+		{
+			name:    "more leaves than expected",
+			builder: merkle.TreeBuilder().WithExpectedLeafCount(7).WithLeafToProve(4),
+			err:     merkle.ErrIncompleteTree,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			tree := tc.builder.Build()
+			for i := range 8 {
+				b := make([]byte, tree.NodeSize())
+				binary.LittleEndian.PutUint64(b, uint64(i))
+				tree.Add(b)
+			}
+
+			root, proof, err := tree.RootAndProofErr()
+			if !errors.Is(err, tc.err) {
+				t.Fatalf("Expected error to be %v, got %v", tc.err, err)
+			}
+			if len(proof) != tc.proofLen {
+				t.Errorf("Expected proof to be of length %d, got %d", tc.proofLen, len(proof))
+			}
+			if tc.err == nil && !bytes.Equal(root, tree.Root()) {
+				t.Errorf("Expected root to be %x, got %x", tree.Root(), root)
+			}
+		})
+	}
+}
+
+func TestTreeRootAndProofReuse(t *testing.T) {
+	t.Parallel()
+
+	tree := merkle.TreeBuilder().
+		WithLeavesToProve(map[uint64]struct{}{2: {}, 4: {}}).
+		WithMinHeight(3).
+		Build()
+	var proof [][]byte
+	for i := range 20 {
+		b := make([]byte, tree.NodeSize())
+		binary.LittleEndian.PutUint64(b, uint64(i))
+		tree.Add(b)
+
+		expectedRoot, expectedProof := tree.RootAndProof()
+		var root []byte
+		root, proof = tree.RootAndProofReuse(proof)
+		if !bytes.Equal(root, expectedRoot) {
+			t.Errorf("Expected root after %d leaves to be %x, got %x", i+1, expectedRoot, root)
+		}
+		if len(proof) != len(expectedProof) {
+			t.Fatalf("Expected proof after %d leaves to be of length %d, got %d", i+1, len(expectedProof), len(proof))
+		}
+		for k := range proof {
+			if !bytes.Equal(proof[k], expectedProof[k]) {
+				t.Errorf("Expected proof[%d] after %d leaves to be %x, got %x", k, i+1, expectedProof[k], proof[k])
+			}
+		}
+	}
+}
+
+func TestTreeRootAndProofReuseSingleLeaf(t *testing.T) {
+	t.Parallel()
+
+	for _, minHeight := range []uint64{0, 2} {
+		t.Run(fmt.Sprintf("min height %d", minHeight), func(t *testing.T) {
+			t.Parallel()
+
+			tree := merkle.TreeBuilder().
+				WithLeafToProve(0).
+				WithMinHeight(minHeight).
+				Build()
+			tree.Add(make([]byte, tree.NodeSize()))
+
+			expectedRoot, expectedProof := tree.RootAndProof()
+			root, proof := tree.RootAndProofReuse(nil)
+			if !bytes.Equal(root, expectedRoot) {
+				t.Errorf("Expected root to be %x, got %x", expectedRoot, root)
+			}
+			if proof == nil || len(proof) != len(expectedProof) {
+				t.Fatalf("Expected proof to be %x, got %x", expectedProof, proof)
+			}
+			for k := range proof {
+				if !bytes.Equal(proof[k], expectedProof[k]) {
+					t.Errorf("Expected proof[%d] to be %x, got %x", k, expectedProof[k], proof[k])
+				}
+			}
+		})
+	}
+}
+
+func TestTreeAddLeafHashSequentialWork(t *testing.T) {
+	t.Parallel()
+
+	leafHasher := merkle.SequentialWorkHasher()
+	reference := merkle.TreeBuilder().
+		WithLeafHasher(leafHasher).
+		Build()
+	tree := merkle.TreeBuilder().
+		WithLeafHasher(leafHasher).
+		WithLeafToProve(4).
+		Build()
+	leaves := make(map[uint64][]byte)
+	for i := range uint64(8) {
+		value := leaf(i)
+		if i == 4 {
+			leaves[i] = value
+		}
+		if i != 1 && i != 2 && i != 6 {
+			tree.Add(value)
+			reference.Add(value)
+			continue
+		}
+
+		// precompute the leaf hash with the parked nodes of the tree, like the leaf hasher would do
+		peaks := tree.PeaksWithHeights()
+		var parkedNodes [][]byte
+		if len(peaks) > 0 {
+			parkedNodes = make([][]byte, peaks[0].Height+1)
+			for _, p := range peaks {
+				parkedNodes[p.Height] = p.Hash
+			}
+		}
+		if err := tree.AddLeafHash(leafHasher.Hash(nil, value, parkedNodes)); err != nil {
+			t.Fatal(err)
+		}
+		reference.Add(value)
+	}
+
+	root, proof := tree.RootAndProof()
+	if !bytes.Equal(root, reference.Root()) {
+		t.Errorf("Expected root to be %x, got %x", reference.Root(), root)
+	}
+	valid, err := merkle.ValidateProof(root, leaves, proof, merkle.WithLeafHasher(leafHasher))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !valid {
+		t.Error("proof is not valid")
+	}
+}
+
+func TestTreeAddLeafHashProven(t *testing.T) {
+	t.Parallel()
+
+	tree := merkle.TreeBuilder().
+		WithLeafHasher(merkle.SequentialWorkHasher()).
+		WithLeafToProve(0).
+		Build()
+	err := tree.AddLeafHash(leaf(0))
+	if !errors.Is(err, merkle.ErrPrecomputedProvenLeaf) {
+		t.Errorf("Expected error to be %v, got %v", merkle.ErrPrecomputedProvenLeaf, err)
+	}
+	if stats := tree.Stats(); stats.LeafCount != 0 {
+		t.Errorf("Expected rejected leaf not to be added, got %d leaves", stats.LeafCount)
+	}
+
+	// without a sequential leaf hasher the precomputed leaf is proven with its hash as value
+	tree = merkle.TreeBuilder().
+		WithLeafContentHashing().
+		WithLeafToProve(2).
+		Build()
+	for i := range uint64(4) {
+		if err := tree.AddLeafHash(leaf(i)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	root, proof := tree.RootAndProof()
+	valid, err := merkle.ValidateProof(root, map[uint64][]byte{2: leaf(2)}, proof)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !valid {
+		t.Error("proof is not valid")
+	}
+}
+
+// Benchmark results
+//
+// goos: linux
+// goarch: amd64
+// pkg: github.com/fasmat/merkle
+// cpu: Intel(R) Xeon(R) Processor
+// BenchmarkTreeAdd                  	 2812114	       432.6 ns/op	      32 B/op	       1 allocs/op
+// BenchmarkTreeAddWithProof         	 2788678	       430.9 ns/op	      32 B/op	       1 allocs/op
+// BenchmarkTreeRootBalanced         	 6979518	       159.8 ns/op	      32 B/op	       1 allocs/op
+// BenchmarkTreeRootUnbalancedSmall  	  425803	      2689 ns/op	      32 B/op	       1 allocs/op
+// BenchmarkTreeRootUnbalancedBig    	  392197	      2974 ns/op	      32 B/op	       1 allocs/op
+// BenchmarkTreeProofBalanced        	 1290844	       918.9 ns/op	     672 B/op	       3 allocs/op
+// BenchmarkTreeProofUnbalancedSmall 	  289676	      3990 ns/op	     672 B/op	       3 allocs/op
+// BenchmarkTreeProofUnbalancedBig   	  274803	      4227 ns/op	     704 B/op	       3 allocs/op
+// BenchmarkTreeAddSequentialWork    	 1000000	      1268 ns/op	      32 B/op	       1 allocs/op
+// PASS
+//
+// Before the nodes of the proof were allocated in a single buffer every proof node was allocated separately, on the
+// same machine:
+//
+// BenchmarkTreeProofBalanced        	 1407008	       806.5 ns/op	     672 B/op	      13 allocs/op
+// BenchmarkTreeProofUnbalancedSmall 	  390046	      3395 ns/op	    1104 B/op	      14 allocs/op
+// BenchmarkTreeProofUnbalancedBig   	  287314	      3803 ns/op	    1280 B/op	      15 allocs/op
+
+func BenchmarkTreeAdd(b *testing.B) {
+	tree := merkle.NewTree()
+	buf := make([]byte, tree.NodeSize())
+	for i := 0; b.Loop(); i++ {
+		binary.LittleEndian.PutUint64(buf, uint64(i))
+		tree.Add(buf)
+	}
+}
+
+func BenchmarkTreeAddWithProof(b *testing.B) {
+	tree := merkle.TreeBuilder().
+		WithLeafToProve(4).
+		Build()
+	buf := make([]byte, tree.NodeSize())
+	for i := 0; b.Loop(); i++ {
+		binary.LittleEndian.PutUint64(buf, uint64(i))
+		tree.Add(buf)
+	}
+}
+
+func BenchmarkTreeBuildWithProof(b *testing.B) {
+	buf := make([]byte, 32)
+	for b.Loop() {
+		tree := merkle.TreeBuilder().
+			WithLeavesToProve(map[uint64]struct{}{4: {}, 1000: {}, 2047: {}}).
+			Build()
+		for i := range 2048 {
+			binary.LittleEndian.PutUint64(buf, uint64(i))
+			tree.Add(buf)
+		}
+	}
+}
+
+func BenchmarkTreeRootBalanced(b *testing.B) {
+	tree := merkle.NewTree()
+	buf := make([]byte, tree.NodeSize())
+
+	// Generate a balanced tree, in this case tree.Root() will only return the (already calculated) root
+	for i := range 2048 {
+		binary.LittleEndian.PutUint64(buf, uint64(i))
+		tree.Add(buf)
+	}
+
+	for b.Loop() {
+		tree.Root()
+	}
+}
+
+func BenchmarkTreeRootUnbalancedSmall(b *testing.B) {
+	tree := merkle.NewTree()
+	buf := make([]byte, tree.NodeSize())
+
+	// Generate an tree that has 1 fewer than a power of 2 leaves, in this case tree.Root() will have to calculate the
+	// root by walking the tree up to the root.
+	for i := range 2047 {
+		binary.LittleEndian.PutUint64(buf, uint64(i))
+		tree.Add(buf)
+	}
+
+	for b.Loop() {
+		tree.Root()
+	}
+}
+
+func BenchmarkTreeCurrentRoot(b *testing.B) {
+	tree := merkle.TreeBuilder().
+		WithLiveRoot().
+		Build()
+	buf := make([]byte, tree.NodeSize())
+
+	// Generate an unbalanced tree, the root is only calculated on the first call to tree.CurrentRoot()
+	for i := range 2047 {
+		binary.LittleEndian.PutUint64(buf, uint64(i))
+		tree.Add(buf)
+	}
+
+	for b.Loop() {
+		tree.CurrentRoot()
+	}
+}
+
+func BenchmarkTreeRootUnbalancedBig(b *testing.B) {
+	tree := merkle.NewTree()
+	buf := make([]byte, tree.NodeSize())
+
+	// Generate an tree that has 1 more than a power of 2 leaves, in this case tree.Root() will have to calculate the
+	// root by walking the tree up to the root and padding on the way.
+	for i := range 2049 {
+		binary.LittleEndian.PutUint64(buf, uint64(i))
+		tree.Add(buf)
+	}
+
+	for b.Loop() {
+		tree.Root()
+	}
+}
+
+func BenchmarkTreeRootProving(b *testing.B) {
+	for _, proving := range []bool{false, true} {
+		b.Run(fmt.Sprintf("proving=%t", proving), func(b *testing.B) {
+			builder := merkle.TreeBuilder()
+			if proving {
+				builder.WithLeafToProve(1000)
+			}
+			tree := builder.Build()
+			buf := make([]byte, tree.NodeSize())
+			for i := range 2049 {
+				binary.LittleEndian.PutUint64(buf, uint64(i))
+				tree.Add(buf)
+			}
+
+			for b.Loop() {
+				tree.Root()
+			}
+		})
+	}
+}
+
+func BenchmarkTreeProofBalanced(b *testing.B) {
+	tree := merkle.TreeBuilder().
+		WithLeafToProve(1000).
+		Build()
+	buf := make([]byte, tree.NodeSize())
+	for i := range 2048 {
+		binary.LittleEndian.PutUint64(buf, uint64(i))
+		tree.Add(buf)
+	}
+
+	for b.Loop() {
+		tree.RootAndProof()
+	}
+}
+
+func BenchmarkTreeProofUnbalancedSmall(b *testing.B) {
+	tree := merkle.TreeBuilder().
+		WithLeafToProve(1001).
+		Build()
+	buf := make([]byte, tree.NodeSize())
+	for i := range 2047 {
+		binary.LittleEndian.PutUint64(buf, uint64(i))
+		tree.Add(buf)
+	}
+
+	for b.Loop() {
+		tree.RootAndProof()
+	}
+}
+
+func BenchmarkTreeProofUnbalancedBig(b *testing.B) {
+	tree := merkle.TreeBuilder().
+		WithLeafToProve(1000).
+		Build()
+	buf := make([]byte, tree.NodeSize())
+	for i := range 2049 {
+		binary.LittleEndian.PutUint64(buf, uint64(i))
+		tree.Add(buf)
+	}
+
+	for b.Loop() {
+		tree.RootAndProof()
+	}
+}
+
+func BenchmarkTreeProofReuse(b *testing.B) {
+	tree := merkle.TreeBuilder().
+		WithLeafToProve(4).
+		Build()
+	buf := make([]byte, tree.NodeSize())
+
+	// Generate an unbalanced tree
+	for i := range 2047 {
+		binary.LittleEndian.PutUint64(buf, uint64(i))
+		tree.Add(buf)
+	}
+
+	// after the first call the proof and root buffers are reused
+	_, proof := tree.RootAndProofReuse(nil)
+	for b.Loop() {
+		_, proof = tree.RootAndProofReuse(proof)
+	}
+}
+
+func BenchmarkTreeAddSequentialWork(b *testing.B) {
+	tree := merkle.TreeBuilder().
+		WithLeafHasher(merkle.SequentialWorkHasher()).
+		Build()
+	buf := make([]byte, tree.NodeSize())
+	for i := 0; b.Loop(); i++ {
+		binary.LittleEndian.PutUint64(buf, uint64(i))
+		tree.Add(buf)
+	}
+}
+
+func TestTreeCheckpoints(t *testing.T) {
+	t.Parallel()
+
+	for _, minHeight := range []uint64{0, 5} {
+		t.Run(fmt.Sprintf("min height %d", minHeight), func(t *testing.T) {
+			t.Parallel()
+
+			tree := merkle.TreeBuilder().
+				WithMinHeight(minHeight).
+				WithRecordedCheckpoints().
+				Build()
+			for i := range uint64(10) {
+				tree.Add(leaf(i))
+			}
+
+			checkpoints := tree.Checkpoints()
+			if len(checkpoints) != 4 {
+				t.Fatalf("Expected 4 checkpoints, got %d", len(checkpoints))
+			}
+			for _, count := range []uint64{1, 2, 4, 8} {
+				fresh := merkle.TreeBuilder().WithMinHeight(minHeight).Build()
+				for i := range count {
+					fresh.Add(leaf(i))
+				}
+				if !bytes.Equal(checkpoints[count], fresh.Root()) {
+					t.Errorf("Expected checkpoint at %d leaves to be %x, got %x",
+						count, fresh.Root(), checkpoints[count])
+				}
+			}
+		})
+	}
+}
+
+func TestTreeCheckpointsNotRecorded(t *testing.T) {
+	t.Parallel()
+
+	tree := merkle.NewTree()
+	tree.Add(leaf(0))
+	if checkpoints := tree.Checkpoints(); checkpoints != nil {
+		t.Errorf("Expected no checkpoints, got %v", checkpoints)
+	}
+}
+
+func TestTreeSizeBinding(t *testing.T) {
+	t.Parallel()
+
+	build := func(leaves uint64) *merkle.Tree {
+		tree := merkle.TreeBuilder().
+			WithSizeBinding().
+			WithLeafToProve(4).
+			WithRetainProvenLeaves().
+			WithLiveRoot().
+			WithRecordedCheckpoints().
+			Build()
+		for i := range leaves {
+			tree.Add(leaf(i))
+		}
+		return tree
+	}
+
+	tree := build(10)
+	root, proof := tree.RootAndProof()
+
+	// the unbound root of a tree with 10 leaves is 59f32a43...
+	unbound, _ := hex.DecodeString("59f32a43534fe4c4c0966421aef624267cdf65bd11f74998c60f27c7caccb12d")
+	count := make([]byte, 8)
+	binary.BigEndian.PutUint64(count, 10)
+	expected := sha256.Sum256(append(count, unbound...))
+	if !bytes.Equal(root, expected[:]) {
+		t.Fatalf("Expected root to be %x, got %x", expected, root)
+	}
+	// pinned, since the encoding of the count is part of the format of bound roots
+	if hex.EncodeToString(root) != "edddeeb1b7c1470dfa31006cf6d55290803de0235336a2d3e6cf6b752f42bc46" {
+		t.Errorf("Expected root to be edddeeb1..., got %x", root)
+	}
+	if !bytes.Equal(tree.CurrentRoot(), root) {
+		t.Errorf("Expected current root to be %x, got %x", root, tree.CurrentRoot())
+	}
+
+	root16 := build(16).Root()
+	if bytes.Equal(root, root16) {
+		t.Fatal("Expected roots bound to different sizes to differ")
+	}
+	if checkpoint := build(16).Checkpoints()[16]; !bytes.Equal(checkpoint, root16) {
+		t.Errorf("Expected checkpoint to be %x, got %x", root16, checkpoint)
+	}
+
+	valid, err := tree.Verify()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !valid {
+		t.Error("Expected tree to verify its own proof")
+	}
+
+	leaves := map[uint64][]byte{4: leaf(4)}
+	for _, tc := range []struct {
+		opts  []merkle.ValidatorOpt
+		valid bool
+	}{
+		{opts: []merkle.ValidatorOpt{merkle.WithSizeBinding(10)}, valid: true},
+		{opts: []merkle.ValidatorOpt{merkle.WithSizeBinding(16)}, valid: false},
+		{opts: nil, valid: false},
+	} {
+		valid, err := merkle.ValidateProof(root, leaves, proof, tc.opts...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if valid != tc.valid {
+			t.Errorf("Expected proof validity to be %t, got %t", tc.valid, valid)
+		}
+	}
+}
+
+func TestTreeAddAndPath(t *testing.T) {
+	t.Parallel()
+
+	tree := merkle.NewTree()
+	paths := make([][][]byte, 8)
+	for i := range uint64(8) {
+		paths[i] = tree.AddAndPath(leaf(i))
+	}
+
+	// leaf 6 has the parent of leaves 4 and 5 and the root of leaves 0 to 3 as left siblings
+	expected := []string{
+		"bd50456d5ad175ae99a1612a53ca229124b65d3eaabd9ff9c7ab979a385cf6b3",
+		"ba94ffe7edabf26ef12736f8eb5ce74d15bedb6af61444ae2906e926b1a95084",
+	}
+	if len(paths[6]) != len(expected) {
+		t.Fatalf("Expected path of leaf 6 to have %d nodes, got %d", len(expected), len(paths[6]))
+	}
+	for i, node := range paths[6] {
+		if hex.EncodeToString(node) != expected[i] {
+			t.Errorf("Expected path[%d] of leaf 6 to be %s, got %x", i, expected[i], node)
+		}
+	}
+
+	// the path contains the nodes of the final proof that are left siblings
+	for i := range uint64(8) {
+		prover := merkle.TreeBuilder().WithLeafToProve(i).Build()
+		for j := range uint64(8) {
+			prover.Add(leaf(j))
+		}
+		_, proof := prover.RootAndProof()
+
+		var leftSiblings [][]byte
+		for height, node := range proof {
+			if i>>height&1 == 1 {
+				leftSiblings = append(leftSiblings, node)
+			}
+		}
+		if !slices.EqualFunc(paths[i], leftSiblings, bytes.Equal) {
+			t.Errorf("Expected path of leaf %d to be %x, got %x", i, leftSiblings, paths[i])
+		}
+	}
+}
+
+func TestTreeTooManyLeaves(t *testing.T) {
+	t.Parallel()
+
+	tree := merkle.NewTree()
+	merkle.SetLeafCount(tree, math.MaxUint64-1)
+	if err := tree.AddChecked(leaf(0)); err != nil {
+		t.Fatalf("Expected last leaf to be added, got %v", err)
+	}
+
+	if err := tree.AddChecked(leaf(1)); !errors.Is(err, merkle.ErrTooManyLeaves) {
+		t.Errorf("Expected error %v, got %v", merkle.ErrTooManyLeaves, err)
+	}
+	if err := tree.AddLeafHash(leaf(1)); !errors.Is(err, merkle.ErrTooManyLeaves) {
+		t.Errorf("Expected error %v, got %v", merkle.ErrTooManyLeaves, err)
+	}
+
+	defer func() {
+		err, _ := recover().(error)
+		if !errors.Is(err, merkle.ErrTooManyLeaves) {
+			t.Errorf("Expected Add to panic with %v, got %v", merkle.ErrTooManyLeaves, err)
+		}
+	}()
+	tree.Add(leaf(1))
+}
+
+func TestTreeAddBatchTooManyLeaves(t *testing.T) {
+	t.Parallel()
+
+	tree := merkle.NewTree()
+	merkle.SetLeafCount(tree, math.MaxUint64-1)
+	root := tree.Root()
+
+	defer func() {
+		err, _ := recover().(error)
+		if !errors.Is(err, merkle.ErrTooManyLeaves) {
+			t.Errorf("Expected AddBatch to panic with %v, got %v", merkle.ErrTooManyLeaves, err)
+		}
+		if !bytes.Equal(tree.Root(), root) {
+			t.Error("Expected no leaf to be added")
+		}
+	}()
+	tree.AddBatch([][]byte{leaf(0), leaf(1)})
 }
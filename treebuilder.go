@@ -1,16 +1,33 @@
 package merkle
 
 import (
+	"fmt"
+	"io"
 	"maps"
 	"slices"
 )
 
 // Builder is a builder for creating a Merkle tree. Use it with TreeBuilder() and With...() methods.
 type Builder struct {
-	hasher        Hasher
-	leafHasher    LeafHasher
-	minHeight     uint64
-	leavesToProve map[uint64]struct{}
+	hasher          Hasher
+	leafHasher      LeafHasher
+	minHeight       uint64
+	indexOffset     uint64
+	leavesToProve   map[uint64]struct{}
+	leafHashToProve []byte
+	retainLeaves    bool
+	requireTargets  bool
+	checkLeafCount  bool
+	leafCount       uint64
+	liveRoot        bool
+	checkpoints     bool
+	sizeBinding     bool
+	layerHasher     func(height uint64) Hasher
+	nodeSink        func(height int, index uint64, hash []byte)
+
+	contentHashing bool
+	paddingSource  io.Reader
+	emptyLeaf      []byte
 }
 
 // NewTree creates a new Merkle tree with the default hash function (SHA256).
@@ -42,12 +59,73 @@ func (tb *Builder) WithLeafHasher(h LeafHasher) *Builder {
 	return tb
 }
 
+// WithLayerHasher sets a function that returns the hash function used to calculate the nodes at the given height. The
+// height of a node is the number of layers below it, i.e. fn(1) is used to hash the leaves together and the root is
+// calculated with fn(h) for a tree of height h. This allows e.g. to use a cheap hash function for the lower layers
+// and a stronger one close to the root.
+//
+// The hasher set with WithHasher is still used for the size of the nodes and the padding. All hashers returned by fn
+// have to be of the same size, otherwise adding leaves to the tree panics. To validate proofs of such a tree pass the
+// same function to the validator with the WithLayerHasher option.
+func (tb *Builder) WithLayerHasher(fn func(height uint64) Hasher) *Builder {
+	tb.layerHasher = fn
+	return tb
+}
+
+// WithLeafContentHashing configures the tree to hash the data passed to Add with the hash function of the tree before
+// using it as leaf. This allows adding leaves of arbitrary length (e.g. large blobs) without hashing them manually
+// first. The data is streamed into the hash function, so it is not copied.
+//
+// Unlike ValueLeafs, which uses the data as is and therefore requires it to be the size of a node, the leaves are
+// always hashed. This option overrides any leaf hasher set with WithLeafHasher. To validate proofs of such a tree use
+// ContentHasher with the same hasher as leaf hasher.
+func (tb *Builder) WithLeafContentHashing() *Builder {
+	tb.contentHashing = true
+	return tb
+}
+
 // WithMinHeight sets the minimum height for the Merkle tree.
 func (tb *Builder) WithMinHeight(h uint64) *Builder {
 	tb.minHeight = h
 	return tb
 }
 
+// WithLeafIndexOffset sets the global index of the first leaf added to the tree. This is useful when the tree is a
+// shard of a larger tree, e.g. shard k of a tree sharded into subtrees of size s starts at offset k*s.
+//
+// The indices passed to WithLeafToProve and WithLeavesToProve are interpreted as global indices, leaves with an index
+// lower than the offset are ignored. The root and proof produced by the tree are those of the shard, i.e. the proof
+// has to be validated against the shard's root with the indices relative to the offset.
+func (tb *Builder) WithLeafIndexOffset(offset uint64) *Builder {
+	tb.indexOffset = offset
+	return tb
+}
+
+// WithRandomPadding configures the tree to use a padding node read from r instead of the default all-zero padding.
+// The padding is read once when the tree is built and then used for all layers of the tree. Build panics if it cannot
+// read enough bytes from r.
+//
+// Random padding hides whether a subtree of an unbalanced tree is empty, since padding nodes are indistinguishable
+// from regular nodes. The tradeoff is that the root cannot be reproduced without knowing the padding. Proofs contain
+// the padding nodes they need, so they can still be validated, but a tree rebuilt from the same leaves will have a
+// different root unless the same padding is used. Use Tree.Padding to retrieve and record the padding of a tree.
+func (tb *Builder) WithRandomPadding(r io.Reader) *Builder {
+	tb.paddingSource = r
+	return tb
+}
+
+// WithEmptyLeaf sets the value of absent leaves, e.g. a domain separated hash like H("EMPTY"). It is used instead of
+// the padding node as sibling of the last leaf if the tree has an odd number of leaves. The value is on the level of
+// the leaf hashes, i.e. the leaf hasher is not applied to it, and has to have the size of a leaf hash.
+//
+// In contrast to the padding (see WithRandomPadding and WithPaddingValue), which stands in for missing nodes on every
+// layer above the leaves, the empty leaf only fills the layer of the leaves. It allows proving that a leaf is absent:
+// the proof of the empty slot next to the last leaf validates with WithEmptyLeaf passed to the validator.
+func (tb *Builder) WithEmptyLeaf(value []byte) *Builder {
+	tb.emptyLeaf = slices.Clone(value)
+	return tb
+}
+
 // WithLeafToProve sets a leaf a merkle proof should be generated for.
 // Can be called multiple times. The proof will be generated for the union of all leaves, overwriting previous ones.
 // For an example see the WithLeavesToProve method.
@@ -63,19 +141,100 @@ func (tb *Builder) WithLeavesToProve(leaves map[uint64]struct{}) *Builder {
 	return tb
 }
 
+// WithRetainProvenLeaves configures the tree to keep a copy of the values of all leaves to prove when they are added.
+// This allows the tree to verify its own proof with Tree.Verify. Only the proven leaves are retained, so the memory
+// overhead is proportional to the number of leaves to prove.
+func (tb *Builder) WithRetainProvenLeaves() *Builder {
+	tb.retainLeaves = true
+	return tb
+}
+
+// WithRequireProofTargets configures the tree to treat requesting a proof without any leaves to prove as an error.
+// Tree.RootAndProofErr returns ErrNoProofTargets for such a tree if neither WithLeafToProve, WithLeavesToProve nor
+// WithLeafHashToProve were used. By default a tree without leaves to prove returns a nil proof.
+func (tb *Builder) WithRequireProofTargets() *Builder {
+	tb.requireTargets = true
+	return tb
+}
+
+// WithExpectedLeafCount declares the number of leaves the tree will hold. Tree.RootAndProofErr returns an error
+// wrapping ErrIncompleteTree if the tree does not hold exactly n leaves, which catches generating a proof before all
+// leaves were added. The other methods of the tree are not affected.
+func (tb *Builder) WithExpectedLeafCount(n uint64) *Builder {
+	tb.checkLeafCount = true
+	tb.leafCount = n
+	return tb
+}
+
+// WithLiveRoot configures the tree to keep its current root up to date while leaves are added. This is useful when the
+// root is polled frequently, e.g. to display it while the tree is being built. See Tree.CurrentRoot for details.
+func (tb *Builder) WithLiveRoot() *Builder {
+	tb.liveRoot = true
+	return tb
+}
+
+// WithRecordedCheckpoints configures the tree to record its root every time the number of added leaves reaches a power
+// of two (1, 2, 4, 8, ...). The recorded roots are returned by Tree.Checkpoints. Since the tree is balanced at these
+// points the roots are available without rehashing any layer, which is cheaper than rebuilding the tree for every
+// checkpoint, e.g. to build consistency proofs of a log later on. The memory overhead is one node per checkpoint.
+func (tb *Builder) WithRecordedCheckpoints() *Builder {
+	tb.checkpoints = true
+	return tb
+}
+
+// WithSizeBinding configures the tree to bind its root to the number of leaves: instead of the root of the tree
+// H(count || root) is returned, where count is the number of leaves encoded as 64-bit big-endian integer and H is
+// the hash function of the tree. This prevents a root from being presented as the root of a tree of a different size.
+// Interior nodes and proofs are unaffected. To validate proofs of such a tree use the WithSizeBinding validator option
+// with the number of leaves of the tree.
+func (tb *Builder) WithSizeBinding() *Builder {
+	tb.sizeBinding = true
+	return tb
+}
+
+// WithFullNodeSink sets a function that is called with every interior node of the tree, e.g. to store all nodes in an
+// external index. The height of a node is the number of layers below it (the parents of the leaves have height 1) and
+// the index is the position of the node in its layer counted from the left, relative to the first leaf of the tree.
+// For subtrees (see BuildSubtree) and trees combined from them it is relative to the first leaf of the larger tree.
+// The hash is only valid for the duration of the call and must be copied if it is retained.
+//
+// Nodes are reported as soon as both of their children are known, so the sink is called from Add in order of
+// increasing index for every layer and a node is always reported after its children. Nodes that depend on padding are
+// only final once no more leaves are added, they are reported by the first call to Root or RootAndProof for the
+// current number of leaves. Further calls don't report them again until more leaves are added.
+func (tb *Builder) WithFullNodeSink(fn func(height int, index uint64, hash []byte)) *Builder {
+	tb.nodeSink = fn
+	return tb
+}
+
 // Build constructs the Merkle tree with the specified properties.
 func (tb *Builder) Build() *Tree {
 	if tb.hasher == nil {
 		tb.hasher = Sha256()
 	}
 
-	if tb.leafHasher == nil {
+	provider, ok := tb.hasher.(leafHasherProvider)
+	switch {
+	case ok && (tb.contentHashing || tb.leafHasher == nil):
+		// The hasher requires its own leaf hasher, which hashes the content of the leaves
+		tb.leafHasher = provider.leafHasher()
+	case tb.contentHashing:
+		tb.leafHasher = ContentHasher(tb.hasher)
+	case tb.leafHasher == nil:
 		// If the leaf hasher is not set, use the values as leaves directly and assume they are
 		// the same size as the hasher.
 		tb.leafHasher = ValueLeafs(tb.hasher.Size())
 	}
 
-	indices := slices.Collect(maps.Keys(tb.leavesToProve))
+	indices := make([]uint64, 0, len(tb.leavesToProve))
+	for leaf := range tb.leavesToProve {
+		if leaf >= tb.indexOffset {
+			indices = append(indices, leaf-tb.indexOffset)
+		}
+	}
+	if len(indices) == 0 {
+		indices = nil
+	}
 	slices.Sort(indices)
 	tree := &Tree{
 		hasher:     tb.hasher,
@@ -85,8 +244,48 @@ func (tb *Builder) Build() *Tree {
 		leafBuf: make([]byte, tb.leafHasher.Size()),
 		padding: make([]byte, tb.hasher.Size()),
 
+		emptyLeaf: tb.emptyLeaf,
+
 		minHeight:     tb.minHeight,
+		indexOffset:   tb.indexOffset,
+		provenIndices: indices,
 		leavesToProve: indices,
+
+		targetLeafHash: tb.leafHashToProve,
+		requireTargets: tb.requireTargets,
+
+		checkLeafCount:    tb.checkLeafCount,
+		expectedLeafCount: tb.leafCount,
+
+		layerHasher: tb.layerHasher,
+		nodeSink:    tb.nodeSink,
+		liveRoot:    tb.liveRoot,
+		sizeBinding: tb.sizeBinding,
+	}
+	if tb.paddingSource != nil {
+		if _, err := io.ReadFull(tb.paddingSource, tree.padding); err != nil {
+			panic(fmt.Sprintf("merkle: failed to read padding: %v", err))
+		}
+	}
+	if tb.checkpoints {
+		tree.checkpoints = make(map[uint64][]byte)
+	}
+	if tb.retainLeaves {
+		tree.provenLeaves = make(map[uint64][]byte, len(indices))
+	}
+	return tree
+}
+
+// BuildFromCursor constructs the Merkle tree with the specified properties and adds all leaves returned by the given
+// cursor function. The cursor is called repeatedly until it returns false, the value returned alongside false is
+// ignored. This allows building a tree directly from any ordered source (e.g. an iterator over a key-value store)
+// without coupling the tree to the storage engine.
+//
+// The value returned by the cursor is only used for the duration of the call to Add and can be reused by the cursor.
+func (tb *Builder) BuildFromCursor(next func() ([]byte, bool)) *Tree {
+	tree := tb.Build()
+	for value, ok := next(); ok; value, ok = next() {
+		tree.Add(value)
 	}
 	return tree
 }
@@ -47,3 +47,17 @@ func TestWithLeavesToProveUnion(t *testing.T) {
 		t.Errorf("Expected leaves to prove to be [0, 1, 2], got %v", tree.leavesToProve)
 	}
 }
+
+func TestWithLeafIndexOffset(t *testing.T) {
+	t.Parallel()
+
+	tree := TreeBuilder().
+		WithLeafIndexOffset(8).
+		WithLeafToProve(3).
+		WithLeafToProve(9).
+		WithLeafToProve(12).
+		Build()
+	if !slices.Equal([]uint64{1, 4}, tree.leavesToProve) {
+		t.Errorf("Expected leaves to prove to be [1, 4], got %v", tree.leavesToProve)
+	}
+}
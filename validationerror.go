@@ -0,0 +1,139 @@
+package merkle
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Reason classifies why the validation of a proof failed.
+type Reason int
+
+const (
+	// ReasonOther is the reason of failures that do not fall in any other category, e.g. errors of a LeafChecker.
+	ReasonOther Reason = iota
+
+	// ReasonMismatch indicates that the input was well-formed, but the reconstructed root does not match the given
+	// root. See WithMismatchError.
+	ReasonMismatch
+
+	// ReasonShortProof indicates that the proof does not contain enough nodes to reconstruct the root.
+	ReasonShortProof
+
+	// ReasonNoLeaves indicates that no leaves were given to validate.
+	ReasonNoLeaves
+
+	// ReasonInvalidLength indicates that the root, a leaf or a proof node does not have the expected length.
+	ReasonInvalidLength
+
+	// ReasonRedundantProofNode indicates that a strict proof contains a node that can be derived from the leaves.
+	ReasonRedundantProofNode
+
+	// ReasonNilProofNode indicates that the proof contains a nil node and no padding value was set.
+	ReasonNilProofNode
+
+	// ReasonInvalidLeafIndex indicates that a leaf has an index that is not part of the tree.
+	ReasonInvalidLeafIndex
+
+	// ReasonDuplicateLeaf indicates that a leaf was given more than once.
+	ReasonDuplicateLeaf
+
+	// ReasonUnexpectedDepth indicates that a leaf is not at the depth asserted with WithExpectedDepth.
+	ReasonUnexpectedDepth
+
+	// ReasonMissingNonce indicates that the nonce of a leaf hashed with NoncedLeafHasher is not known.
+	ReasonMissingNonce
+
+	// ReasonUnsupportedAlgorithm indicates that a proof bundle passed to VerifyJSON uses an unknown hash algorithm.
+	ReasonUnsupportedAlgorithm
+)
+
+// String returns the name of the reason.
+func (r Reason) String() string {
+	switch r {
+	case ReasonOther:
+		return "other"
+	case ReasonMismatch:
+		return "mismatch"
+	case ReasonShortProof:
+		return "short proof"
+	case ReasonNoLeaves:
+		return "no leaves"
+	case ReasonInvalidLength:
+		return "invalid length"
+	case ReasonRedundantProofNode:
+		return "redundant proof node"
+	case ReasonNilProofNode:
+		return "nil proof node"
+	case ReasonInvalidLeafIndex:
+		return "invalid leaf index"
+	case ReasonDuplicateLeaf:
+		return "duplicate leaf"
+	case ReasonUnexpectedDepth:
+		return "unexpected depth"
+	case ReasonMissingNonce:
+		return "missing nonce"
+	case ReasonUnsupportedAlgorithm:
+		return "unsupported algorithm"
+	default:
+		return fmt.Sprintf("Reason(%d)", int(r))
+	}
+}
+
+// reasons maps the sentinel errors returned during validation to their reason.
+var reasons = []struct {
+	err    error
+	reason Reason
+}{
+	{ErrRootMismatch, ReasonMismatch},
+	{ErrShortProof, ReasonShortProof},
+	{ErrNoLeaves, ReasonNoLeaves},
+	{ErrInvalidNodeSize, ReasonInvalidLength},
+	{ErrInvalidRootLength, ReasonInvalidLength},
+	{ErrRedundantProofNode, ReasonRedundantProofNode},
+	{ErrNilProofNode, ReasonNilProofNode},
+	{ErrInvalidLeafIndex, ReasonInvalidLeafIndex},
+	{ErrDuplicateLeaf, ReasonDuplicateLeaf},
+	{ErrUnexpectedDepth, ReasonUnexpectedDepth},
+	{ErrMissingNonce, ReasonMissingNonce},
+	{ErrUnsupportedAlgorithm, ReasonUnsupportedAlgorithm},
+}
+
+// ValidationError is the error returned by ValidateProof and related functions when a proof cannot be validated. Use
+// errors.As to obtain it and branch on its Reason. The underlying error is still available with errors.Is, e.g.
+// errors.Is(err, ErrShortProof) holds for a ValidationError with ReasonShortProof.
+//
+// Functions that report the validity of a proof as boolean return false without an error for a well-formed proof that
+// does not match the root. Only ValidateProofDetailed and ComputeRoot, or the other functions with WithMismatchError,
+// report this case as a ValidationError with ReasonMismatch.
+type ValidationError struct {
+	Reason Reason
+	Err    error
+}
+
+// Error returns the message of the underlying error.
+func (e *ValidationError) Error() string {
+	return e.Err.Error()
+}
+
+// Unwrap returns the underlying error.
+func (e *ValidationError) Unwrap() error {
+	return e.Err
+}
+
+// validationError wraps the given error in a ValidationError classified by the sentinel error it wraps. Nil and errors
+// that already are a ValidationError are returned unchanged.
+func validationError(err error) error {
+	if err == nil {
+		return nil
+	}
+	var validationErr *ValidationError
+	if errors.As(err, &validationErr) {
+		return err
+	}
+	for _, r := range reasons {
+		if errors.Is(err, r.err) {
+			return &ValidationError{Reason: r.reason, Err: err}
+		}
+	}
+	return &ValidationError{Reason: ReasonOther, Err: err}
+}
@@ -0,0 +1,281 @@
+package merkle_test
+
+import (
+	"encoding/hex"
+	"errors"
+	"testing"
+
+	"github.com/fasmat/merkle"
+)
+
+func TestValidationError(t *testing.T) {
+	t.Parallel()
+
+	tree := merkle.TreeBuilder().WithLeafToProve(4).Build()
+	for i := range uint64(8) {
+		tree.Add(leaf(i))
+	}
+	root, proof := tree.RootAndProof()
+	leaves := map[uint64][]byte{4: leaf(4)}
+	wrongRoot := make([]byte, len(root))
+	proofMMR := mmrProof(t, 10, 4)
+
+	tt := []struct {
+		name     string
+		validate func() error
+		reason   merkle.Reason
+		err      error
+	}{
+		{
+			name: "mismatch",
+			validate: func() error {
+				return merkle.ValidateProofDetailed(wrongRoot, leaves, proof)
+			},
+			reason: merkle.ReasonMismatch,
+			err:    merkle.ErrRootMismatch,
+		},
+		{
+			name: "short proof",
+			validate: func() error {
+				_, err := merkle.ValidateProof(root, leaves, proof[:2])
+				return err
+			},
+			reason: merkle.ReasonShortProof,
+			err:    merkle.ErrShortProof,
+		},
+		{
+			name: "no leaves",
+			validate: func() error {
+				_, err := merkle.ValidateProof(root, nil, proof)
+				return err
+			},
+			reason: merkle.ReasonNoLeaves,
+			err:    merkle.ErrNoLeaves,
+		},
+		{
+			name: "invalid root length",
+			validate: func() error {
+				_, err := merkle.ValidateProof(root[:16], leaves, proof, merkle.WithNodeSizeCheck())
+				return err
+			},
+			reason: merkle.ReasonInvalidLength,
+			err:    merkle.ErrInvalidRootLength,
+		},
+		{
+			name: "invalid node size",
+			validate: func() error {
+				shortProof := [][]byte{proof[0][:16], proof[1], proof[2]}
+				_, err := merkle.ValidateProof(root, leaves, shortProof, merkle.WithNodeSizeCheck())
+				return err
+			},
+			reason: merkle.ReasonInvalidLength,
+			err:    merkle.ErrInvalidNodeSize,
+		},
+		{
+			name: "redundant proof node",
+			validate: func() error {
+				// proof[0] is leaf 5, which is redundant when leaf 5 is proven as well
+				both := map[uint64][]byte{4: leaf(4), 5: leaf(5)}
+				_, err := merkle.ValidateProof(root, both, proof, merkle.WithStrictProof())
+				return err
+			},
+			reason: merkle.ReasonRedundantProofNode,
+			err:    merkle.ErrRedundantProofNode,
+		},
+		{
+			name: "nil proof node",
+			validate: func() error {
+				_, err := merkle.ValidateProof(root, leaves, [][]byte{nil, proof[1], proof[2]})
+				return err
+			},
+			reason: merkle.ReasonNilProofNode,
+			err:    merkle.ErrNilProofNode,
+		},
+		{
+			name: "invalid leaf index",
+			validate: func() error {
+				_, err := merkle.ValidateProof(root, leaves, proof, merkle.WithLeafIndexOffset(8))
+				return err
+			},
+			reason: merkle.ReasonInvalidLeafIndex,
+			err:    merkle.ErrInvalidLeafIndex,
+		},
+		{
+			name: "duplicate leaf",
+			validate: func() error {
+				ch := make(chan merkle.IndexedLeaf, 2)
+				ch <- merkle.IndexedLeaf{Index: 4, Value: leaf(4)}
+				ch <- merkle.IndexedLeaf{Index: 4, Value: leaf(4)}
+				close(ch)
+				_, err := merkle.ValidateProofChan(root, ch, proof)
+				return err
+			},
+			reason: merkle.ReasonDuplicateLeaf,
+			err:    merkle.ErrDuplicateLeaf,
+		},
+		{
+			name: "reusable validator",
+			validate: func() error {
+				_, err := merkle.NewReusableValidator(root).Validate(4, leaf(4), proof[:2])
+				return err
+			},
+			reason: merkle.ReasonShortProof,
+			err:    merkle.ErrShortProof,
+		},
+		{
+			name: "mismatch error",
+			validate: func() error {
+				_, err := merkle.ValidateProof(wrongRoot, leaves, proof, merkle.WithMismatchError())
+				return err
+			},
+			reason: merkle.ReasonMismatch,
+			err:    merkle.ErrRootMismatch,
+		},
+		{
+			name: "mismatch error with leaf hashes",
+			validate: func() error {
+				_, _, err := merkle.ValidateAndHashLeaves(wrongRoot, leaves, proof, merkle.WithMismatchError())
+				return err
+			},
+			reason: merkle.ReasonMismatch,
+			err:    merkle.ErrRootMismatch,
+		},
+		{
+			name: "mismatch error with audit trail",
+			validate: func() error {
+				_, _, err := merkle.ValidateWithAuditTrail(wrongRoot, 4, leaf(4), proof, merkle.WithMismatchError())
+				return err
+			},
+			reason: merkle.ReasonMismatch,
+			err:    merkle.ErrRootMismatch,
+		},
+		{
+			name: "mismatch error of reusable validator",
+			validate: func() error {
+				_, err := merkle.NewReusableValidator(wrongRoot, merkle.WithMismatchError()).Validate(4, leaf(4), proof)
+				return err
+			},
+			reason: merkle.ReasonMismatch,
+			err:    merkle.ErrRootMismatch,
+		},
+		{
+			name: "mismatch error of batch",
+			validate: func() error {
+				items := []merkle.ProofItem{{Index: 4, Leaf: leaf(4), Proof: proof, Root: wrongRoot}}
+				_, err := merkle.VerifyBatchParallel(items, 1, merkle.WithMismatchError())
+				return err
+			},
+			reason: merkle.ReasonMismatch,
+			err:    merkle.ErrRootMismatch,
+		},
+		{
+			name: "short MMR proof",
+			validate: func() error {
+				_, err := merkle.VerifyMMRProof(wrongRoot, leaf(4), 4, 10, proofMMR[:len(proofMMR)-1])
+				return err
+			},
+			reason: merkle.ReasonShortProof,
+			err:    merkle.ErrShortProof,
+		},
+		{
+			name: "MMR leaf beyond size",
+			validate: func() error {
+				_, err := merkle.VerifyMMRProof(wrongRoot, leaf(10), 10, 10, proofMMR)
+				return err
+			},
+			reason: merkle.ReasonInvalidLeafIndex,
+			err:    merkle.ErrInvalidLeafIndex,
+		},
+		{
+			name: "mismatch error of MMR proof",
+			validate: func() error {
+				_, err := merkle.VerifyMMRProof(wrongRoot, leaf(4), 4, 10, proofMMR, merkle.WithMismatchError())
+				return err
+			},
+			reason: merkle.ReasonMismatch,
+			err:    merkle.ErrRootMismatch,
+		},
+		{
+			name: "mismatch error of same root",
+			validate: func() error {
+				bundleA := merkle.ProofBundle{Leaves: leaves, Proof: proof}
+				bundleB := merkle.ProofBundle{Leaves: map[uint64][]byte{4: leaf(5)}, Proof: proof}
+				_, err := merkle.SameRoot(bundleA, bundleB, merkle.WithMismatchError())
+				return err
+			},
+			reason: merkle.ReasonMismatch,
+			err:    merkle.ErrRootMismatch,
+		},
+		{
+			name: "unsupported algorithm",
+			validate: func() error {
+				_, err := merkle.VerifyJSON([]byte(`{"root": "00", "leaves": {"4": "04"}, "algorithm": "md5"}`))
+				return err
+			},
+			reason: merkle.ReasonUnsupportedAlgorithm,
+			err:    merkle.ErrUnsupportedAlgorithm,
+		},
+		{
+			name: "malformed bundle",
+			validate: func() error {
+				_, err := merkle.VerifyJSON([]byte(`{"root": "zz", "leaves": {"4": "04"}}`))
+				return err
+			},
+			reason: merkle.ReasonOther,
+			err:    hex.InvalidByteError('z'),
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			err := tc.validate()
+			var validationErr *merkle.ValidationError
+			if !errors.As(err, &validationErr) {
+				t.Fatalf("Expected a ValidationError, got %v", err)
+			}
+			if validationErr.Reason != tc.reason {
+				t.Errorf("Expected reason %v, got %v", tc.reason, validationErr.Reason)
+			}
+			if !errors.Is(err, tc.err) {
+				t.Errorf("Expected error %v, got %v", tc.err, err)
+			}
+		})
+	}
+}
+
+func TestValidationErrorValid(t *testing.T) {
+	t.Parallel()
+
+	tree := merkle.TreeBuilder().WithLeafToProve(4).Build()
+	for i := range uint64(8) {
+		tree.Add(leaf(i))
+	}
+	root, proof := tree.RootAndProof()
+	leaves := map[uint64][]byte{4: leaf(4)}
+
+	if err := merkle.ValidateProofDetailed(root, leaves, proof); err != nil {
+		t.Errorf("Expected no error, got %v", err)
+	}
+
+	// a mismatch is not an error for ValidateProof
+	valid, err := merkle.ValidateProof(make([]byte, len(root)), leaves, proof)
+	if err != nil {
+		t.Errorf("Expected no error, got %v", err)
+	}
+	if valid {
+		t.Error("Expected proof to be invalid")
+	}
+}
+
+func TestReasonString(t *testing.T) {
+	t.Parallel()
+
+	if s := merkle.ReasonMismatch.String(); s != "mismatch" {
+		t.Errorf("Expected %q, got %q", "mismatch", s)
+	}
+	if s := merkle.Reason(100).String(); s != "Reason(100)" {
+		t.Errorf("Expected %q, got %q", "Reason(100)", s)
+	}
+}
@@ -3,10 +3,11 @@ package merkle
 import (
 	"bytes"
 	"errors"
-	"maps"
+	"fmt"
 	"math"
 	"math/bits"
 	"slices"
+	"sync"
 )
 
 var (
@@ -15,27 +16,114 @@ var (
 
 	// ErrNoLeaves is returned when there are no leaves to prove.
 	ErrNoLeaves = errors.New("no leaves to prove")
+
+	// ErrRedundantProofNode is returned in strict mode when the proof contains a node that can be derived from the
+	// proven leaves.
+	ErrRedundantProofNode = errors.New("proof contains redundant node")
+
+	// ErrInvalidNodeSize is returned when size checks are enabled with WithNodeSizeCheck and a leaf or proof node does
+	// not have the expected size.
+	ErrInvalidNodeSize = errors.New("invalid node size")
+
+	// ErrInvalidRootLength is returned when size checks are enabled with WithNodeSizeCheck and the root to validate
+	// against does not have the size of a node.
+	ErrInvalidRootLength = errors.New("invalid root length")
+
+	// ErrNilProofNode is returned when the proof contains a nil node and no padding value was set with
+	// WithPaddingValue.
+	ErrNilProofNode = errors.New("proof contains nil node")
+
+	// ErrUnexpectedDepth is returned when a depth was asserted with WithExpectedDepth and the leaf is not at that depth
+	// of the reconstructed tree.
+	ErrUnexpectedDepth = errors.New("unexpected leaf depth")
+
+	// ErrRootMismatch is returned by ValidateProofDetailed, or other validating functions with WithMismatchError, when
+	// the reconstructed root does not match the given root.
+	ErrRootMismatch = errors.New("root mismatch")
 )
 
 type validatorOpts struct {
-	hasher     Hasher
-	leafHasher LeafHasher
+	hasher      Hasher
+	leafHasher  LeafHasher
+	layerHasher func(height uint64) Hasher
+	strict      bool
+	checkSizes  bool
+	copyLeaves  bool
+
+	mismatchError bool // Indicates if a root mismatch is reported as ValidationError instead of (false, nil)
+	skipRedundant bool // Indicates if proof nodes at the positions of derived siblings are skipped, see checkRedundant
+
+	duplicatePadding bool   // Indicates if missing right siblings are replaced by duplicating the left sibling
+	treeSize         uint64 // The number of leaves of the tree, only used with duplicate padding
+
+	maxExtraPadding int
+	padding         []byte // The value of nil proof nodes and extra padding layers, zeros if nil
+
+	leafHashes  map[uint64][]byte // Collects the hashes of the proven leaves if set, see ValidateAndHashLeaves
+	indexOffset uint64            // The global index of the first leaf of the tree
+
+	sizeBinding bool   // Indicates if the root is bound to the number of leaves of the tree
+	leafCount   uint64 // The number of leaves the root is bound to, only used with size binding
+
+	expectedDepths []expectedDepth // The depths asserted with WithExpectedDepth
+	rootInProof    bool            // Indicates if the last node of the proof is the root of the tree
+
+	leafNonces map[uint64][]byte // The nonces of the proven leaves set with WithLeafNonces
+	emptyLeaf  []byte            // The value of absent leaves set with WithEmptyLeaf
+
+	leafIndexBase uint64 // Added to the index of a leaf when it is hashed, see VerifyWithinSubtree
 }
 
+// expectedDepth is the depth asserted for a leaf with WithExpectedDepth.
+type expectedDepth struct {
+	index uint64
+	depth int
+}
+
+// The default hashers are shared by all validations that do not configure their own, so the hash instances pooled
+// by them are reused across calls.
+var (
+	defaultHasher     = Sha256()
+	defaultLeafHasher = ValueLeafs(defaultHasher.Size())
+)
+
 func (v *validatorOpts) Hasher() Hasher {
 	if v.hasher == nil {
-		v.hasher = Sha256()
+		v.hasher = defaultHasher
 	}
 	return v.hasher
 }
 
 func (v *validatorOpts) LeafHasher() LeafHasher {
-	if v.leafHasher == nil {
+	provider, ok := v.Hasher().(leafHasherProvider)
+	switch {
+	case v.leafHasher != nil:
+	case v.leafNonces != nil:
+		v.leafHasher = NoncedLeafHasher(v.Hasher(), v.leafNonces)
+	case v.Hasher() == defaultHasher:
+		v.leafHasher = defaultLeafHasher
+	case ok:
+		v.leafHasher = provider.leafHasher()
+	default:
 		v.leafHasher = ValueLeafs(v.Hasher().Size())
 	}
 	return v.leafHasher
 }
 
+// BoundRoot returns the given root bound to the leaf count if size binding is enabled, otherwise the root is returned
+// unchanged. The given root is not modified.
+func (v *validatorOpts) BoundRoot(root []byte) []byte {
+	if !v.sizeBinding || root == nil {
+		return root
+	}
+	return bindSize(v.Hasher(), nil, v.leafCount, root)
+}
+
+// HasherAt returns the hasher used to calculate the nodes at the given height.
+func (v *validatorOpts) HasherAt(height uint64) Hasher {
+	return hasherAt(v.layerHasher, height, v.Hasher())
+}
+
 // ValidatorOpt is a functional option for configuring the validator.
 type ValidatorOpt func(*validatorOpts)
 
@@ -58,48 +146,802 @@ func WithLeafHasher(h LeafHasher) ValidatorOpt {
 	}
 }
 
-// ValidateProof validates a Merkle tree proof against the provided root and leaves.
+// WithLayerHasher sets a function that returns the hash function used for the nodes at the given height, see
+// Builder.WithLayerHasher. The hasher set with WithHasher is used for the padding and has to be of the same size as
+// all hashers returned by fn.
+func WithLayerHasher(fn func(height uint64) Hasher) ValidatorOpt {
+	return func(opts *validatorOpts) {
+		opts.layerHasher = fn
+	}
+}
+
+// WithStrictProof enables strict validation of the proof. In strict mode the proof is rejected with
+// ErrRedundantProofNode if it contains a node at a position that can be derived from the proven leaves, i.e. a copy of
+// a sibling whose subtree contains a proven leaf. A well-formed proof never contains such a node, since the prover omits
+// every node that can be derived from the proven leaves.
+//
+// The check is done while the root is reconstructed: when a sibling was derived from the leaves, the next proof node
+// must not be a copy of it. The proof node that legitimately follows a derived sibling belongs to a higher layer, so it
+// can only be equal to the sibling if it is a padding node, which is the same on every layer (e.g. for a tree with
+// zero-valued leaves and a minimum height). Such a node is only reported as redundant if the proof does not reproduce
+// the root, but does so without the node, so valid proofs are never rejected. ComputeRoot can only resolve this case
+// with WithRootInProof.
+func WithStrictProof() ValidatorOpt {
+	return func(opts *validatorOpts) {
+		opts.strict = true
+	}
+}
+
+// WithMismatchError configures ValidateProof and the other functions that report the validity of a proof as boolean
+// (ValidateAndHashLeaves, ValidateWithAuditTrail, ValidateProofChan, ReusableValidator.Validate, VerifyBatchParallel,
+// ...) to return a *ValidationError with ReasonMismatch wrapping ErrRootMismatch instead of false without an error if
+// a well-formed proof does not match the root. This way every failure is classified by a ValidationError, like with
+// ValidateProofDetailed.
+func WithMismatchError() ValidatorOpt {
+	return func(opts *validatorOpts) {
+		opts.mismatchError = true
+	}
+}
+
+// WithDuplicatePadding configures the validator for trees that are padded by duplicating the last node of an
+// unbalanced layer (as done for Bitcoin transaction trees) instead of using a padding node. A node without right
+// sibling is hashed with itself and such siblings are not part of the proof. Since the validator cannot tell from the
+// proof where the tree ends, the number of leaves of the tree has to be passed. Leaves with an index at or beyond the
+// tree size are rejected with an error wrapping ErrInvalidLeafIndex, since the duplicated node is not a leaf of the tree
+// (see CVE-2012-2459).
+//
+// Duplicate padding is not supported for sequential leaf hashers.
+func WithDuplicatePadding(treeSize uint64) ValidatorOpt {
+	return func(opts *validatorOpts) {
+		opts.duplicatePadding = true
+		opts.treeSize = treeSize
+	}
+}
+
+// WithCopyLeaves configures the validator to work on a deep copy of the leaves (see CloneLeaves) instead of the given
+// map. The validator never modifies the leaves, but with this option the caller is free to modify the map and its
+// values after ValidateProof (or the other validating functions) returned, e.g. to reuse it for the next validation.
+// The copy is made by the validating function itself, so the leaves must not be modified concurrently to the call.
+func WithCopyLeaves() ValidatorOpt {
+	return func(opts *validatorOpts) {
+		opts.copyLeaves = true
+	}
+}
+
+// WithNodeSizeCheck enables a check of the sizes of the leaves and proof nodes before the root is reconstructed. Every
+// proof node has to be of the size of the hasher and if the leaves are used as is (see ValueLeafs) every leaf as well.
+// Otherwise an error wrapping ErrInvalidNodeSize is returned that describes the first mismatch.
+//
+// The root to validate against has to be of the size of the hasher as well, or of the size of the leaf hasher if the
+// tree consists of a single leaf. Otherwise an error wrapping ErrInvalidRootLength is returned.
+//
+// This helps to detect a mismatch between the configuration of the prover and the validator (e.g. different hash
+// functions) that would otherwise only result in an invalid proof. It cannot be used with leaves of varying size or
+// hashers that produce nodes of varying size.
+func WithNodeSizeCheck() ValidatorOpt {
+	return func(opts *validatorOpts) {
+		opts.checkSizes = true
+	}
+}
+
+// WithMaxExtraPadding allows the validator to accept roots of trees that were built with a minimum height (see
+// Builder.WithMinHeight) when the proof does not contain the padding nodes above the natural root of the tree. If the
+// reconstructed root does not match, up to n additional layers are added on top of it by hashing it with the padding
+// node (all zeros) and each resulting root is compared against the expected root.
+//
+// Proofs produced by Tree.RootAndProof always contain these padding nodes, so this option is only needed for proofs
+// that were stripped of them. n should be kept small, since every extra layer costs an additional hash when the proof
+// is invalid.
+func WithMaxExtraPadding(n int) ValidatorOpt {
+	return func(opts *validatorOpts) {
+		opts.maxExtraPadding = n
+	}
+}
+
+// WithSizeBinding validates proofs against roots of trees built with Builder.WithSizeBinding. The reconstructed root is
+// bound to the given number of leaves before it is compared, so the proof is only valid if count is the number of
+// leaves of the tree.
+func WithSizeBinding(count uint64) ValidatorOpt {
+	return func(opts *validatorOpts) {
+		opts.sizeBinding = true
+		opts.leafCount = count
+	}
+}
+
+// WithPaddingValue sets the value of the padding node of the tree, e.g. the value returned by Tree.Padding for trees
+// built with Builder.WithRandomPadding. Nil nodes in the proof are replaced by the padding value, which allows
+// validating proofs of serializers that encode padding nodes as nil. Without this option a proof containing a nil node
+// is rejected with ErrNilProofNode. The padding value is also used for the layers added with WithMaxExtraPadding.
+func WithPaddingValue(padding []byte) ValidatorOpt {
+	return func(opts *validatorOpts) {
+		opts.padding = padding
+	}
+}
+
+// WithLeafIndexOffset validates proofs of trees built with Builder.WithLeafIndexOffset using global leaf indices: the
+// keys of the leaves passed to the validator are interpreted as global indices and the given offset (the global index
+// of the first leaf of the tree) is subtracted from them. The proof is validated against the root of the tree (e.g.
+// the shard), not of the larger tree. Leaves with an index lower than the offset are rejected with an error wrapping
+// ErrInvalidLeafIndex.
+func WithLeafIndexOffset(offset uint64) ValidatorOpt {
+	return func(opts *validatorOpts) {
+		opts.indexOffset = offset
+	}
+}
+
+// WithLeafNonces validates proofs of trees built with NoncedLeafHasher: the proven leaves are hashed with the nonce of
+// their index in the given map using the hasher of the validator, i.e. this is a shorthand for
+// WithLeafHasher(NoncedLeafHasher(hasher, nonces)) that can be passed before or after WithHasher. A leaf hasher set
+// with WithLeafHasher takes precedence. If the nonce of a proven leaf is missing an error wrapping ErrMissingNonce is
+// returned.
+func WithLeafNonces(nonces map[uint64][]byte) ValidatorOpt {
+	return func(opts *validatorOpts) {
+		opts.leafNonces = nonces
+	}
+}
+
+// WithEmptyLeaf validates proofs of trees built with Builder.WithEmptyLeaf. Nil values in the leaves passed to the
+// validator are treated as absent leaves: instead of hashing them with the leaf hasher the given empty leaf is used as
+// their node. This allows validating that the slot next to the last leaf of a tree with an odd number of leaves is
+// empty, by passing a nil leaf for its index together with the proof of the last leaf where the first node is
+// replaced by the last leaf (hashed with the leaf hasher if the tree uses one).
+//
+// Unlike WithPaddingValue, which sets the value of padding nodes on all layers and of nil proof nodes, the empty leaf
+// is only used for leaves.
+func WithEmptyLeaf(value []byte) ValidatorOpt {
+	return func(opts *validatorOpts) {
+		opts.emptyLeaf = value
+	}
+}
+
+// WithExpectedDepth asserts that the leaf with the given index is at the given depth of the tree, i.e. that exactly
+// depth hashing steps were needed to reconstruct the root from the leaf. This rejects proofs that were crafted for a
+// tree of a different shape with an error wrapping ErrUnexpectedDepth, which is also returned if the leaf is not one
+// of the validated leaves. The option can be passed multiple times to assert the depth of several leaves.
+//
+// Missing siblings are padded, so all leaves of a tree are at the same depth: the height of the tree (e.g. 4 for a
+// tree with 10 leaves) or its minimum height if that is higher. Layers added with WithMaxExtraPadding are not counted.
+func WithExpectedDepth(index uint64, depth int) ValidatorOpt {
+	return func(opts *validatorOpts) {
+		opts.expectedDepths = append(opts.expectedDepths, expectedDepth{index: index, depth: depth})
+	}
+}
+
+// WithRootInProof validates self-certifying proofs that have the root of the tree appended as last node. The last node
+// is removed from the proof and the root reconstructed from the leaves and the remaining proof is compared against it.
+//
+// If a root is passed to the validating function as well, it takes precedence: the proof is only valid if the root
+// in the proof equals the given root. If the given root is nil only the root in the proof is used, which shows that
+// the proof is consistent, but not that it belongs to a trusted root. ComputeRoot returns an error wrapping
+// ErrRootMismatch if the reconstructed root differs from the root in the proof.
+func WithRootInProof() ValidatorOpt {
+	return func(opts *validatorOpts) {
+		opts.rootInProof = true
+	}
+}
+
+// ValidateProof validates a Merkle tree proof against the provided root and leaves. If the input is malformed, e.g.
+// the proof is too short, a *ValidationError classifying the failure is returned. A well-formed proof that does not
+// match the root is reported as false without an error, use WithMismatchError or ValidateProofDetailed to receive a
+// ValidationError with ReasonMismatch instead.
 func ValidateProof(root []byte, leaves map[uint64][]byte, proof [][]byte, opts ...ValidatorOpt) (bool, error) {
+	validatorOpts := parseValidatorOpts(opts)
+	root, proof, rootMatch, err := rootFromProof(root, proof, validatorOpts)
+	if err != nil {
+		return false, validationError(err)
+	}
+	if err := checkRootLength(root, leaves, proof, validatorOpts); err != nil {
+		return false, validationError(err)
+	}
+	calculatedRoot, height, err := reconstructRoot(leaves, proof, validatorOpts)
+	if err != nil {
+		return false, validationError(err)
+	}
+	valid := rootMatch && matchRoot(root, calculatedRoot, height, validatorOpts)
+	if err := checkRedundant(valid || !rootMatch, root, leaves, proof, validatorOpts); err != nil {
+		return false, validationError(err)
+	}
+	return checkMatch(valid, validatorOpts)
+}
+
+// ValidateAndHashLeaves validates a Merkle tree proof like ValidateProof and additionally returns the hashes of the
+// proven leaves computed by the leaf hasher during validation, e.g. to cross-check them against an external index.
+// The hashes are returned even if the proof is invalid, as long as no error occurred.
+func ValidateAndHashLeaves(
+	root []byte,
+	leaves map[uint64][]byte,
+	proof [][]byte,
+	opts ...ValidatorOpt,
+) (bool, map[uint64][]byte, error) {
+	validatorOpts := parseValidatorOpts(opts)
+	root, proof, rootMatch, err := rootFromProof(root, proof, validatorOpts)
+	if err != nil {
+		return false, nil, validationError(err)
+	}
+	if err := checkRootLength(root, leaves, proof, validatorOpts); err != nil {
+		return false, nil, validationError(err)
+	}
+	validatorOpts.leafHashes = make(map[uint64][]byte, len(leaves))
+	calculatedRoot, height, err := reconstructRoot(leaves, proof, validatorOpts)
+	if err != nil {
+		return false, nil, validationError(err)
+	}
+	valid := rootMatch && matchRoot(root, calculatedRoot, height, validatorOpts)
+	if err := checkRedundant(valid || !rootMatch, root, leaves, proof, validatorOpts); err != nil {
+		return false, nil, validationError(err)
+	}
+	valid, err = checkMatch(valid, validatorOpts)
+	return valid, validatorOpts.leafHashes, err
+}
+
+// ValidateWithAuditTrail validates the proof of a single leaf like ValidateProof and additionally returns the audit
+// trail of the validation: every node computed on the path from the leaf to the root, starting with the hash of the
+// leaf and ending with the reconstructed root. The root is the one reconstructed from the proof, before it is bound to
+// the tree size (WithSizeBinding) or extended by padding layers (WithMaxExtraPadding). The trail is returned even if
+// the proof is invalid, as long as no error occurred.
+func ValidateWithAuditTrail(
+	root []byte,
+	index uint64,
+	leaf []byte,
+	proof [][]byte,
+	opts ...ValidatorOpt,
+) (bool, [][]byte, error) {
+	validatorOpts := parseValidatorOpts(opts)
+	root, proof, rootMatch, err := rootFromProof(root, proof, validatorOpts)
+	if err != nil {
+		return false, nil, validationError(err)
+	}
+	leaves := map[uint64][]byte{index: leaf}
+	if err := checkRootLength(root, leaves, proof, validatorOpts); err != nil {
+		return false, nil, validationError(err)
+	}
+	leaves, err = shiftLeaves(leaves, validatorOpts.indexOffset, validatorOpts.copyLeaves)
+	if err != nil {
+		return false, nil, validationError(err)
+	}
+
+	v := newValidator(validatorOpts)
+	v.trail = make([][]byte, 0, len(proof)+1)
+	index -= validatorOpts.indexOffset
+	buf := make([]byte, 0, v.leafHasher.Size())
+	calculatedRoot, height, err := v.reconstruct(leaves, []uint64{index}, proof, validatorOpts, buf)
+	if err != nil {
+		return false, nil, validationError(err)
+	}
+	valid, err := checkMatch(rootMatch && matchRoot(root, calculatedRoot, height, validatorOpts), validatorOpts)
+	return valid, v.trail, err
+}
+
+// VerifyWithinSubtree validates the proof of a single leaf against the root of the subtree with the given height that
+// contains it, e.g. a subtree root cached by a client that only syncs part of the tree. The index is the index of the
+// leaf in the tree, the index of the first leaf of the subtree is applied as offset to get the index within the
+// subtree. The proof is the path from the leaf to the subtree root: only its first subtreeHeight nodes are used, so
+// the full proof of the leaf in the tree can be passed as well.
+//
+// The options of ValidateProof are supported. Leaf hashers that depend on the index of the leaf (e.g. WithLeafNonces
+// or CounterLeafHasher) are applied with the index of the leaf in the tree, as when the tree was built. Options that
+// depend on the root of the whole tree (e.g. WithSizeBinding) are applied to the subtree root instead. A proof with
+// less than subtreeHeight nodes is rejected with an error wrapping ErrShortProof.
+func VerifyWithinSubtree(
+	subtreeRoot []byte,
+	subtreeHeight uint64,
+	index uint64,
+	leaf []byte,
+	proof [][]byte,
+	opts ...ValidatorOpt,
+) (bool, error) {
+	if uint64(len(proof)) < subtreeHeight {
+		return false, validationError(fmt.Errorf("%w: proof has %d nodes, subtree has height %d",
+			ErrShortProof, len(proof), subtreeHeight))
+	}
+	offset := parseValidatorOpts(opts).indexOffset
+	if index < offset {
+		return false, validationError(fmt.Errorf("%w: leaf %d is below the index offset %d",
+			ErrInvalidLeafIndex, index, offset))
+	}
+	var start uint64 // index of the first leaf of the subtree in the tree
+	if subtreeHeight < 64 {
+		start = (index - offset) &^ (1<<subtreeHeight - 1)
+	}
+	opts = append(slices.Clone(opts), WithLeafIndexOffset(offset+start), func(opts *validatorOpts) {
+		opts.leafIndexBase = start
+	})
+	return ValidateProof(subtreeRoot, map[uint64][]byte{index: leaf}, proof[:subtreeHeight], opts...)
+}
+
+// IndexedLeaf is the value of a leaf together with its index in the tree.
+type IndexedLeaf struct {
+	Index uint64
+	Value []byte
+}
+
+// ValidateProofChan validates a Merkle tree proof like ValidateProof, but receives the proven leaves from the given
+// channel. The leaves can be sent in any order, the channel is drained until it is closed before the proof is
+// validated. This allows concurrent producers to send the leaves they are responsible for without collecting them
+// first.
+//
+// If a leaf with the same index is received more than once an error wrapping ErrDuplicateLeaf is returned. The channel
+// is still drained in this case, so producers are never blocked.
+func ValidateProofChan(root []byte, leaves <-chan IndexedLeaf, proof [][]byte, opts ...ValidatorOpt) (bool, error) {
+	leafMap := make(map[uint64][]byte)
+	var err error
+	for leaf := range leaves {
+		if _, ok := leafMap[leaf.Index]; ok && err == nil {
+			err = fmt.Errorf("%w: index %d", ErrDuplicateLeaf, leaf.Index)
+		}
+		leafMap[leaf.Index] = leaf.Value
+	}
+	if err != nil {
+		return false, validationError(err)
+	}
+	return ValidateProof(root, leafMap, proof, opts...)
+}
+
+// ComputeRoot reconstructs the root of a Merkle tree from the provided leaves and proof without comparing it to a
+// known root. The returned root is only trustworthy if it is compared against a root obtained from a trusted source.
+func ComputeRoot(leaves map[uint64][]byte, proof [][]byte, opts ...ValidatorOpt) ([]byte, error) {
+	validatorOpts := parseValidatorOpts(opts)
+	claimedRoot, proof, _, err := rootFromProof(nil, proof, validatorOpts)
+	if err != nil {
+		return nil, validationError(err)
+	}
+	root, _, err := reconstructRoot(leaves, proof, validatorOpts)
+	if err != nil {
+		return validatorOpts.BoundRoot(root), validationError(err)
+	}
+	root = validatorOpts.BoundRoot(root)
+	if validatorOpts.rootInProof && !bytes.Equal(claimedRoot, root) {
+		if err := checkRedundant(false, claimedRoot, leaves, proof, validatorOpts); err != nil {
+			return root, validationError(err)
+		}
+		return root, validationError(fmt.Errorf("%w: root in proof: %s", ErrRootMismatch,
+			CompareRoots(claimedRoot, root)))
+	}
+	return root, nil
+}
+
+// ValidateProofDetailed validates a Merkle tree proof against the provided root and leaves like ValidateProof, but
+// returns an error describing the failure instead of a boolean. If the proof is valid nil is returned. Every failure is
+// reported as a *ValidationError: if the root reconstructed from the leaves and proof does not match the provided root
+// its Reason is ReasonMismatch and it wraps ErrRootMismatch with a description of the difference between the two roots
+// (see CompareRoots).
+func ValidateProofDetailed(root []byte, leaves map[uint64][]byte, proof [][]byte, opts ...ValidatorOpt) error {
+	validatorOpts := parseValidatorOpts(opts)
+	givenRoot := root
+	root, proof, rootMatch, err := rootFromProof(root, proof, validatorOpts)
+	if err != nil {
+		return validationError(err)
+	}
+	if err := checkRootLength(root, leaves, proof, validatorOpts); err != nil {
+		return validationError(err)
+	}
+	calculatedRoot, height, err := reconstructRoot(leaves, proof, validatorOpts)
+	if err != nil {
+		return validationError(err)
+	}
+	if !rootMatch {
+		return validationError(fmt.Errorf("%w: root in proof: %s", ErrRootMismatch, CompareRoots(givenRoot, root)))
+	}
+	if !matchRoot(root, slices.Clone(calculatedRoot), height, validatorOpts) {
+		if err := checkRedundant(false, root, leaves, proof, validatorOpts); err != nil {
+			return validationError(err)
+		}
+		return validationError(fmt.Errorf("%w: %s", ErrRootMismatch,
+			CompareRoots(root, validatorOpts.BoundRoot(calculatedRoot))))
+	}
+	return nil
+}
+
+// rootFromProof removes the root appended to the proof if WithRootInProof is set and returns it as the root to validate
+// against together with the remaining proof. If the given root is not nil it takes precedence: the returned flag
+// indicates if it matches the root in the proof. Without WithRootInProof the given root and proof are returned as is.
+func rootFromProof(root []byte, proof [][]byte, validatorOpts *validatorOpts) ([]byte, [][]byte, bool, error) {
+	if !validatorOpts.rootInProof {
+		return root, proof, true, nil
+	}
+	if len(proof) == 0 {
+		return nil, nil, false, fmt.Errorf("%w: proof does not contain the root", ErrShortProof)
+	}
+	claimedRoot := proof[len(proof)-1]
+	if claimedRoot == nil {
+		return nil, nil, false, fmt.Errorf("%w: root at index %d", ErrNilProofNode, len(proof)-1)
+	}
+	proof = proof[:len(proof)-1]
+	if root == nil {
+		return claimedRoot, proof, true, nil
+	}
+	return claimedRoot, proof, bytes.Equal(root, claimedRoot), nil
+}
+
+// CloneLeaves returns a deep copy of the given leaves, i.e. modifying the returned map or its values does not affect
+// the given leaves.
+func CloneLeaves(leaves map[uint64][]byte) map[uint64][]byte {
+	if leaves == nil {
+		return nil
+	}
+	clone := make(map[uint64][]byte, len(leaves))
+	for idx, leaf := range leaves {
+		clone[idx] = slices.Clone(leaf)
+	}
+	return clone
+}
+
+// CompareRoots returns a human-readable description of the difference between the expected and the computed root.
+// It reports the index of the first differing byte and both roots in hex. If the roots are equal an empty string is
+// returned.
+func CompareRoots(expected, computed []byte) string {
+	if bytes.Equal(expected, computed) {
+		return ""
+	}
+
+	idx := 0
+	for idx < len(expected) && idx < len(computed) && expected[idx] == computed[idx] {
+		idx++
+	}
+	if len(expected) != len(computed) {
+		return fmt.Sprintf(
+			"roots differ in length (expected %d bytes, computed %d bytes), first difference at byte %d: "+
+				"expected %x, computed %x",
+			len(expected), len(computed), idx, expected, computed,
+		)
+	}
+	return fmt.Sprintf("roots differ at byte %d: expected %x, computed %x", idx, expected, computed)
+}
+
+// checkRedundant is called in strict mode with the result of the comparison of the reconstructed root with the given
+// root to resolve copies of derived siblings that are equal to the padding (see checkCopy). If they do not match, the
+// root is reconstructed again while skipping the proof node that follows every sibling
+// derived from the proven leaves if it is equal to that sibling, i.e. if it is a copy of the sibling at its position in
+// the proof. If this reproduces the given root the proof contains nodes at positions that can be derived from the
+// leaves and an error wrapping ErrRedundantProofNode is returned.
+func checkRedundant(
+	valid bool,
+	root []byte,
+	leaves map[uint64][]byte,
+	proof [][]byte,
+	validatorOpts *validatorOpts,
+) error {
+	if valid || !validatorOpts.strict {
+		return nil
+	}
+
+	opts := *validatorOpts
+	opts.skipRedundant = true // also disables checkCopy
+	opts.leafHashes = nil
+	opts.expectedDepths = nil
+	calculatedRoot, height, err := reconstructRoot(leaves, proof, &opts)
+	if err != nil || !matchRoot(root, calculatedRoot, height, &opts) {
+		return nil
+	}
+	return ErrRedundantProofNode
+}
+
+// checkMatch returns the result of a validation that reconstructed the root without error. If the root does not match
+// and WithMismatchError is set, a ValidationError wrapping ErrRootMismatch is returned.
+func checkMatch(valid bool, validatorOpts *validatorOpts) (bool, error) {
+	if valid || !validatorOpts.mismatchError {
+		return valid, nil
+	}
+	return false, validationError(fmt.Errorf("%w: reconstructed root differs from the given root", ErrRootMismatch))
+}
+
+// matchRoot compares the expected root with the calculated root. If configured it folds up to maxExtraPadding padding
+// layers on top of the calculated root, which has the given height, until it matches. The calculated root is modified
+// in the process.
+func matchRoot(root, calculatedRoot []byte, height uint64, validatorOpts *validatorOpts) bool {
+	if bytes.Equal(root, validatorOpts.BoundRoot(calculatedRoot)) {
+		return true
+	}
+	if validatorOpts.maxExtraPadding <= 0 {
+		return false
+	}
+
+	padding := validatorOpts.padding
+	if padding == nil {
+		padding = make([]byte, validatorOpts.Hasher().Size())
+	}
+	for i := range uint64(validatorOpts.maxExtraPadding) {
+		hasher := validatorOpts.HasherAt(height + i + 1)
+		calculatedRoot = hasher.Hash(calculatedRoot, calculatedRoot, padding)
+		if bytes.Equal(root, validatorOpts.BoundRoot(calculatedRoot)) {
+			return true
+		}
+	}
+	return false
+}
+
+func parseValidatorOpts(opts []ValidatorOpt) *validatorOpts {
 	validatorOpts := &validatorOpts{}
 	for _, opt := range opts {
 		opt(validatorOpts)
 	}
+	return validatorOpts
+}
 
+// reconstructRoot calculates the root of the Merkle tree from the provided leaves and proof. It returns the root and
+// its height.
+func reconstructRoot(leaves map[uint64][]byte, proof [][]byte, validatorOpts *validatorOpts) ([]byte, uint64, error) {
 	if len(leaves) == 0 {
-		return false, ErrNoLeaves
+		return nil, 0, ErrNoLeaves
+	}
+	switch {
+	case validatorOpts.indexOffset != 0:
+		// shifting the leaves creates a new map, so they don't have to be copied
+		shifted, err := shiftLeaves(leaves, validatorOpts.indexOffset, validatorOpts.copyLeaves)
+		if err != nil {
+			return nil, 0, err
+		}
+		leaves = shifted
+	case validatorOpts.copyLeaves:
+		leaves = CloneLeaves(leaves)
 	}
 
-	indices := slices.Collect(maps.Keys(leaves))
+	// The cast is safe, since we control the pool
+	v := validatorPool.Get().(*validator)
+	defer validatorPool.Put(v)
+	defer v.Reset()
+	v.configure(validatorOpts)
+
+	indices := v.indexBuf[:0]
+	for idx := range leaves {
+		indices = append(indices, idx)
+	}
 	slices.Sort(indices)
+	v.indexBuf = indices
+	return v.reconstruct(leaves, indices, proof, validatorOpts, make([]byte, 0, v.leafHasher.Size()))
+}
+
+// shiftLeaves returns the given leaves with the offset subtracted from their indices. The values are copied if
+// copyValues is set. An error wrapping ErrInvalidLeafIndex is returned if an index is lower than the offset.
+func shiftLeaves(leaves map[uint64][]byte, offset uint64, copyValues bool) (map[uint64][]byte, error) {
+	shifted := make(map[uint64][]byte, len(leaves))
+	for idx, leaf := range leaves {
+		if idx < offset {
+			return nil, fmt.Errorf("%w: leaf %d is below the index offset %d", ErrInvalidLeafIndex, idx, offset)
+		}
+		if copyValues {
+			leaf = slices.Clone(leaf)
+		}
+		shifted[idx-offset] = leaf
+	}
+	return shifted, nil
+}
+
+// validatorPool holds validators that are reused by ValidateProof and related functions to avoid allocating the
+// validator and its buffers on every call.
+var validatorPool = sync.Pool{
+	New: func() any {
+		return &validator{}
+	},
+}
 
-	v := &validator{
-		hasher:     validatorOpts.Hasher(),
-		leafHasher: validatorOpts.LeafHasher(),
+func newValidator(validatorOpts *validatorOpts) *validator {
+	v := &validator{}
+	v.configure(validatorOpts)
+	return v
+}
+
+// configure sets the hashers and padding of the validator from the given options.
+func (v *validator) configure(validatorOpts *validatorOpts) {
+	v.hasher = validatorOpts.Hasher()
+	v.leafHasher = validatorOpts.LeafHasher()
+	v.layerHasher = validatorOpts.layerHasher
+
+	v.duplicatePadding = validatorOpts.duplicatePadding
+	v.treeSize = validatorOpts.treeSize
+	v.strict = validatorOpts.strict && !validatorOpts.skipRedundant
+	v.skipRedundant = validatorOpts.skipRedundant
+	v.padding = validatorOpts.padding
+	v.leafHashes = validatorOpts.leafHashes
+	v.indexOffset = validatorOpts.indexOffset
+	v.emptyLeaf = validatorOpts.emptyLeaf
+	v.leafIndexBase = validatorOpts.leafIndexBase
+}
+
+// Reset clears the configuration and the per-call state of the validator before it is returned to the pool. Buffers
+// are kept to be reused by the next validation.
+func (v *validator) Reset() {
+	v.hasher = nil
+	v.leafHasher = nil
+	v.layerHasher = nil
+
+	v.clearState()
+
+	v.duplicatePadding = false
+	v.treeSize = 0
+
+	v.strict = false
+	v.skipRedundant = false
+	v.padding = nil
+	v.leafHashes = nil
+	v.indexOffset = 0
+	v.emptyLeaf = nil
+	v.leafIndexBase = 0
+}
 
-		leaves:  leaves,
-		indices: indices,
-		proof:   proof,
+// clearState clears the per-call state of the validator, i.e. the leaves, indices, parked nodes and proof of the last
+// validation, so it does not retain references to them.
+func (v *validator) clearState() {
+	v.leaves = nil
+	v.indices = nil
+	clear(v.parkedNodes)
+	v.proof = nil
+	v.rootHeight = 0
+	v.trail = nil
+}
+
+// reconstruct calculates the root of the Merkle tree from the provided leaves with the given sorted indices and proof
+// in the given buffer. It returns the root and its height. The validator can be reused for multiple calls.
+func (v *validator) reconstruct(
+	leaves map[uint64][]byte,
+	indices []uint64,
+	proof [][]byte,
+	validatorOpts *validatorOpts,
+	buf []byte,
+) ([]byte, uint64, error) {
+	proof, err := resolveNilProofNodes(proof, validatorOpts)
+	if err != nil {
+		return nil, 0, err
+	}
+	if validatorOpts.checkSizes {
+		if err := checkNodeSizes(leaves, indices, proof, validatorOpts); err != nil {
+			return nil, 0, err
+		}
 	}
+	v.leaves = leaves
+	if err := v.checkLeaves(indices); err != nil {
+		return nil, 0, err
+	}
+
+	v.indices = indices
+	v.proof = proof
+	v.rootHeight = 0
 	if err := v.initParkingNodes(); err != nil {
-		return false, err
+		return nil, 0, err
 	}
 
-	buf := make([]byte, 0, v.leafHasher.Size())
-	calculatedRoot, err := v.calcRoot(math.MaxUint64, buf)
+	root, err := v.calcRoot(math.MaxUint64, buf)
 	if err != nil {
-		return false, err
+		return nil, 0, err
+	}
+	if err := checkDepths(leaves, v.rootHeight, validatorOpts); err != nil {
+		return nil, 0, err
+	}
+	return root, v.rootHeight, nil
+}
+
+// checkLeaves checks that the leaf hasher of the validator can hash the leaves with the given indices. Absent leaves
+// are not hashed and therefore not checked. With duplicate padding every index has to be within the tree, otherwise a
+// duplicated node could be passed off as a leaf beyond the end of the tree.
+func (v *validator) checkLeaves(indices []uint64) error {
+	if v.duplicatePadding && indices[len(indices)-1] >= v.treeSize {
+		return fmt.Errorf("%w: leaf %d is beyond the tree size %d",
+			ErrInvalidLeafIndex, v.indexOffset+indices[len(indices)-1], v.treeSize)
+	}
+	if checker, ok := v.leafHasher.(indexChecker); ok {
+		for _, idx := range indices {
+			if v.isEmptyLeaf(idx) {
+				continue
+			}
+			if err := checker.checkIndex(v.leafIndexBase + idx); err != nil {
+				return err
+			}
+		}
+	}
+	if checker, ok := v.leafHasher.(LeafChecker); ok {
+		for _, idx := range indices {
+			if v.isEmptyLeaf(idx) {
+				continue
+			}
+			if err := checker.Check(v.leaves[idx]); err != nil {
+				return fmt.Errorf("leaf %d: %w", idx, err)
+			}
+		}
+	}
+	return nil
+}
+
+// checkDepths checks the depths asserted with WithExpectedDepth against the height of the reconstructed root. The
+// indices of the given leaves are relative to the index offset of the validator.
+func checkDepths(leaves map[uint64][]byte, height uint64, validatorOpts *validatorOpts) error {
+	for _, d := range validatorOpts.expectedDepths {
+		if _, ok := leaves[d.index-validatorOpts.indexOffset]; !ok || d.index < validatorOpts.indexOffset {
+			return fmt.Errorf("%w: leaf %d is not proven", ErrUnexpectedDepth, d.index)
+		}
+		if d.depth < 0 || uint64(d.depth) != height {
+			return fmt.Errorf("%w: leaf %d has depth %d, expected %d", ErrUnexpectedDepth, d.index, height, d.depth)
+		}
 	}
-	return bytes.Equal(root, calculatedRoot), nil
+	return nil
+}
+
+// resolveNilProofNodes replaces nil nodes in the proof with the padding value if set, otherwise an error wrapping
+// ErrNilProofNode is returned. The given proof is not modified, if it contains nil nodes a copy is returned.
+func resolveNilProofNodes(proof [][]byte, validatorOpts *validatorOpts) ([][]byte, error) {
+	var resolved [][]byte
+	for i, node := range proof {
+		if node != nil {
+			continue
+		}
+		if validatorOpts.padding == nil {
+			return nil, fmt.Errorf("%w: index %d", ErrNilProofNode, i)
+		}
+		if resolved == nil {
+			resolved = slices.Clone(proof)
+		}
+		resolved[i] = validatorOpts.padding
+	}
+	if resolved == nil {
+		return proof, nil
+	}
+	return resolved, nil
+}
+
+// checkRootLength checks that the root has the size of the hasher if size checks are enabled. If the tree consists of
+// a single leaf the root can have the size of the leaf hasher instead.
+func checkRootLength(root []byte, leaves map[uint64][]byte, proof [][]byte, validatorOpts *validatorOpts) error {
+	if !validatorOpts.checkSizes {
+		return nil
+	}
+
+	size := validatorOpts.Hasher().Size()
+	if len(root) == size {
+		return nil
+	}
+	if len(leaves) == 1 && len(proof) == 0 && len(root) == validatorOpts.LeafHasher().Size() {
+		return nil
+	}
+	return fmt.Errorf("%w: root has %d bytes, expected %d", ErrInvalidRootLength, len(root), size)
+}
+
+// checkNodeSizes checks that all proof nodes and, if the leaves are used as is, all leaves have the size of the hasher.
+func checkNodeSizes(leaves map[uint64][]byte, indices []uint64, proof [][]byte, validatorOpts *validatorOpts) error {
+	size := validatorOpts.Hasher().Size()
+	if _, ok := validatorOpts.LeafHasher().(*valueLeafs); ok {
+		for _, idx := range indices {
+			if leaves[idx] == nil && validatorOpts.emptyLeaf != nil {
+				continue
+			}
+			if len(leaves[idx]) != size {
+				return fmt.Errorf("%w: leaf %d has %d bytes, expected %d",
+					ErrInvalidNodeSize, idx, len(leaves[idx]), size)
+			}
+		}
+	}
+	for i, node := range proof {
+		if len(node) != size {
+			return fmt.Errorf("%w: proof node %d has %d bytes, expected %d", ErrInvalidNodeSize, i, len(node), size)
+		}
+	}
+	return nil
 }
 
 type validator struct {
-	hasher     Hasher
-	leafHasher LeafHasher
+	hasher      Hasher
+	leafHasher  LeafHasher
+	layerHasher func(height uint64) Hasher
 
 	leaves      map[uint64][]byte
-	indices     []uint64
+	indices     []uint64 // indices of the leaves not yet consumed by calcRoot
+	indexBuf    []uint64 // buffer for the indices of pooled validators
 	parkedNodes map[uint64][][]byte
+	parkedBuf   [][]byte // buffer for the parked nodes, reused across validations
 	proof       [][]byte
+
+	duplicatePadding bool
+	treeSize         uint64
+
+	strict        bool   // indicates if copies of derived siblings in the proof are rejected, see checkCopy
+	skipRedundant bool   // indicates if copies of derived siblings are skipped in the proof, see checkRedundant
+	padding       []byte // the padding value of the tree, zeros if nil
+
+	leafHashes  map[uint64][]byte // hashes of the proven leaves by global index, only collected if set
+	indexOffset uint64            // global index of the first leaf of the tree
+	rootHeight  uint64            // height of the reconstructed root
+	trail       [][]byte          // nodes computed from the leaf to the root, only collected if not nil
+	emptyLeaf   []byte            // node of nil leaves, nil leaves are hashed if not set
+
+	leafIndexBase uint64 // added to the index of a leaf when it is hashed
 }
 
 func (v *validator) initParkingNodes() error {
@@ -113,15 +955,23 @@ func (v *validator) initParkingNodes() error {
 
 	// we preallocate parked nodes for all indices with a length of the calculated tree height
 	// this avoids unnecessary allocations when we park the nodes
-	v.parkedNodes = make(map[uint64][][]byte, len(v.indices))
+	if v.parkedNodes == nil {
+		v.parkedNodes = make(map[uint64][][]byte, len(v.indices))
+	}
 
+	// the nodes are taken from the buffer of the validator, so they are reused by the next validation
+	n := len(v.indices) * treeHeight
+	if len(v.parkedBuf) < n {
+		v.parkedBuf = append(v.parkedBuf, make([][]byte, n-len(v.parkedBuf))...)
+	}
 	for idx := range v.indices {
-		parkedNodes := make([][]byte, treeHeight)
-		if len(parkedNodes) > 0 {
-			parkedNodes[0] = make([]byte, 0, v.leafHasher.Size())
-		}
-		for i := 1; i < len(parkedNodes); i++ {
-			parkedNodes[i] = make([]byte, 0, v.hasher.Size())
+		parkedNodes := v.parkedBuf[idx*treeHeight : (idx+1)*treeHeight : (idx+1)*treeHeight]
+		for i := range parkedNodes {
+			size := v.hasher.Size()
+			if i == 0 {
+				size = v.leafHasher.Size()
+			}
+			parkedNodes[i] = slices.Grow(parkedNodes[i][:0], size)
 		}
 		v.parkedNodes[v.indices[idx]] = parkedNodes
 	}
@@ -187,18 +1037,26 @@ func (v *validator) calcRoot(maxHeight uint64, rootBuf []byte) ([]byte, error) {
 	curIndex := v.indices[0]
 	curParkedNodes := v.parkedNodes[curIndex]
 	v.indices = v.indices[1:]
-	curNode := v.leafHasher.Hash(rootBuf, v.leaves[curIndex], curParkedNodes)
+	curNode := v.leafNode(rootBuf, curIndex, curParkedNodes)
+	if v.leafHashes != nil {
+		v.leafHashes[curIndex+v.indexOffset] = slices.Clone(curNode)
+	}
+	v.recordTrail(curNode)
 
 	var lChild, rChild []byte
 	var siblingBuf []byte
 
 	for height := range maxHeight {
 		switch {
+		case v.missingSibling(height, curIndex):
+			// the current node is the last node of an unbalanced layer, with duplicate padding it is its own sibling
+			lChild, rChild = curNode, curNode
 		case len(v.proof) == 0 && len(v.indices) == 0: // no more to prove
 			if curIndex != 0 {
 				// if we reached the root curIndex should be 0, if it isn't we are missing proof nodes
 				return nil, ErrShortProof
 			}
+			v.rootHeight = height
 			return curNode, nil
 		case len(v.indices) > 0 && (v.indices[0]>>height) == (curIndex^1):
 			// next index is an ancestor of the right sibling of the current node
@@ -212,6 +1070,10 @@ func (v *validator) calcRoot(maxHeight uint64, rootBuf []byte) ([]byte, error) {
 			if err != nil {
 				return nil, err
 			}
+			if err := v.checkCopy(height, sibling); err != nil {
+				return nil, err
+			}
+			v.skipCopy(sibling)
 			lChild, rChild = curNode, sibling
 		default: // next index is not an ancestor of the sibling of the current node
 			if len(v.proof) == 0 {
@@ -227,13 +1089,39 @@ func (v *validator) calcRoot(maxHeight uint64, rootBuf []byte) ([]byte, error) {
 
 		// we are moving up the tree, the index of the current node on the new height is half of the current index
 		curIndex >>= 1
-		curNode = v.hasher.Hash(curNode, lChild, rChild)
+		curNode = hasherAt(v.layerHasher, height+1, v.hasher).Hash(curNode, lChild, rChild)
+		v.recordTrail(curNode)
 	}
 
 	// we reached the root of the tree with the given max height
 	return curNode, nil
 }
 
+// leafNode returns the node of the leaf with the given index in the given buffer. Nil leaves are absent leaves if an
+// empty leaf is set, their node is the empty leaf.
+func (v *validator) leafNode(buf []byte, index uint64, leftSiblings [][]byte) []byte {
+	if v.isEmptyLeaf(index) {
+		return append(buf[:0], v.emptyLeaf...)
+	}
+	return hashLeaf(v.leafHasher, buf, v.leafIndexBase+index, v.leaves[index], leftSiblings)
+}
+
+// isEmptyLeaf returns true if the leaf with the given index is an absent leaf, i.e. it is nil and an empty leaf is set.
+func (v *validator) isEmptyLeaf(index uint64) bool {
+	return v.emptyLeaf != nil && v.leaves[index] == nil
+}
+
+// missingSibling returns true if duplicate padding is enabled and the node with the given index at the given height
+// has no right sibling, i.e. the first leaf of the sibling's subtree is beyond the end of the tree. The first node of
+// a layer never has a missing sibling, if its sibling is beyond the end of the tree it is the root.
+func (v *validator) missingSibling(height, index uint64) bool {
+	if !v.duplicatePadding || index == 0 || index&1 == 1 || height >= 64 {
+		return false
+	}
+	sibling := index ^ 1
+	return sibling > (math.MaxUint64>>height) || sibling<<height >= v.treeSize
+}
+
 // copyParkedNodes sets the parked nodes for the next index in the proof to the same as for the current index
 // starting from the given height.
 func (v *validator) copyParkedNodes(height uint64, curNode []byte, curParkedNodes [][]byte) {
@@ -250,3 +1138,83 @@ func (v *validator) copyParkedNodes(height uint64, curNode []byte, curParkedNode
 		v.parkedNodes[v.indices[0]][i] = append(v.parkedNodes[v.indices[0]][i][:0], curParkedNodes[i]...)
 	}
 }
+
+// recordTrail adds a copy of the given node to the audit trail if it is collected.
+func (v *validator) recordTrail(node []byte) {
+	if v.trail != nil {
+		v.trail = append(v.trail, slices.Clone(node))
+	}
+}
+
+// checkCopy returns an error wrapping ErrRedundantProofNode in strict mode if the next proof node is a copy of the
+// given sibling at the given height that was just derived from the proven leaves, i.e. the node is at the position of
+// the sibling in the proof. Copies of padding nodes are ambiguous, they are resolved by checkRedundant.
+func (v *validator) checkCopy(height uint64, sibling []byte) error {
+	if !v.strict || len(v.proof) == 0 || !bytes.Equal(v.proof[0], sibling) || IsPaddingNode(sibling, v.padding) {
+		return nil
+	}
+	return fmt.Errorf("%w: node at height %d can be derived from the leaves", ErrRedundantProofNode, height)
+}
+
+// skipCopy skips the next proof node if copies of derived siblings are skipped and it is equal to the given sibling
+// that was just derived from the proven leaves, i.e. if the node is at the position of the sibling in the proof.
+func (v *validator) skipCopy(sibling []byte) {
+	if v.skipRedundant && len(v.proof) > 0 && bytes.Equal(v.proof[0], sibling) {
+		v.proof = v.proof[1:]
+	}
+}
+
+// ImpliedTreeSize returns the range of leaf counts (inclusive) of a tree for which a single leaf proof for the leaf
+// with the given index has the given length. Verifiers can use it to sanity-check a proof against the expected size
+// of the tree. If no tree size is possible for the given index and proof length (0, 0) is returned.
+//
+// A proof of length l for a tree without a minimum height proves a leaf in a tree with more than 2^(l-1) and at most
+// 2^l leaves. If the tree was built with a minimum height higher than its natural height, the proof is longer and the
+// returned range only gives an upper bound of the tree size.
+func ImpliedTreeSize(index uint64, proofLen int) (uint64, uint64) {
+	switch {
+	case proofLen < 0 || proofLen > 64 || index == math.MaxUint64:
+		return 0, 0
+	case proofLen == 0:
+		if index != 0 {
+			return 0, 0
+		}
+		return 1, 1
+	case proofLen < 64 && index >= 1<<proofLen:
+		return 0, 0
+	}
+
+	minSize := max(index+1, 1<<(proofLen-1)+1)
+	maxSize := uint64(math.MaxUint64)
+	if proofLen < 64 {
+		maxSize = 1 << proofLen
+	}
+	return minSize, maxSize
+}
+
+// AuditPathLength returns the number of nodes in the audit path of the leaf with the given index in a tree with the
+// given number of leaves as defined in RFC 6962. If the index is not within the tree 0 is returned.
+//
+// RFC 6962 trees do not use padding: an unbalanced tree is split into a balanced left subtree of the largest power of
+// two smaller than the tree size and the remaining right subtree. Leaves in the right subtree therefore can have
+// shorter audit paths than proofs of this package, which always have a length of ceil(log2(treeSize)) since they
+// contain the padding nodes.
+func AuditPathLength(index, treeSize uint64) int {
+	if index >= treeSize {
+		return 0
+	}
+
+	length := 0
+	for treeSize > 1 {
+		// k is the largest power of two smaller than treeSize, i.e. the size of the left subtree
+		k := uint64(1) << (bits.Len64(treeSize-1) - 1)
+		if index < k {
+			treeSize = k
+		} else {
+			index -= k
+			treeSize -= k
+		}
+		length++
+	}
+	return length
+}
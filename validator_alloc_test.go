@@ -0,0 +1,62 @@
+//go:build !race
+
+package merkle_test
+
+import (
+	"encoding/hex"
+	"testing"
+
+	"github.com/fasmat/merkle"
+)
+
+// TestValidateProofAllocs checks that validators are reused across calls to ValidateProof. It is excluded from race
+// builds, since the race detector randomly drops items put into a sync.Pool.
+//
+//nolint:paralleltest // AllocsPerRun must not run in parallel tests
+func TestValidateProofAllocs(t *testing.T) {
+	leaves := make(map[uint64][]byte)
+	leaves[4], _ = hex.DecodeString("0400000000000000000000000000000000000000000000000000000000000000")
+
+	root, _ := hex.DecodeString("89a0f1577268cc19b0a39c7a69f804fd140640c699585eb635ebb03c06154cce")
+	proof := make([][]byte, 3)
+	proof[0], _ = hex.DecodeString("0500000000000000000000000000000000000000000000000000000000000000")
+	proof[1], _ = hex.DecodeString("fa670379e5c2212ed93ff09769622f81f98a91e1ec8fb114d607dd25220b9088")
+	proof[2], _ = hex.DecodeString("ba94ffe7edabf26ef12736f8eb5ce74d15bedb6af61444ae2906e926b1a95084")
+
+	allocs := testing.AllocsPerRun(100, func() {
+		valid, err := merkle.ValidateProof(root, leaves, proof)
+		if err != nil || !valid {
+			t.Fatalf("Expected proof to be valid, got %t, %v", valid, err)
+		}
+	})
+	// the options and the buffer of the returned root are allocated on every call
+	if allocs > 2 {
+		t.Errorf("Expected at most 2 allocations per validation, got %.0f", allocs)
+	}
+}
+
+// TestReusableValidatorSequentialAllocs checks that a ReusableValidator reuses the parked nodes of sequential leaf
+// hashers across calls to Validate, also after it was reset.
+//
+//nolint:paralleltest // AllocsPerRun must not run in parallel tests
+func TestReusableValidatorSequentialAllocs(t *testing.T) {
+	leaf, _ := hex.DecodeString("0400000000000000000000000000000000000000000000000000000000000000")
+
+	root, _ := hex.DecodeString("02ce397ec513f034dd6ec5dce3cdb8bfcf10f400a9979cb03abf52d3b5f6c88b")
+	proof := make([][]byte, 3)
+	proof[0], _ = hex.DecodeString("03085fced9119406c955dc302885a509bf81972ead5fb8b1d87dd3308f9830a2")
+	proof[1], _ = hex.DecodeString("64276da1ef80b4d466e654c5808c4ea3f2c57dda04499e0f495ac4593c746993")
+	proof[2], _ = hex.DecodeString("c3831849e0ae67538cb54a4de0729118685c41822f714f7c466ee641380d01db")
+
+	v := merkle.NewReusableValidator(root, merkle.WithLeafHasher(merkle.SequentialWorkHasher()))
+	allocs := testing.AllocsPerRun(100, func() {
+		valid, err := v.Validate(4, leaf, proof)
+		if err != nil || !valid {
+			t.Fatalf("Expected proof to be valid, got %t, %v", valid, err)
+		}
+		v.Reset()
+	})
+	if allocs > 0 {
+		t.Errorf("Expected no allocations per validation, got %.0f", allocs)
+	}
+}
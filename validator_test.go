@@ -1,11 +1,15 @@
 package merkle_test
 
 import (
+	"bytes"
+	"crypto/sha256"
 	"encoding/binary"
 	"encoding/hex"
 	"errors"
 	"fmt"
+	"math"
 	"math/rand/v2"
+	"slices"
 	"testing"
 
 	"github.com/fasmat/merkle"
@@ -700,6 +704,1514 @@ func TestValidateProofEmpty(t *testing.T) {
 	}
 }
 
+func TestValidateProofStrict(t *testing.T) {
+	t.Parallel()
+
+	leaves := make(map[uint64][]byte)
+	leaves[0], _ = hex.DecodeString("0000000000000000000000000000000000000000000000000000000000000000")
+	leaves[1], _ = hex.DecodeString("0100000000000000000000000000000000000000000000000000000000000000")
+	leaves[4], _ = hex.DecodeString("0400000000000000000000000000000000000000000000000000000000000000")
+
+	root, _ := hex.DecodeString("89a0f1577268cc19b0a39c7a69f804fd140640c699585eb635ebb03c06154cce")
+	proof := make([][]byte, 3)
+	proof[0], _ = hex.DecodeString("0094579cfc7b716038d416a311465309bea202baa922b224a7b08f01599642fb")
+	proof[1], _ = hex.DecodeString("0500000000000000000000000000000000000000000000000000000000000000")
+	proof[2], _ = hex.DecodeString("fa670379e5c2212ed93ff09769622f81f98a91e1ec8fb114d607dd25220b9088")
+
+	valid, err := merkle.ValidateProof(root, leaves, proof, merkle.WithStrictProof())
+	if err != nil {
+		t.Error(err)
+	}
+	if !valid {
+		t.Error("proof is not valid")
+	}
+
+	// pad the proof with leaf 1, which can be derived from the proven leaves
+	paddedProof := append([][]byte{leaves[1]}, proof...)
+
+	valid, err = merkle.ValidateProof(root, leaves, paddedProof)
+	if err != nil {
+		t.Errorf("expected no error without strict mode, got: %v", err)
+	}
+	if valid {
+		t.Error("expected proof to be invalid")
+	}
+
+	valid, err = merkle.ValidateProof(root, leaves, paddedProof, merkle.WithStrictProof())
+	if !errors.Is(err, merkle.ErrRedundantProofNode) {
+		t.Errorf("expected error: %v, got: %v", merkle.ErrRedundantProofNode, err)
+	}
+	if valid {
+		t.Error("expected proof to be invalid")
+	}
+}
+
+func TestValidateProofStrictMultiProof(t *testing.T) {
+	t.Parallel()
+
+	tree := merkle.TreeBuilder().
+		WithLeavesToProve(map[uint64]struct{}{1: {}, 4: {}, 5: {}, 6: {}}).
+		Build()
+	values := make([][]byte, 8)
+	leaves := make(map[uint64][]byte)
+	for i := range values {
+		values[i] = make([]byte, tree.NodeSize())
+		binary.LittleEndian.PutUint64(values[i], uint64(i))
+		tree.Add(values[i])
+		if i == 1 || i == 4 || i == 5 || i == 6 {
+			leaves[uint64(i)] = values[i]
+		}
+	}
+	root, proof := tree.RootAndProof()
+	if len(proof) != 3 {
+		t.Fatalf("Expected proof of leaves 1, 4, 5 and 6 to have 3 nodes, got %d", len(proof))
+	}
+
+	hasher := merkle.Sha256()
+	node67 := hasher.Hash(nil, values[6], values[7])
+	node4567 := hasher.Hash(nil, hasher.Hash(nil, values[4], values[5]), node67)
+
+	// the nodes are inserted at the positions of the siblings they can be derived from
+	tt := []struct {
+		name  string
+		proof [][]byte
+	}{
+		{
+			name:  "leaf 5",
+			proof: [][]byte{proof[0], proof[1], values[5], proof[2]},
+		},
+		{
+			name:  "node of leaves 6 and 7",
+			proof: [][]byte{proof[0], proof[1], proof[2], node67},
+		},
+		{
+			name:  "node of leaves 4 to 7",
+			proof: [][]byte{proof[0], proof[1], proof[2], node4567},
+		},
+	}
+
+	valid, err := merkle.ValidateProof(root, leaves, proof, merkle.WithStrictProof())
+	if err != nil {
+		t.Fatalf("unexpected error in strict mode: %v", err)
+	}
+	if !valid {
+		t.Fatal("proof is not valid in strict mode")
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			valid, err := merkle.ValidateProof(root, leaves, tc.proof, merkle.WithStrictProof())
+			if !errors.Is(err, merkle.ErrRedundantProofNode) {
+				t.Errorf("expected error: %v, got: %v", merkle.ErrRedundantProofNode, err)
+			}
+			if valid {
+				t.Error("expected proof to be invalid")
+			}
+
+			// the node is detected while the root is reconstructed, i.e. also without a root to compare to
+			_, err = merkle.ComputeRoot(leaves, tc.proof, merkle.WithStrictProof())
+			var validationErr *merkle.ValidationError
+			if !errors.As(err, &validationErr) || validationErr.Reason != merkle.ReasonRedundantProofNode {
+				t.Errorf("expected validation error with reason %v, got: %v", merkle.ReasonRedundantProofNode, err)
+			}
+		})
+	}
+}
+
+func TestValidateProofStrictDuplicateLeaves(t *testing.T) {
+	t.Parallel()
+
+	// all leaves are equal, so every proof node at height 0 matches the proven leaves
+	for _, indices := range [][]uint64{{0}, {5}, {0, 1}, {2, 5, 6}} {
+		toProve := make(map[uint64]struct{})
+		leaves := make(map[uint64][]byte)
+		for _, i := range indices {
+			toProve[i] = struct{}{}
+			leaves[i] = make([]byte, 32)
+		}
+
+		tree := merkle.TreeBuilder().WithLeavesToProve(toProve).Build()
+		for range 8 {
+			tree.Add(make([]byte, 32))
+		}
+		root, proof := tree.RootAndProof()
+
+		valid, err := merkle.ValidateProof(root, leaves, proof, merkle.WithStrictProof())
+		if err != nil {
+			t.Errorf("leaves %v: unexpected error: %v", indices, err)
+		}
+		if !valid {
+			t.Errorf("leaves %v: proof is not valid", indices)
+		}
+	}
+}
+
+func TestValidateProofStrictMinHeight(t *testing.T) {
+	t.Parallel()
+
+	// the padding nodes of the proof are equal to the zero-valued leaves, but they are not at their positions
+	tree := merkle.TreeBuilder().
+		WithMinHeight(3).
+		WithLeavesToProve(map[uint64]struct{}{0: {}, 1: {}}).
+		Build()
+	tree.Add(make([]byte, 32))
+	tree.Add(make([]byte, 32))
+	root, proof := tree.RootAndProof()
+	leaves := map[uint64][]byte{0: make([]byte, 32), 1: make([]byte, 32)}
+
+	valid, err := merkle.ValidateProof(root, leaves, proof)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !valid {
+		t.Fatal("proof is not valid")
+	}
+
+	valid, err = merkle.ValidateProof(root, leaves, proof, merkle.WithStrictProof())
+	if err != nil {
+		t.Fatalf("unexpected error in strict mode: %v", err)
+	}
+	if !valid {
+		t.Error("proof is not valid in strict mode")
+	}
+
+	// a copy of leaf 1 at its position is still detected
+	paddedProof := append([][]byte{leaves[1]}, proof...)
+	_, err = merkle.ValidateProof(root, leaves, paddedProof, merkle.WithStrictProof())
+	if !errors.Is(err, merkle.ErrRedundantProofNode) {
+		t.Errorf("expected error: %v, got: %v", merkle.ErrRedundantProofNode, err)
+	}
+}
+
+func TestValidateProofDetailed(t *testing.T) {
+	t.Parallel()
+
+	leaves := make(map[uint64][]byte)
+	leaves[4], _ = hex.DecodeString("0400000000000000000000000000000000000000000000000000000000000000")
+
+	root, _ := hex.DecodeString("89a0f1577268cc19b0a39c7a69f804fd140640c699585eb635ebb03c06154cce")
+	proof := make([][]byte, 3)
+	proof[0], _ = hex.DecodeString("0500000000000000000000000000000000000000000000000000000000000000")
+	proof[1], _ = hex.DecodeString("fa670379e5c2212ed93ff09769622f81f98a91e1ec8fb114d607dd25220b9088")
+	proof[2], _ = hex.DecodeString("ba94ffe7edabf26ef12736f8eb5ce74d15bedb6af61444ae2906e926b1a95084")
+
+	err := merkle.ValidateProofDetailed(root, leaves, proof)
+	if err != nil {
+		t.Error(err)
+	}
+
+	invalidRoot, _ := hex.DecodeString("89a0f1577268cc19b0a39c7a69f804fd140640c699585eb635ebb03c06154ccf")
+	err = merkle.ValidateProofDetailed(invalidRoot, leaves, proof)
+	if !errors.Is(err, merkle.ErrRootMismatch) {
+		t.Errorf("expected error: %v, got: %v", merkle.ErrRootMismatch, err)
+	}
+	expectedMsg := "root mismatch: roots differ at byte 31: " +
+		"expected 89a0f1577268cc19b0a39c7a69f804fd140640c699585eb635ebb03c06154ccf, " +
+		"computed 89a0f1577268cc19b0a39c7a69f804fd140640c699585eb635ebb03c06154cce"
+	if err.Error() != expectedMsg {
+		t.Errorf("expected error message %q, got %q", expectedMsg, err.Error())
+	}
+}
+
+func TestCompareRoots(t *testing.T) {
+	t.Parallel()
+
+	tt := []struct {
+		name     string
+		expected string
+		computed string
+		msg      string
+	}{
+		{
+			name:     "equal",
+			expected: "0102",
+			computed: "0102",
+			msg:      "",
+		},
+		{
+			name:     "different",
+			expected: "010203",
+			computed: "01ff03",
+			msg:      "roots differ at byte 1: expected 010203, computed 01ff03",
+		},
+		{
+			name:     "different length",
+			expected: "010203",
+			computed: "0102",
+			msg: "roots differ in length (expected 3 bytes, computed 2 bytes), first difference at byte 2: " +
+				"expected 010203, computed 0102",
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			expected, _ := hex.DecodeString(tc.expected)
+			computed, _ := hex.DecodeString(tc.computed)
+			msg := merkle.CompareRoots(expected, computed)
+			if msg != tc.msg {
+				t.Errorf("expected message %q, got %q", tc.msg, msg)
+			}
+		})
+	}
+}
+
+func TestValidateProofContentHasher(t *testing.T) {
+	t.Parallel()
+
+	leavesToProve := map[uint64]struct{}{
+		1: {},
+		4: {},
+		8: {},
+	}
+	tree := merkle.TreeBuilder().
+		WithLeafContentHashing().
+		WithLeavesToProve(leavesToProve).
+		Build()
+
+	leaves := make(map[uint64][]byte, len(leavesToProve))
+	for i := range 10 {
+		// leaves of different length, none of them the size of a node
+		blob := bytes.Repeat([]byte{byte(i)}, 100*(i+1))
+		tree.Add(blob)
+
+		if _, ok := leavesToProve[uint64(i)]; ok {
+			leaves[uint64(i)] = blob
+		}
+	}
+
+	root, proof := tree.RootAndProof()
+	leafHasher := merkle.ContentHasher(merkle.Sha256())
+	valid, err := merkle.ValidateProof(root, leaves, proof, merkle.WithLeafHasher(leafHasher))
+	if err != nil {
+		t.Error(err)
+	}
+	if !valid {
+		t.Error("proof is not valid")
+	}
+
+	leaves[4] = bytes.Repeat([]byte{4}, 499)
+	valid, err = merkle.ValidateProof(root, leaves, proof, merkle.WithLeafHasher(leafHasher))
+	if err != nil {
+		t.Error(err)
+	}
+	if valid {
+		t.Error("expected proof with modified leaf to be invalid")
+	}
+}
+
+func TestImpliedTreeSize(t *testing.T) {
+	t.Parallel()
+
+	tt := []struct {
+		index    uint64
+		proofLen int
+		min      uint64
+		max      uint64
+	}{
+		{4, 3, 5, 8},
+		{0, 3, 5, 8},
+		{6, 3, 7, 8},
+		{8, 4, 9, 16},
+		{0, 0, 1, 1},
+		{0, 1, 2, 2},
+		{1, 1, 2, 2},
+		{1, 0, 0, 0},
+		{8, 3, 0, 0},
+		{0, -1, 0, 0},
+		{0, 65, 0, 0},
+		{math.MaxUint64 - 1, 64, math.MaxUint64, math.MaxUint64},
+		{math.MaxUint64, 64, 0, 0},
+	}
+
+	for _, tc := range tt {
+		t.Run(fmt.Sprintf("index=%d/proofLen=%d", tc.index, tc.proofLen), func(t *testing.T) {
+			t.Parallel()
+
+			minSize, maxSize := merkle.ImpliedTreeSize(tc.index, tc.proofLen)
+			if minSize != tc.min || maxSize != tc.max {
+				t.Errorf("expected tree size in [%d, %d], got [%d, %d]", tc.min, tc.max, minSize, maxSize)
+			}
+		})
+	}
+}
+
+func TestValidateProofMaxExtraPadding(t *testing.T) {
+	t.Parallel()
+
+	tree := merkle.TreeBuilder().
+		WithMinHeight(6).
+		WithLeafToProve(4).
+		Build()
+	leaves := make(map[uint64][]byte)
+	for i := range 8 {
+		b := make([]byte, tree.NodeSize())
+		binary.LittleEndian.PutUint64(b, uint64(i))
+		tree.Add(b)
+		if i == 4 {
+			leaves[uint64(i)] = b
+		}
+	}
+
+	root, proof := tree.RootAndProof()
+	if len(proof) != 5 {
+		t.Fatalf("Expected proof to be of length %d, got %d", 5, len(proof))
+	}
+
+	valid, err := merkle.ValidateProof(root, leaves, proof)
+	if err != nil {
+		t.Error(err)
+	}
+	if !valid {
+		t.Error("proof is not valid")
+	}
+
+	// strip the padding nodes from the proof
+	proof = proof[:3]
+
+	tt := []struct {
+		maxExtraPadding int
+		valid           bool
+	}{
+		{0, false},
+		{1, false},
+		{2, true},
+		{10, true},
+	}
+
+	for _, tc := range tt {
+		t.Run(fmt.Sprintf("maxExtraPadding=%d", tc.maxExtraPadding), func(t *testing.T) {
+			t.Parallel()
+
+			valid, err := merkle.ValidateProof(root, leaves, proof, merkle.WithMaxExtraPadding(tc.maxExtraPadding))
+			if err != nil {
+				t.Error(err)
+			}
+			if valid != tc.valid {
+				t.Errorf("expected valid: %t, got: %t", tc.valid, valid)
+			}
+		})
+	}
+}
+
+func TestValidateProofChan(t *testing.T) {
+	t.Parallel()
+
+	tree := merkle.TreeBuilder().
+		WithLeavesToProve(map[uint64]struct{}{0: {}, 4: {}, 7: {}, 9: {}}).
+		Build()
+	leaves := make([]merkle.IndexedLeaf, 0, 4)
+	for i := range 10 {
+		b := make([]byte, tree.NodeSize())
+		binary.LittleEndian.PutUint64(b, uint64(i))
+		tree.Add(b)
+		if i == 0 || i == 4 || i == 7 || i == 9 {
+			leaves = append(leaves, merkle.IndexedLeaf{Index: uint64(i), Value: b})
+		}
+	}
+	root, proof := tree.RootAndProof()
+
+	t.Run("random order", func(t *testing.T) {
+		t.Parallel()
+
+		ch := make(chan merkle.IndexedLeaf)
+		go func() {
+			defer close(ch)
+			for _, i := range rand.Perm(len(leaves)) {
+				ch <- leaves[i]
+			}
+		}()
+
+		valid, err := merkle.ValidateProofChan(root, ch, proof)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !valid {
+			t.Error("proof is not valid")
+		}
+	})
+
+	t.Run("duplicate leaf", func(t *testing.T) {
+		t.Parallel()
+
+		ch := make(chan merkle.IndexedLeaf, len(leaves)+1)
+		for _, leaf := range leaves {
+			ch <- leaf
+		}
+		ch <- leaves[1]
+		close(ch)
+
+		valid, err := merkle.ValidateProofChan(root, ch, proof)
+		if !errors.Is(err, merkle.ErrDuplicateLeaf) {
+			t.Errorf("Expected error to be %v, got %v", merkle.ErrDuplicateLeaf, err)
+		}
+		if valid {
+			t.Error("Expected proof to be invalid")
+		}
+	})
+}
+
+func TestValidateProofNodeSizeCheck(t *testing.T) {
+	t.Parallel()
+
+	tree := merkle.TreeBuilder().
+		WithLeafToProve(4).
+		Build()
+	leaves := make(map[uint64][]byte)
+	for i := range 8 {
+		b := make([]byte, tree.NodeSize())
+		binary.LittleEndian.PutUint64(b, uint64(i))
+		tree.Add(b)
+		if i == 4 {
+			leaves[uint64(i)] = b
+		}
+	}
+	root, proof := tree.RootAndProof()
+
+	valid, err := merkle.ValidateProof(root, leaves, proof, merkle.WithNodeSizeCheck())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !valid {
+		t.Error("proof is not valid")
+	}
+
+	shortProof := make([][]byte, len(proof))
+	for i, node := range proof {
+		shortProof[i] = node[:16]
+	}
+	_, err = merkle.ValidateProof(root, leaves, shortProof, merkle.WithNodeSizeCheck())
+	if !errors.Is(err, merkle.ErrInvalidNodeSize) {
+		t.Errorf("Expected error to be %v, got %v", merkle.ErrInvalidNodeSize, err)
+	}
+	expected := "invalid node size: proof node 0 has 16 bytes, expected 32"
+	if err == nil || err.Error() != expected {
+		t.Errorf("Expected error message %q, got %v", expected, err)
+	}
+
+	shortLeaves := map[uint64][]byte{4: leaves[4][:16]}
+	_, err = merkle.ValidateProof(root, shortLeaves, proof, merkle.WithNodeSizeCheck())
+	if !errors.Is(err, merkle.ErrInvalidNodeSize) {
+		t.Errorf("Expected error to be %v, got %v", merkle.ErrInvalidNodeSize, err)
+	}
+}
+
+func TestAuditPathLength(t *testing.T) {
+	t.Parallel()
+
+	tt := []struct {
+		name     string
+		index    uint64
+		treeSize uint64
+		length   int
+	}{
+		// worked examples from RFC 6962, section 2.1.3: a tree with 7 leaves d0 to d6
+		{name: "RFC 6962 leaf b", index: 1, treeSize: 7, length: 3},
+		{name: "RFC 6962 leaf d", index: 3, treeSize: 7, length: 3},
+		{name: "RFC 6962 leaf e", index: 4, treeSize: 7, length: 3},
+		{name: "RFC 6962 leaf g", index: 6, treeSize: 7, length: 2},
+
+		{name: "single leaf", index: 0, treeSize: 1, length: 0},
+		{name: "balanced tree", index: 5, treeSize: 8, length: 3},
+		{name: "last leaf of unbalanced tree", index: 8, treeSize: 9, length: 1},
+		{name: "first leaf of unbalanced tree", index: 0, treeSize: 9, length: 4},
+		{name: "large tree", index: 1 << 62, treeSize: math.MaxUint64, length: 64},
+		{name: "index out of range", index: 7, treeSize: 7, length: 0},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			length := merkle.AuditPathLength(tc.index, tc.treeSize)
+			if length != tc.length {
+				t.Errorf("Expected audit path length to be %d, got %d", tc.length, length)
+			}
+		})
+	}
+}
+
+func TestValidateProofRootLength(t *testing.T) {
+	t.Parallel()
+
+	tree := merkle.TreeBuilder().
+		WithLeafToProve(4).
+		Build()
+	leaves := make(map[uint64][]byte)
+	for i := range 8 {
+		b := make([]byte, tree.NodeSize())
+		binary.LittleEndian.PutUint64(b, uint64(i))
+		tree.Add(b)
+		if i == 4 {
+			leaves[uint64(i)] = b
+		}
+	}
+	root, proof := tree.RootAndProof()
+
+	valid, err := merkle.ValidateProof(root[:16], leaves, proof)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if valid {
+		t.Error("Expected proof to be invalid for a short root")
+	}
+
+	_, err = merkle.ValidateProof(root[:16], leaves, proof, merkle.WithNodeSizeCheck())
+	if !errors.Is(err, merkle.ErrInvalidRootLength) {
+		t.Errorf("Expected error to be %v, got %v", merkle.ErrInvalidRootLength, err)
+	}
+	expected := "invalid root length: root has 16 bytes, expected 32"
+	if err == nil || err.Error() != expected {
+		t.Errorf("Expected error message %q, got %v", expected, err)
+	}
+
+	err = merkle.ValidateProofDetailed(root[:16], leaves, proof, merkle.WithNodeSizeCheck())
+	if !errors.Is(err, merkle.ErrInvalidRootLength) {
+		t.Errorf("Expected error to be %v, got %v", merkle.ErrInvalidRootLength, err)
+	}
+
+	// a tree with a single leaf has the leaf as root
+	leaf := map[uint64][]byte{0: bytes.Repeat([]byte{0x01}, 32)}
+	valid, err = merkle.ValidateProof(leaf[0], leaf, nil,
+		merkle.WithNodeSizeCheck(), merkle.WithLeafHasher(merkle.ValueLeafs(32)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !valid {
+		t.Error("proof is not valid")
+	}
+}
+
+func TestVerifyWithinSubtree(t *testing.T) {
+	t.Parallel()
+
+	tree := merkle.TreeBuilder().WithLeafToProve(5).Build()
+	for i := range uint64(8) {
+		tree.Add(leaf(i))
+	}
+	_, proof := tree.RootAndProof()
+
+	// the root of the subtree of height 2 containing leaf 5 (leaves 4 to 7)
+	subtreeRoot, err := hex.DecodeString("633b26ee8a5d96d49a4861e9a5720492f0db5b6af305c0b5cfcc6a7ec9b676d4")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tt := []struct {
+		name   string
+		root   []byte
+		height uint64
+		index  uint64
+		leaf   []byte
+		proof  [][]byte
+		valid  bool
+		err    error
+	}{
+		{
+			name:   "path within subtree",
+			root:   subtreeRoot,
+			height: 2,
+			index:  5,
+			leaf:   leaf(5),
+			proof:  proof[:2],
+			valid:  true,
+		},
+		{
+			name:   "full proof",
+			root:   subtreeRoot,
+			height: 2,
+			index:  5,
+			leaf:   leaf(5),
+			proof:  proof,
+			valid:  true,
+		},
+		{
+			name:   "index within subtree",
+			root:   subtreeRoot,
+			height: 2,
+			index:  1,
+			leaf:   leaf(5),
+			proof:  proof[:2],
+			valid:  true,
+		},
+		{
+			name:   "leaf as subtree",
+			root:   leaf(5),
+			height: 0,
+			index:  5,
+			leaf:   leaf(5),
+			valid:  true,
+		},
+		{
+			name:   "wrong leaf",
+			root:   subtreeRoot,
+			height: 2,
+			index:  5,
+			leaf:   leaf(6),
+			proof:  proof[:2],
+			valid:  false,
+		},
+		{
+			name:   "wrong index",
+			root:   subtreeRoot,
+			height: 2,
+			index:  4,
+			leaf:   leaf(5),
+			proof:  proof[:2],
+			valid:  false,
+		},
+		{
+			name:   "short proof",
+			root:   subtreeRoot,
+			height: 2,
+			index:  5,
+			leaf:   leaf(5),
+			proof:  proof[:1],
+			err:    merkle.ErrShortProof,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			valid, err := merkle.VerifyWithinSubtree(tc.root, tc.height, tc.index, tc.leaf, tc.proof)
+			if !errors.Is(err, tc.err) {
+				t.Fatalf("Expected error to be %v, got %v", tc.err, err)
+			}
+			if valid != tc.valid {
+				t.Errorf("Expected proof validity to be %v, got %v", tc.valid, valid)
+			}
+		})
+	}
+}
+
+func TestVerifyWithinSubtreeIndexedLeafHasher(t *testing.T) {
+	t.Parallel()
+
+	nonces := make(map[uint64][]byte)
+	for i := range uint64(8) {
+		nonces[i] = []byte{byte(i), 0xff}
+	}
+
+	tt := []struct {
+		name       string
+		leafHasher merkle.LeafHasher
+		opts       []merkle.ValidatorOpt
+	}{
+		{
+			name:       "leaf nonces",
+			leafHasher: merkle.NoncedLeafHasher(merkle.Sha256(), nonces),
+			opts:       []merkle.ValidatorOpt{merkle.WithLeafNonces(nonces)},
+		},
+		{
+			name:       "counter leaf hasher",
+			leafHasher: merkle.CounterLeafHasher(merkle.Sha256()),
+			opts:       []merkle.ValidatorOpt{merkle.WithLeafHasher(merkle.CounterLeafHasher(merkle.Sha256()))},
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			tree := merkle.TreeBuilder().
+				WithLeafHasher(tc.leafHasher).
+				WithLeafToProve(5).
+				Build()
+			for i := range uint64(8) {
+				tree.Add(leaf(i))
+			}
+			root, proof := tree.RootAndProof()
+
+			// the trail contains the root of the subtree of height 2 containing leaf 5
+			_, trail, err := merkle.ValidateWithAuditTrail(root, 5, leaf(5), proof, tc.opts...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			subtreeRoot := trail[2]
+
+			valid, err := merkle.VerifyWithinSubtree(subtreeRoot, 2, 5, leaf(5), proof, tc.opts...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !valid {
+				t.Error("proof is not valid")
+			}
+
+			// the leaf is hashed with its index in the tree, not in the subtree
+			valid, err = merkle.VerifyWithinSubtree(subtreeRoot, 2, 1, leaf(5), proof, tc.opts...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if valid {
+				t.Error("Expected proof with the index within the subtree to be invalid")
+			}
+		})
+	}
+}
+
+func TestVerifyWithinSubtreeLeafIndexOffset(t *testing.T) {
+	t.Parallel()
+
+	leafHasher := merkle.CounterLeafHasher(merkle.Sha256())
+	tree := merkle.TreeBuilder().
+		WithLeafHasher(leafHasher).
+		WithLeafIndexOffset(100).
+		WithLeafToProve(105).
+		Build()
+	for i := range uint64(8) {
+		tree.Add(leaf(100 + i))
+	}
+	root, proof := tree.RootAndProof()
+
+	opts := []merkle.ValidatorOpt{merkle.WithLeafHasher(leafHasher), merkle.WithLeafIndexOffset(100)}
+	_, trail, err := merkle.ValidateWithAuditTrail(root, 105, leaf(105), proof, opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	valid, err := merkle.VerifyWithinSubtree(trail[2], 2, 105, leaf(105), proof, opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !valid {
+		t.Error("proof is not valid")
+	}
+
+	_, err = merkle.VerifyWithinSubtree(trail[2], 2, 99, leaf(105), proof, opts...)
+	if !errors.Is(err, merkle.ErrInvalidLeafIndex) {
+		t.Errorf("Expected error %v, got %v", merkle.ErrInvalidLeafIndex, err)
+	}
+}
+
+func TestValidateProofEmptyLeaf(t *testing.T) {
+	t.Parallel()
+
+	emptyLeaf := bytes.Repeat([]byte{0xee}, 32)
+	tree := merkle.TreeBuilder().
+		WithEmptyLeaf(emptyLeaf).
+		WithLeafToProve(10).
+		Build()
+	for i := range uint64(11) {
+		tree.Add(leaf(i))
+	}
+	root, proof := tree.RootAndProof()
+
+	tree = merkle.TreeBuilder().
+		WithEmptyLeaf(emptyLeaf).
+		WithLeafToProve(9).
+		Build()
+	for i := range uint64(11) {
+		tree.Add(leaf(i))
+	}
+	_, proof9 := tree.RootAndProof()
+
+	// the slot 11 is empty: its sibling is leaf 10, the other nodes are the same as in the proof of leaf 10
+	nonInclusion := append([][]byte{leaf(10)}, proof[1:]...)
+
+	tt := []struct {
+		name   string
+		leaves map[uint64][]byte
+		proof  [][]byte
+		opts   []merkle.ValidatorOpt
+		valid  bool
+	}{
+		{
+			name:   "inclusion",
+			leaves: map[uint64][]byte{10: leaf(10)},
+			proof:  proof,
+			opts:   []merkle.ValidatorOpt{merkle.WithEmptyLeaf(emptyLeaf)},
+			valid:  true,
+		},
+		{
+			name:   "non-inclusion",
+			leaves: map[uint64][]byte{11: nil},
+			proof:  nonInclusion,
+			opts:   []merkle.ValidatorOpt{merkle.WithEmptyLeaf(emptyLeaf), merkle.WithNodeSizeCheck()},
+			valid:  true,
+		},
+		{
+			name:   "non-inclusion with both leaves",
+			leaves: map[uint64][]byte{10: leaf(10), 11: nil},
+			proof:  proof[1:],
+			opts:   []merkle.ValidatorOpt{merkle.WithEmptyLeaf(emptyLeaf)},
+			valid:  true,
+		},
+		{
+			name:   "leaf in empty slot",
+			leaves: map[uint64][]byte{11: leaf(11)},
+			proof:  nonInclusion,
+			opts:   []merkle.ValidatorOpt{merkle.WithEmptyLeaf(emptyLeaf)},
+			valid:  false,
+		},
+		{
+			name:   "wrong empty leaf",
+			leaves: map[uint64][]byte{11: nil},
+			proof:  nonInclusion,
+			opts:   []merkle.ValidatorOpt{merkle.WithEmptyLeaf(make([]byte, 32))},
+			valid:  false,
+		},
+		{
+			name:   "non-empty slot",
+			leaves: map[uint64][]byte{9: nil},
+			proof:  proof9,
+			opts:   []merkle.ValidatorOpt{merkle.WithEmptyLeaf(emptyLeaf)},
+			valid:  false,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			valid, err := merkle.ValidateProof(root, tc.leaves, tc.proof, tc.opts...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if valid != tc.valid {
+				t.Errorf("Expected proof validity to be %v, got %v", tc.valid, valid)
+			}
+		})
+	}
+}
+
+func TestValidateProofDuplicatePadding(t *testing.T) {
+	t.Parallel()
+
+	h := merkle.Sha256()
+	a, b, c := leaf(0), leaf(1), leaf(2)
+	ab := h.Hash(nil, a, b)
+	cc := h.Hash(nil, c, c)
+	// Bitcoin-style tree with 3 leaves: the last leaf is duplicated to fill the unbalanced layer
+	root := h.Hash(nil, ab, cc)
+
+	tt := []struct {
+		name   string
+		leaves map[uint64][]byte
+		proof  [][]byte
+	}{
+		{name: "first leaf", leaves: map[uint64][]byte{0: a}, proof: [][]byte{b, cc}},
+		{name: "second leaf", leaves: map[uint64][]byte{1: b}, proof: [][]byte{a, cc}},
+		{name: "duplicated leaf", leaves: map[uint64][]byte{2: c}, proof: [][]byte{ab}},
+		{name: "multiple leaves", leaves: map[uint64][]byte{0: a, 2: c}, proof: [][]byte{b}},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			valid, err := merkle.ValidateProof(root, tc.leaves, tc.proof, merkle.WithDuplicatePadding(3))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !valid {
+				t.Error("proof is not valid")
+			}
+		})
+	}
+
+	t.Run("without duplicate padding", func(t *testing.T) {
+		t.Parallel()
+
+		valid, _ := merkle.ValidateProof(root, map[uint64][]byte{2: c}, [][]byte{ab})
+		if valid {
+			t.Error("Expected proof to be invalid without duplicate padding")
+		}
+	})
+
+	t.Run("index beyond tree size", func(t *testing.T) {
+		t.Parallel()
+
+		// the duplicated leaf must not be accepted as a leaf of its own (CVE-2012-2459)
+		tt := []struct {
+			name   string
+			leaves map[uint64][]byte
+			proof  [][]byte
+		}{
+			{name: "tree size", leaves: map[uint64][]byte{3: c}, proof: [][]byte{c, ab}},
+			{name: "beyond tree size", leaves: map[uint64][]byte{4: c}, proof: [][]byte{c, ab}},
+			{name: "with valid leaf", leaves: map[uint64][]byte{2: c, 3: c}, proof: [][]byte{ab}},
+		}
+		for _, tc := range tt {
+			valid, err := merkle.ValidateProof(root, tc.leaves, tc.proof, merkle.WithDuplicatePadding(3))
+			if !errors.Is(err, merkle.ErrInvalidLeafIndex) {
+				t.Errorf("%s: expected error %v, got %v", tc.name, merkle.ErrInvalidLeafIndex, err)
+			}
+			if valid {
+				t.Errorf("%s: expected proof to be invalid", tc.name)
+			}
+		}
+	})
+
+	t.Run("5 leaves", func(t *testing.T) {
+		t.Parallel()
+
+		d, e := leaf(3), leaf(4)
+		abcd := h.Hash(nil, ab, h.Hash(nil, c, d))
+		ee := h.Hash(nil, e, e)
+		root := h.Hash(nil, abcd, h.Hash(nil, ee, ee))
+
+		valid, err := merkle.ValidateProof(root, map[uint64][]byte{4: e}, [][]byte{abcd},
+			merkle.WithDuplicatePadding(5))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !valid {
+			t.Error("proof is not valid")
+		}
+	})
+}
+
+func TestCloneLeaves(t *testing.T) {
+	t.Parallel()
+
+	leaves := map[uint64][]byte{0: leaf(0), 4: leaf(4)}
+	clone := merkle.CloneLeaves(leaves)
+	if len(clone) != len(leaves) {
+		t.Fatalf("Expected clone to have %d leaves, got %d", len(leaves), len(clone))
+	}
+	for idx, value := range leaves {
+		if !bytes.Equal(clone[idx], value) {
+			t.Errorf("Expected leaf %d to be %x, got %x", idx, value, clone[idx])
+		}
+	}
+
+	clone[0][0] = 0xff
+	clone[7] = leaf(7)
+	if leaves[0][0] != 0x00 {
+		t.Error("Expected modifying the clone not to modify the original leaves")
+	}
+	if _, ok := leaves[7]; ok {
+		t.Error("Expected adding to the clone not to add to the original leaves")
+	}
+
+	if merkle.CloneLeaves(nil) != nil {
+		t.Error("Expected clone of nil to be nil")
+	}
+}
+
+func TestValidateProofCopyLeaves(t *testing.T) {
+	t.Parallel()
+
+	tree := merkle.TreeBuilder().
+		WithLeavesToProve(map[uint64]struct{}{0: {}, 4: {}, 7: {}}).
+		Build()
+	leaves := make(map[uint64][]byte)
+	for i := range uint64(8) {
+		tree.Add(leaf(i))
+		if i == 0 || i == 4 || i == 7 {
+			leaves[i] = leaf(i)
+		}
+	}
+	root, proof := tree.RootAndProof()
+	original := merkle.CloneLeaves(leaves)
+
+	valid, err := merkle.ValidateProof(root, leaves, proof, merkle.WithCopyLeaves())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !valid {
+		t.Error("proof is not valid")
+	}
+
+	if len(leaves) != len(original) {
+		t.Fatalf("Expected leaves to have %d entries, got %d", len(original), len(leaves))
+	}
+	for idx, value := range original {
+		if !bytes.Equal(leaves[idx], value) {
+			t.Errorf("Expected leaf %d to be untouched %x, got %x", idx, value, leaves[idx])
+		}
+	}
+}
+
+func TestReusableValidator(t *testing.T) {
+	t.Parallel()
+
+	root, _ := hex.DecodeString("89a0f1577268cc19b0a39c7a69f804fd140640c699585eb635ebb03c06154cce")
+	leaf4, _ := hex.DecodeString("0400000000000000000000000000000000000000000000000000000000000000")
+	proof := make([][]byte, 3)
+	proof[0], _ = hex.DecodeString("0500000000000000000000000000000000000000000000000000000000000000")
+	proof[1], _ = hex.DecodeString("fa670379e5c2212ed93ff09769622f81f98a91e1ec8fb114d607dd25220b9088")
+	proof[2], _ = hex.DecodeString("ba94ffe7edabf26ef12736f8eb5ce74d15bedb6af61444ae2906e926b1a95084")
+
+	v := merkle.NewReusableValidator(root, merkle.WithStrictProof())
+	for range 3 {
+		valid, err := v.Validate(4, leaf4, proof)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !valid {
+			t.Error("proof is not valid")
+		}
+
+		valid, err = v.Validate(5, leaf4, proof)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if valid {
+			t.Error("proof for wrong index is valid")
+		}
+
+		_, err = v.Validate(4, leaf4, proof[:2])
+		if !errors.Is(err, merkle.ErrShortProof) {
+			t.Errorf("Expected error %v, got %v", merkle.ErrShortProof, err)
+		}
+
+		// a copy of the leaf at the position of its sibling is not redundant, the proof is just invalid
+		valid, err = v.Validate(4, leaf4, [][]byte{leaf4, proof[1], proof[2]})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if valid {
+			t.Error("proof with wrong sibling is valid")
+		}
+	}
+}
+
+func TestReusableValidatorMatchesValidateProof(t *testing.T) {
+	t.Parallel()
+
+	v := (*merkle.ReusableValidator)(nil)
+	for i := range uint64(10) {
+		tree := merkle.TreeBuilder().
+			WithLeavesToProve(map[uint64]struct{}{i: {}}).
+			Build()
+		for j := range uint64(10) {
+			tree.Add(leaf(j))
+		}
+		root, proof := tree.RootAndProof()
+		if v == nil {
+			v = merkle.NewReusableValidator(root)
+		}
+
+		valid, err := v.Validate(i, leaf(i), proof)
+		if err != nil {
+			t.Fatal(err)
+		}
+		expected, err := merkle.ValidateProof(root, map[uint64][]byte{i: leaf(i)}, proof)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !valid || valid != expected {
+			t.Errorf("leaf %d: expected proof to be valid, got %t (ValidateProof: %t)", i, valid, expected)
+		}
+	}
+}
+
+func TestValidateProofNilProofNode(t *testing.T) {
+	t.Parallel()
+
+	tt := []struct {
+		name      string
+		leaves    uint64
+		minHeight uint64
+		padding   []byte
+	}{
+		{name: "balanced", leaves: 16, minHeight: 6},
+		{name: "unbalanced", leaves: 11},
+		{name: "random padding", leaves: 11, padding: bytes.Repeat([]byte{0xab}, 32)},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			builder := merkle.TreeBuilder().WithLeafToProve(8).WithMinHeight(tc.minHeight)
+			if tc.padding != nil {
+				builder.WithRandomPadding(bytes.NewReader(tc.padding))
+			}
+			tree := builder.Build()
+			for i := range tc.leaves {
+				tree.Add(leaf(i))
+			}
+			root, proof := tree.RootAndProof()
+			leaves := map[uint64][]byte{8: leaf(8)}
+
+			// replace padding nodes with nil, in balanced trees they are added on top to reach the minimum height
+			padding := tree.Padding()
+			nilProof := append([][]byte(nil), proof...)
+			replaced := 0
+			for i, node := range nilProof {
+				if bytes.Equal(node, padding) {
+					nilProof[i] = nil
+					replaced++
+				}
+			}
+			if replaced == 0 {
+				t.Fatal("Expected proof to contain padding nodes")
+			}
+
+			_, err := merkle.ValidateProof(root, leaves, nilProof)
+			if !errors.Is(err, merkle.ErrNilProofNode) {
+				t.Fatalf("Expected error %v, got %v", merkle.ErrNilProofNode, err)
+			}
+
+			valid, err := merkle.ValidateProof(root, leaves, nilProof, merkle.WithPaddingValue(padding))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !valid {
+				t.Error("Expected proof with nil nodes to be valid with padding value")
+			}
+		})
+	}
+}
+
+func TestValidateAndHashLeaves(t *testing.T) {
+	t.Parallel()
+
+	tt := []struct {
+		name       string
+		leafHasher merkle.LeafHasher
+		leafHash   func(index uint64, data []byte) []byte
+	}{
+		{
+			name:       "content hasher",
+			leafHasher: merkle.ContentHasher(merkle.Sha256()),
+			leafHash: func(_ uint64, data []byte) []byte {
+				hash := sha256.Sum256(data)
+				return hash[:]
+			},
+		},
+		{
+			name:       "counter leaf hasher",
+			leafHasher: merkle.CounterLeafHasher(merkle.Sha256()),
+			leafHash: func(index uint64, data []byte) []byte {
+				hash := sha256.Sum256(append(binary.BigEndian.AppendUint64(nil, index), data...))
+				return hash[:]
+			},
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			tree := merkle.TreeBuilder().
+				WithLeafHasher(tc.leafHasher).
+				WithLeavesToProve(map[uint64]struct{}{0: {}, 4: {}, 9: {}}).
+				Build()
+			for i := range uint64(10) {
+				tree.Add(leaf(i))
+			}
+			root, proof := tree.RootAndProof()
+			leaves := map[uint64][]byte{0: leaf(0), 4: leaf(4), 9: leaf(9)}
+
+			opts := []merkle.ValidatorOpt{merkle.WithLeafHasher(tc.leafHasher)}
+			valid, hashes, err := merkle.ValidateAndHashLeaves(root, leaves, proof, opts...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !valid {
+				t.Error("proof is not valid")
+			}
+			if len(hashes) != len(leaves) {
+				t.Fatalf("Expected %d leaf hashes, got %d", len(leaves), len(hashes))
+			}
+			for idx, value := range leaves {
+				if expected := tc.leafHash(idx, value); !bytes.Equal(hashes[idx], expected) {
+					t.Errorf("Expected hash of leaf %d to be %x, got %x", idx, expected, hashes[idx])
+				}
+			}
+		})
+	}
+}
+
+func TestValidateProofLeafIndexOffset(t *testing.T) {
+	t.Parallel()
+
+	shard := merkle.TreeBuilder().
+		WithLeafIndexOffset(8).
+		WithLeavesToProve(map[uint64]struct{}{10: {}, 13: {}}).
+		Build()
+	for i := range uint64(8) {
+		shard.Add(leaf(8 + i))
+	}
+	root, proof := shard.RootAndProof()
+	leaves := map[uint64][]byte{10: leaf(10), 13: leaf(13)}
+
+	valid, err := merkle.ValidateProof(root, leaves, proof, merkle.WithLeafIndexOffset(8))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !valid {
+		t.Error("proof is not valid")
+	}
+	if len(leaves) != 2 || leaves[10] == nil || leaves[13] == nil {
+		t.Errorf("Expected leaves not to be modified, got %v", leaves)
+	}
+
+	v := merkle.NewReusableValidator(root, merkle.WithLeafIndexOffset(8))
+	single := merkle.TreeBuilder().WithLeafIndexOffset(8).WithLeafToProve(10).Build()
+	for i := range uint64(8) {
+		single.Add(leaf(8 + i))
+	}
+	_, singleProof := single.RootAndProof()
+	valid, err = v.Validate(10, leaf(10), singleProof)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !valid {
+		t.Error("proof is not valid with reusable validator")
+	}
+
+	_, err = merkle.ValidateProof(root, map[uint64][]byte{3: leaf(3)}, proof, merkle.WithLeafIndexOffset(8))
+	if !errors.Is(err, merkle.ErrInvalidLeafIndex) {
+		t.Errorf("Expected error %v, got %v", merkle.ErrInvalidLeafIndex, err)
+	}
+	_, err = v.Validate(3, leaf(3), singleProof)
+	if !errors.Is(err, merkle.ErrInvalidLeafIndex) {
+		t.Errorf("Expected error %v, got %v", merkle.ErrInvalidLeafIndex, err)
+	}
+}
+
+func TestValidateProofExpectedDepth(t *testing.T) {
+	t.Parallel()
+
+	tree := merkle.TreeBuilder().WithLeafToProve(8).Build()
+	for i := range uint64(10) {
+		tree.Add(leaf(i))
+	}
+	root, proof := tree.RootAndProof()
+	leaves := map[uint64][]byte{8: leaf(8)}
+
+	tt := []struct {
+		name  string
+		index uint64
+		depth int
+		err   error
+	}{
+		{
+			name:  "expected depth",
+			index: 8,
+			depth: 4,
+		},
+		{
+			name:  "shallow depth",
+			index: 8,
+			depth: 2,
+			err:   merkle.ErrUnexpectedDepth,
+		},
+		{
+			name:  "deeper depth",
+			index: 8,
+			depth: 5,
+			err:   merkle.ErrUnexpectedDepth,
+		},
+		{
+			name:  "leaf not proven",
+			index: 9,
+			depth: 4,
+			err:   merkle.ErrUnexpectedDepth,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			valid, err := merkle.ValidateProof(root, leaves, proof, merkle.WithExpectedDepth(tc.index, tc.depth))
+			if !errors.Is(err, tc.err) {
+				t.Fatalf("Expected error %v, got %v", tc.err, err)
+			}
+			if valid != (tc.err == nil) {
+				t.Errorf("Expected valid to be %v, got %v", tc.err == nil, valid)
+			}
+
+			v := merkle.NewReusableValidator(root, merkle.WithExpectedDepth(tc.index, tc.depth))
+			_, err = v.Validate(8, leaf(8), proof)
+			if !errors.Is(err, tc.err) {
+				t.Errorf("Expected error %v from reusable validator, got %v", tc.err, err)
+			}
+		})
+	}
+}
+
+func TestValidateProofExpectedDepthDifferentShape(t *testing.T) {
+	t.Parallel()
+
+	// a proof for leaf 0 of a tree with 2 leaves is valid against its root, but not if leaf 0 is expected at the
+	// depth of a tree with 10 leaves
+	tree := merkle.TreeBuilder().WithLeafToProve(0).Build()
+	tree.Add(leaf(0))
+	tree.Add(leaf(1))
+	root, proof := tree.RootAndProof()
+	leaves := map[uint64][]byte{0: leaf(0)}
+
+	valid, err := merkle.ValidateProof(root, leaves, proof, merkle.WithExpectedDepth(0, 1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !valid {
+		t.Error("proof is not valid")
+	}
+
+	_, err = merkle.ValidateProof(root, leaves, proof, merkle.WithExpectedDepth(0, 4))
+	if !errors.Is(err, merkle.ErrUnexpectedDepth) {
+		t.Errorf("Expected error %v, got %v", merkle.ErrUnexpectedDepth, err)
+	}
+}
+
+func TestValidateProofRootInProof(t *testing.T) {
+	t.Parallel()
+
+	tree := merkle.TreeBuilder().WithLeafToProve(4).Build()
+	for i := range uint64(10) {
+		tree.Add(leaf(i))
+	}
+	root, proof := tree.RootAndProof()
+	leaves := map[uint64][]byte{4: leaf(4)}
+	wrongRoot := make([]byte, len(root))
+
+	tt := []struct {
+		name      string
+		root      []byte
+		proofRoot []byte
+		valid     bool
+	}{
+		{
+			name:      "root only in proof",
+			proofRoot: root,
+			valid:     true,
+		},
+		{
+			name:      "same root given",
+			root:      root,
+			proofRoot: root,
+			valid:     true,
+		},
+		{
+			name:      "wrong root in proof",
+			proofRoot: wrongRoot,
+		},
+		{
+			name:      "given root differs",
+			root:      wrongRoot,
+			proofRoot: root,
+		},
+		{
+			name:      "both roots wrong",
+			root:      wrongRoot,
+			proofRoot: wrongRoot,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			selfCertifying := append(slices.Clone(proof), tc.proofRoot)
+			valid, err := merkle.ValidateProof(tc.root, leaves, selfCertifying, merkle.WithRootInProof())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if valid != tc.valid {
+				t.Errorf("Expected valid to be %v, got %v", tc.valid, valid)
+			}
+
+			err = merkle.ValidateProofDetailed(tc.root, leaves, selfCertifying, merkle.WithRootInProof())
+			if tc.valid && err != nil {
+				t.Errorf("Expected no error, got %v", err)
+			}
+			if !tc.valid && !errors.Is(err, merkle.ErrRootMismatch) {
+				t.Errorf("Expected error %v, got %v", merkle.ErrRootMismatch, err)
+			}
+
+			v := merkle.NewReusableValidator(tc.root, merkle.WithRootInProof())
+			valid, err = v.Validate(4, leaf(4), selfCertifying)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if valid != tc.valid {
+				t.Errorf("Expected valid to be %v from reusable validator, got %v", tc.valid, valid)
+			}
+		})
+	}
+}
+
+func TestComputeRootRootInProof(t *testing.T) {
+	t.Parallel()
+
+	tree := merkle.TreeBuilder().WithLeafToProve(4).Build()
+	for i := range uint64(10) {
+		tree.Add(leaf(i))
+	}
+	root, proof := tree.RootAndProof()
+	leaves := map[uint64][]byte{4: leaf(4)}
+
+	computed, err := merkle.ComputeRoot(leaves, append(slices.Clone(proof), root), merkle.WithRootInProof())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(computed, root) {
+		t.Errorf("Expected root %x, got %x", root, computed)
+	}
+
+	_, err = merkle.ComputeRoot(leaves, append(slices.Clone(proof), make([]byte, len(root))), merkle.WithRootInProof())
+	if !errors.Is(err, merkle.ErrRootMismatch) {
+		t.Errorf("Expected error %v, got %v", merkle.ErrRootMismatch, err)
+	}
+
+	_, err = merkle.ValidateProof(root, leaves, nil, merkle.WithRootInProof())
+	if !errors.Is(err, merkle.ErrShortProof) {
+		t.Errorf("Expected error %v, got %v", merkle.ErrShortProof, err)
+	}
+}
+
+func TestValidateWithAuditTrail(t *testing.T) {
+	t.Parallel()
+
+	tree := merkle.TreeBuilder().WithLeafToProve(4).Build()
+	for i := range uint64(8) {
+		tree.Add(leaf(i))
+	}
+	root, proof := tree.RootAndProof()
+
+	expected := []string{
+		"0400000000000000000000000000000000000000000000000000000000000000",
+		"bd50456d5ad175ae99a1612a53ca229124b65d3eaabd9ff9c7ab979a385cf6b3",
+		"633b26ee8a5d96d49a4861e9a5720492f0db5b6af305c0b5cfcc6a7ec9b676d4",
+		"89a0f1577268cc19b0a39c7a69f804fd140640c699585eb635ebb03c06154cce",
+	}
+
+	valid, trail, err := merkle.ValidateWithAuditTrail(root, 4, leaf(4), proof)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !valid {
+		t.Error("proof is not valid")
+	}
+	if len(trail) != len(expected) {
+		t.Fatalf("Expected trail of %d nodes, got %d", len(expected), len(trail))
+	}
+	for i, node := range trail {
+		if hex.EncodeToString(node) != expected[i] {
+			t.Errorf("Expected node %d to be %s, got %x", i, expected[i], node)
+		}
+	}
+
+	// the trail is returned for invalid proofs as well
+	valid, trail, err = merkle.ValidateWithAuditTrail(root, 4, leaf(5), proof)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if valid {
+		t.Error("Expected proof to be invalid")
+	}
+	if len(trail) != len(expected) {
+		t.Errorf("Expected trail of %d nodes, got %d", len(expected), len(trail))
+	}
+
+	_, _, err = merkle.ValidateWithAuditTrail(root, 4, leaf(4), proof[:2])
+	if !errors.Is(err, merkle.ErrShortProof) {
+		t.Errorf("Expected error %v, got %v", merkle.ErrShortProof, err)
+	}
+}
+
 // Benchmark results
 //
 // goos: linux
@@ -726,6 +2238,21 @@ func BenchmarkValidateProof(b *testing.B) {
 	}
 }
 
+func BenchmarkReusableValidator(b *testing.B) {
+	leaf, _ := hex.DecodeString("0400000000000000000000000000000000000000000000000000000000000000")
+
+	root, _ := hex.DecodeString("89a0f1577268cc19b0a39c7a69f804fd140640c699585eb635ebb03c06154cce")
+	proof := make([][]byte, 3)
+	proof[0], _ = hex.DecodeString("0500000000000000000000000000000000000000000000000000000000000000")
+	proof[1], _ = hex.DecodeString("fa670379e5c2212ed93ff09769622f81f98a91e1ec8fb114d607dd25220b9088")
+	proof[2], _ = hex.DecodeString("ba94ffe7edabf26ef12736f8eb5ce74d15bedb6af61444ae2906e926b1a95084")
+
+	v := merkle.NewReusableValidator(root)
+	for b.Loop() {
+		v.Validate(4, leaf, proof) //nolint:errcheck
+	}
+}
+
 func BenchmarkValidateMultiProof(b *testing.B) {
 	leaves := make(map[uint64][]byte)
 	leaves[0], _ = hex.DecodeString("0000000000000000000000000000000000000000000000000000000000000000")
@@ -759,6 +2286,22 @@ func BenchmarkValidateProofSequentialWork(b *testing.B) {
 	}
 }
 
+func BenchmarkReusableValidatorSequentialWork(b *testing.B) {
+	leaf, _ := hex.DecodeString("0400000000000000000000000000000000000000000000000000000000000000")
+
+	root, _ := hex.DecodeString("02ce397ec513f034dd6ec5dce3cdb8bfcf10f400a9979cb03abf52d3b5f6c88b")
+	proof := make([][]byte, 3)
+	proof[0], _ = hex.DecodeString("03085fced9119406c955dc302885a509bf81972ead5fb8b1d87dd3308f9830a2")
+	proof[1], _ = hex.DecodeString("64276da1ef80b4d466e654c5808c4ea3f2c57dda04499e0f495ac4593c746993")
+	proof[2], _ = hex.DecodeString("c3831849e0ae67538cb54a4de0729118685c41822f714f7c466ee641380d01db")
+
+	v := merkle.NewReusableValidator(root, merkle.WithLeafHasher(merkle.SequentialWorkHasher()))
+	b.ReportAllocs()
+	for b.Loop() {
+		v.Validate(4, leaf, proof) //nolint:errcheck
+	}
+}
+
 func BenchmarkValidateMultiProofSequentialWork(b *testing.B) {
 	leaves := make(map[uint64][]byte)
 	leaves[0], _ = hex.DecodeString("0000000000000000000000000000000000000000000000000000000000000000")